@@ -0,0 +1,191 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+type Format string
+
+const (
+	Zip   Format = "zip"
+	Tar   Format = "tar"
+	TarGz Format = "tar.gz"
+)
+
+// Source is the interface needed to read archives without fetching the whole
+// object. *minio.Object satisfies this and will make range requests for
+// ReadAt and reads following a Seek.
+type Source interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+}
+
+type Entry struct {
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	IsDir    bool      `json:"is_dir"`
+}
+
+var ErrEntryNotFound = errors.New("entry not found in archive")
+
+// FormatFor returns the archive format of an object, or "" if it's not a
+// supported archive.
+func FormatFor(contentType, name string) Format {
+	name = strings.ToLower(name)
+
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return TarGz
+	case strings.HasSuffix(name, ".tar"), contentType == "application/x-tar":
+		return Tar
+	case path.Ext(name) == ".zip", contentType == "application/zip", contentType == "application/x-zip-compressed":
+		return Zip
+	}
+
+	return ""
+}
+
+// List returns the entries in an archive. Zip files are listed from the
+// central directory at the end of the file, and uncompressed tar files seek
+// over entry content, so only gzipped tar files need to be read in full.
+func List(src Source, size int64, format Format) ([]Entry, error) {
+	switch format {
+	case Zip:
+		zr, err := zip.NewReader(src, size)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open zip: %w", err)
+		}
+
+		entries := make([]Entry, 0, len(zr.File))
+		for _, f := range zr.File {
+			entries = append(entries, Entry{
+				Name:     f.Name,
+				Size:     int64(f.UncompressedSize64),
+				Modified: f.Modified,
+				IsDir:    f.FileInfo().IsDir(),
+			})
+		}
+
+		return entries, nil
+	case Tar, TarGz:
+		tr, closer, err := tarReader(src, format)
+		if err != nil {
+			return nil, err
+		}
+		defer closer.Close()
+
+		var entries []Entry
+		for {
+			hdr, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read tar header: %w", err)
+			}
+
+			entries = append(entries, Entry{
+				Name:     hdr.Name,
+				Size:     hdr.Size,
+				Modified: hdr.ModTime,
+				IsDir:    hdr.Typeflag == tar.TypeDir,
+			})
+		}
+
+		return entries, nil
+	}
+
+	return nil, fmt.Errorf("unsupported archive format: %q", format)
+}
+
+// Open returns a reader for a single file in an archive. The caller must
+// close the returned reader.
+func Open(src Source, size int64, format Format, name string) (io.ReadCloser, *Entry, error) {
+	switch format {
+	case Zip:
+		zr, err := zip.NewReader(src, size)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open zip: %w", err)
+		}
+
+		for _, f := range zr.File {
+			if f.Name != name || f.FileInfo().IsDir() {
+				continue
+			}
+
+			rc, err := f.Open()
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to open zip entry: %w", err)
+			}
+
+			return rc, &Entry{
+				Name:     f.Name,
+				Size:     int64(f.UncompressedSize64),
+				Modified: f.Modified,
+			}, nil
+		}
+
+		return nil, nil, ErrEntryNotFound
+	case Tar, TarGz:
+		tr, closer, err := tarReader(src, format)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for {
+			hdr, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				closer.Close()
+				return nil, nil, fmt.Errorf("failed to read tar header: %w", err)
+			}
+
+			if hdr.Name != name || hdr.Typeflag == tar.TypeDir {
+				continue
+			}
+
+			return &readCloser{Reader: tr, Closer: closer}, &Entry{
+				Name:     hdr.Name,
+				Size:     hdr.Size,
+				Modified: hdr.ModTime,
+			}, nil
+		}
+
+		closer.Close()
+
+		return nil, nil, ErrEntryNotFound
+	}
+
+	return nil, nil, fmt.Errorf("unsupported archive format: %q", format)
+}
+
+func tarReader(src Source, format Format) (*tar.Reader, io.Closer, error) {
+	if format == Tar {
+		// the source is passed directly so that tar can seek past content
+		return tar.NewReader(src), io.NopCloser(nil), nil
+	}
+
+	gz, err := gzip.NewReader(src)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open gzip: %w", err)
+	}
+
+	return tar.NewReader(gz), gz, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,198 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+var testFiles = map[string]string{
+	"a.txt":     "hello",
+	"dir/b.txt": "hello world",
+}
+
+func buildZip(t *testing.T) []byte {
+	buf := bytes.NewBuffer([]byte{})
+	zw := zip.NewWriter(buf)
+
+	for _, name := range []string{"a.txt", "dir/b.txt"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		_, err = w.Write([]byte(testFiles[name]))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	err := zw.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	return buf.Bytes()
+}
+
+func buildTar(t *testing.T, gzipped bool) []byte {
+	buf := bytes.NewBuffer([]byte{})
+
+	var w io.Writer = buf
+	var gz *gzip.Writer
+	if gzipped {
+		gz = gzip.NewWriter(buf)
+		w = gz
+	}
+
+	tw := tar.NewWriter(w)
+
+	err := tw.WriteHeader(&tar.Header{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0o755, ModTime: time.Unix(0, 0)})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, name := range []string{"a.txt", "dir/b.txt"} {
+		err := tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0o644,
+			Size:    int64(len(testFiles[name])),
+			ModTime: time.Unix(0, 0),
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		_, err = tw.Write([]byte(testFiles[name]))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	err = tw.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if gz != nil {
+		err = gz.Close()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+func TestFormatFor(t *testing.T) {
+	tests := map[string]struct {
+		contentType string
+		name        string
+		expected    Format
+	}{
+		"zip":                {contentType: "application/zip", name: "backup.zip", expected: Zip},
+		"zip by extension":   {contentType: "application/octet-stream", name: "BACKUP.ZIP", expected: Zip},
+		"tar":                {contentType: "application/x-tar", name: "backup.tar", expected: Tar},
+		"tar.gz":             {contentType: "application/gzip", name: "backup.tar.gz", expected: TarGz},
+		"tgz":                {contentType: "application/octet-stream", name: "backup.tgz", expected: TarGz},
+		"plain gzip":         {contentType: "application/gzip", name: "log.gz", expected: ""},
+		"not an archive":     {contentType: "image/jpeg", name: "photo.jpg", expected: ""},
+		"docx is not listed": {contentType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document", name: "a.docx", expected: ""},
+	}
+
+	for testCase, testData := range tests {
+		t.Run(testCase, func(t *testing.T) {
+			if got := FormatFor(testData.contentType, testData.name); got != testData.expected {
+				t.Fatalf("expected %q, got %q", testData.expected, got)
+			}
+		})
+	}
+}
+
+func TestListAndOpen(t *testing.T) {
+	tests := map[string]struct {
+		format       Format
+		content      []byte
+		expectedList []string
+	}{
+		"zip": {
+			format:       Zip,
+			content:      buildZip(t),
+			expectedList: []string{"a.txt", "dir/b.txt"},
+		},
+		"tar": {
+			format:       Tar,
+			content:      buildTar(t, false),
+			expectedList: []string{"dir/", "a.txt", "dir/b.txt"},
+		},
+		"tar.gz": {
+			format:       TarGz,
+			content:      buildTar(t, true),
+			expectedList: []string{"dir/", "a.txt", "dir/b.txt"},
+		},
+	}
+
+	for testCase, testData := range tests {
+		t.Run(testCase, func(t *testing.T) {
+			src := bytes.NewReader(testData.content)
+
+			entries, err := List(src, int64(len(testData.content)), testData.format)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if exp, got := len(testData.expectedList), len(entries); exp != got {
+				t.Fatalf("expected %d entries, got %d", exp, got)
+			}
+
+			for i, e := range entries {
+				if e.Name != testData.expectedList[i] {
+					t.Fatalf("expected entry %q, got %q", testData.expectedList[i], e.Name)
+				}
+
+				if !e.IsDir && e.Size != int64(len(testFiles[e.Name])) {
+					t.Fatalf("unexpected size for %s: %d", e.Name, e.Size)
+				}
+			}
+
+			_, err = src.Seek(0, io.SeekStart)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			rc, entry, err := Open(src, int64(len(testData.content)), testData.format, "dir/b.txt")
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			defer rc.Close()
+
+			bs, err := io.ReadAll(rc)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if exp, got := testFiles["dir/b.txt"], string(bs); exp != got {
+				t.Fatalf("expected %q, got %q", exp, got)
+			}
+
+			if entry.Name != "dir/b.txt" {
+				t.Fatalf("unexpected entry name: %s", entry.Name)
+			}
+
+			_, err = src.Seek(0, io.SeekStart)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			_, _, err = Open(src, int64(len(testData.content)), testData.format, "missing.txt")
+			if !errors.Is(err, ErrEntryNotFound) {
+				t.Fatalf("expected not found error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+
+	"github.com/charlieegan3/storage-console/pkg/config"
+	"github.com/charlieegan3/storage-console/pkg/database"
+)
+
+// exit codes returned by Run
+const (
+	ExitOK = 0
+	// ExitError is returned when a command fails
+	ExitError = 1
+	// ExitUsage is returned for unknown commands and invalid flags
+	ExitUsage = 2
+	// ExitPartial is returned when a run completes but some blobs failed to
+	// process, they are retried on a later run
+	ExitPartial = 3
+)
+
+const usage = `Usage: storage-console <command> [flags]
+
+Commands:
+  serve     migrate the database and run the web server
+  import    import objects from the bucket
+  meta      run the metadata processors
+  props     run the properties processors
+  migrate   run database migrations: up, down or version
+  stats     show counts of objects, blobs and processing results
+
+Run storage-console <command> -h for the flags of a command. Config keys can
+also be set with STORAGE_CONSOLE_* environment variables, and -config "" uses
+only the environment.
+`
+
+// errUsage is returned by commands for invalid arguments, the message is
+// shown with the usage
+var errUsage = errors.New("usage")
+
+// errPartial is returned by commands which completed with processing errors
+var errPartial = errors.New("completed with processing errors")
+
+type command struct {
+	name string
+	run  func(ctx context.Context, env *env, args []string) error
+}
+
+var commands = []command{
+	{"serve", runServe},
+	{"import", runImport},
+	{"meta", runMeta},
+	{"props", runProps},
+	{"migrate", runMigrate},
+	{"stats", runStats},
+}
+
+// env is shared by the commands
+type env struct {
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// Run runs the command named by the first argument and returns the exit
+// code. A single config file argument is treated as serve for
+// compatibility with earlier versions.
+func Run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	e := &env{stdout: stdout, stderr: stderr}
+
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return ExitUsage
+	}
+
+	name, args := args[0], args[1:]
+	if len(args) == 0 && isConfigPath(name) {
+		name, args = "serve", []string{"-config", name}
+	}
+
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+
+		err := c.run(ctx, e, args)
+		switch {
+		case err == nil:
+			return ExitOK
+		case errors.Is(err, flag.ErrHelp):
+			return ExitOK
+		case errors.Is(err, errUsage):
+			fmt.Fprintf(stderr, "%s\n", err)
+			return ExitUsage
+		case errors.Is(err, errPartial):
+			fmt.Fprintf(stderr, "%s: %s\n", name, err)
+			return ExitPartial
+		default:
+			fmt.Fprintf(stderr, "%s: %s\n", name, err)
+			return ExitError
+		}
+	}
+
+	if name == "-h" || name == "--help" || name == "help" {
+		fmt.Fprint(stdout, usage)
+		return ExitOK
+	}
+
+	fmt.Fprintf(stderr, "unknown command %q\n\n%s", name, usage)
+
+	return ExitUsage
+}
+
+func isConfigPath(arg string) bool {
+	switch strings.ToLower(filepath.Ext(arg)) {
+	case ".yaml", ".yml":
+		return true
+	}
+
+	return false
+}
+
+// commonFlags are accepted by every command
+type commonFlags struct {
+	configPath string
+	output     string
+}
+
+func newFlagSet(e *env, name string, common *commonFlags) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+
+	fs.StringVar(&common.configPath, "config", "config.yaml", "path to the config file, empty to use only environment variables")
+	fs.StringVar(&common.output, "output", "text", "output format, text or json")
+
+	return fs
+}
+
+func parseFlags(fs *flag.FlagSet, common *commonFlags, args []string) error {
+	err := fs.Parse(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+
+		return fmt.Errorf("%w: %s", errUsage, err)
+	}
+
+	switch common.output {
+	case "text", "json":
+	default:
+		return fmt.Errorf("%w: unsupported output %q, must be text or json", errUsage, common.output)
+	}
+
+	return nil
+}
+
+func loadConfig(path string) (*config.Config, error) {
+	if path == "" {
+		cfg, err := config.LoadConfig(strings.NewReader(""))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing config: %w", err)
+		}
+
+		return cfg, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+	defer f.Close()
+
+	cfg, err := config.LoadConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+func openDB(cfg *config.Config, hooks ...database.Hook) (*sql.DB, error) {
+	db, err := database.Open(cfg.Database.ConnectionString, hooks...)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to database: %w", err)
+	}
+
+	return db, nil
+}
+
+func migrationConfig(cfg *config.Config) *postgres.Config {
+	return &postgres.Config{
+		MigrationsTable: cfg.Database.MigrationsTable,
+		SchemaName:      cfg.Database.MigrationsSchema,
+	}
+}
+
+// splitList parses a comma separated flag value
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
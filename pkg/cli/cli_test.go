@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRunExitCodes(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		args     []string
+		expected int
+	}{
+		"no args": {
+			args:     nil,
+			expected: ExitUsage,
+		},
+		"help": {
+			args:     []string{"help"},
+			expected: ExitOK,
+		},
+		"unknown command": {
+			args:     []string{"frobnicate"},
+			expected: ExitUsage,
+		},
+		"unknown flag": {
+			args:     []string{"stats", "-nope"},
+			expected: ExitUsage,
+		},
+		"bad output format": {
+			args:     []string{"stats", "-output", "xml"},
+			expected: ExitUsage,
+		},
+		"missing config file": {
+			args:     []string{"stats", "-config", "does-not-exist.yaml"},
+			expected: ExitError,
+		},
+		"missing config file as only arg": {
+			args:     []string{"does-not-exist.yaml"},
+			expected: ExitError,
+		},
+		"unknown migrate action": {
+			args:     []string{"migrate", "sideways"},
+			expected: ExitUsage,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var stdout, stderr bytes.Buffer
+			code := Run(context.Background(), tc.args, &stdout, &stderr)
+			if code != tc.expected {
+				t.Fatalf("expected exit code %d, got %d\nstderr: %s", tc.expected, code, stderr.String())
+			}
+		})
+	}
+}
+
+func TestSelectProcessors(t *testing.T) {
+	t.Parallel()
+
+	available := []string{"exif", "color", "places"}
+
+	selected, err := selectProcessors("", available)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !slices.Equal(selected, available) {
+		t.Fatalf("expected all processors, got %v", selected)
+	}
+
+	selected, err = selectProcessors("color, exif", available)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !slices.Equal(selected, []string{"color", "exif"}) {
+		t.Fatalf("unexpected processors: %v", selected)
+	}
+
+	_, err = selectProcessors("ocr", available)
+	if err == nil {
+		t.Fatal("expected error for unknown processor")
+	}
+}
+
+func TestPrintReport(t *testing.T) {
+	t.Parallel()
+
+	report := &Stats{
+		Objects:  3,
+		Metadata: map[string]int{"thumbnail/ok": 2, "exif/ok": 1},
+		Errors:   map[string]int{},
+	}
+
+	var text bytes.Buffer
+	err := printReport(&text, "text", report)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, line := range []string{
+		"Objects: 3\n",
+		"Metadata.exif/ok: 1\nMetadata.thumbnail/ok: 2\n",
+		"Errors: none\n",
+	} {
+		if !strings.Contains(text.String(), line) {
+			t.Fatalf("expected %q in:\n%s", line, text.String())
+		}
+	}
+
+	var json bytes.Buffer
+	err = printReport(&json, "json", report)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !strings.Contains(json.String(), `"Objects": 3`) {
+		t.Fatalf("unexpected json:\n%s", json.String())
+	}
+}
@@ -0,0 +1,445 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charlieegan3/storage-console/pkg/config"
+	"github.com/charlieegan3/storage-console/pkg/database"
+	"github.com/charlieegan3/storage-console/pkg/database/migration"
+	"github.com/charlieegan3/storage-console/pkg/importer"
+	"github.com/charlieegan3/storage-console/pkg/logging"
+	metaRunner "github.com/charlieegan3/storage-console/pkg/meta/runner"
+	"github.com/charlieegan3/storage-console/pkg/metrics"
+	propRunner "github.com/charlieegan3/storage-console/pkg/properties/runner"
+	"github.com/charlieegan3/storage-console/pkg/server"
+	"github.com/charlieegan3/storage-console/pkg/server/handlers"
+	"github.com/charlieegan3/storage-console/pkg/tracing"
+)
+
+// shutdownTimeout is how long the server has to finish open requests
+const shutdownTimeout = 10 * time.Second
+
+func runServe(ctx context.Context, e *env, args []string) error {
+	var common commonFlags
+	fs := newFlagSet(e, "serve", &common)
+	if err := parseFlags(fs, &common, args); err != nil {
+		return err
+	}
+	if err := noArgs(fs); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(common.configPath)
+	if err != nil {
+		return err
+	}
+
+	var m *metrics.Metrics
+	var hooks []database.Hook
+	if cfg.Server.Metrics.Enabled {
+		m = metrics.New()
+		hooks = append(hooks, m.DBHook())
+	}
+
+	if cfg.Server.Tracing.Enabled {
+		shutdownTracing, err := tracing.Setup(ctx, cfg.Server.Tracing)
+		if err != nil {
+			return err
+		}
+
+		// spans still being exported are flushed after the server stops
+		defer func() {
+			flushCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+
+			err := shutdownTracing(flushCtx)
+			if err != nil {
+				cfg.Server.Logger.Error("failed to flush traces", logging.Err(err))
+			}
+		}()
+
+		hooks = append(hooks, tracing.DBHook())
+	}
+
+	db, err := openDB(cfg, hooks...)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	err = migration.Up(db, cfg.Database.SchemaName, migrationConfig(cfg))
+	if err != nil {
+		return fmt.Errorf("error running migrations: %w", err)
+	}
+
+	buckets, err := server.OpenBuckets(cfg, db, m)
+	if err != nil {
+		return err
+	}
+
+	srv, err := server.NewServer(db, buckets, cfg, m)
+	if err != nil {
+		return fmt.Errorf("error creating server: %w", err)
+	}
+
+	cfg.Server.Logger.Info(
+		"starting server",
+		"address", fmt.Sprintf("http://%s:%d", cfg.Server.Address, cfg.Server.Port),
+	)
+
+	// the server is stopped below so that open requests can finish
+	err = srv.Start(context.WithoutCancel(ctx))
+	if err != nil {
+		return fmt.Errorf("error starting server: %w", err)
+	}
+
+	<-ctx.Done()
+	fmt.Fprintln(e.stderr, "Shutting down...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err = srv.Stop(shutdownCtx)
+	if err != nil {
+		return fmt.Errorf("error stopping server: %w", err)
+	}
+
+	return nil
+}
+
+func runImport(ctx context.Context, e *env, args []string) error {
+	var common commonFlags
+	fs := newFlagSet(e, "import", &common)
+	prefix := fs.String("prefix", "", "only import objects with keys starting with this prefix")
+	bucketName := fs.String("bucket", "", "only import objects from this bucket, defaults to all")
+	if err := parseFlags(fs, &common, args); err != nil {
+		return err
+	}
+	if err := noArgs(fs); err != nil {
+		return err
+	}
+
+	b, err := newBatch(e, common, *bucketName)
+	if err != nil {
+		return err
+	}
+	defer b.close()
+
+	var reports []bucketReport
+	for _, bucket := range b.buckets {
+		rpt, err := importer.Run(ctx, b.db, bucket.S3, &importer.Options{
+			BucketName: bucket.BucketName,
+			BucketID:   bucket.ID,
+			SchemaName: b.cfg.Database.SchemaName,
+			Prefix:     *prefix,
+			Layout:     bucket.Layout,
+			Logger:     b.logger,
+		})
+		if err != nil {
+			return fmt.Errorf("error running importer for bucket %s: %w", bucket.Name, err)
+		}
+
+		reports = append(reports, bucketReport{bucket: bucket.Name, report: rpt})
+	}
+
+	return printBucketReports(e.stdout, common.output, reports)
+}
+
+func runMeta(ctx context.Context, e *env, args []string) error {
+	var common commonFlags
+	fs := newFlagSet(e, "meta", &common)
+	prefix := fs.String("prefix", "", "only process objects with keys starting with this prefix")
+	processorList := fs.String("processors", "", "comma separated metadata processors to run, defaults to all")
+	bucketName := fs.String("bucket", "", "only process objects from this bucket, defaults to all")
+	if err := parseFlags(fs, &common, args); err != nil {
+		return err
+	}
+	if err := noArgs(fs); err != nil {
+		return err
+	}
+
+	b, err := newBatch(e, common, *bucketName)
+	if err != nil {
+		return err
+	}
+	defer b.close()
+
+	// every bucket's registry is built from the same processor config
+	enabled, err := selectProcessors(*processorList, b.buckets[0].Processors.MetaNames())
+	if err != nil {
+		return err
+	}
+
+	var reports []bucketReport
+	failed := 0
+	for _, bucket := range b.buckets {
+		rpt, err := metaRunner.Run(ctx, b.db, bucket.S3, &metaRunner.Options{
+			BucketName:        bucket.BucketName,
+			BucketID:          bucket.ID,
+			SchemaName:        b.cfg.Database.SchemaName,
+			Prefix:            *prefix,
+			Layout:            bucket.Layout,
+			Registry:          bucket.Processors,
+			EnabledProcessors: enabled,
+			Logger:            b.logger,
+		})
+		if err != nil {
+			return fmt.Errorf("error running metadata runner for bucket %s: %w", bucket.Name, err)
+		}
+
+		for _, n := range rpt.Errors {
+			failed += n
+		}
+
+		reports = append(reports, bucketReport{bucket: bucket.Name, report: rpt})
+	}
+
+	err = printBucketReports(e.stdout, common.output, reports)
+	if err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%w: %d failures", errPartial, failed)
+	}
+
+	return nil
+}
+
+func runProps(ctx context.Context, e *env, args []string) error {
+	var common commonFlags
+	fs := newFlagSet(e, "props", &common)
+	prefix := fs.String("prefix", "", "only process objects with keys starting with this prefix")
+	processorList := fs.String("processors", "", "comma separated properties processors to run, defaults to all")
+	bucketName := fs.String("bucket", "", "only process objects from this bucket, defaults to all")
+	if err := parseFlags(fs, &common, args); err != nil {
+		return err
+	}
+	if err := noArgs(fs); err != nil {
+		return err
+	}
+
+	b, err := newBatch(e, common, *bucketName)
+	if err != nil {
+		return err
+	}
+	defer b.close()
+
+	enabled, err := selectProcessors(*processorList, b.buckets[0].Processors.PropertiesNames())
+	if err != nil {
+		return err
+	}
+
+	var reports []bucketReport
+	for _, bucket := range b.buckets {
+		rpt, err := propRunner.Run(ctx, b.db, bucket.S3, &propRunner.Options{
+			BucketName:        bucket.BucketName,
+			BucketID:          bucket.ID,
+			SchemaName:        b.cfg.Database.SchemaName,
+			Prefix:            *prefix,
+			Layout:            bucket.Layout,
+			Registry:          bucket.Processors,
+			EnabledProcessors: enabled,
+			Logger:            b.logger,
+		})
+		if err != nil {
+			return fmt.Errorf("error running properties runner for bucket %s: %w", bucket.Name, err)
+		}
+
+		reports = append(reports, bucketReport{bucket: bucket.Name, report: rpt})
+	}
+
+	return printBucketReports(e.stdout, common.output, reports)
+}
+
+// migrationStatus is printed by the migrate command
+type migrationStatus struct {
+	Version uint
+	Dirty   bool
+}
+
+func runMigrate(ctx context.Context, e *env, args []string) error {
+	var common commonFlags
+	fs := newFlagSet(e, "migrate", &common)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: storage-console migrate <up|down|version> [flags]\n")
+		fs.PrintDefaults()
+	}
+	all := fs.Bool("all", false, "with down, roll back every migration rather than the last one")
+	if err := parseFlags(fs, &common, args); err != nil {
+		return err
+	}
+
+	// flags may come before or after the action
+	if fs.NArg() == 0 {
+		return fmt.Errorf("%w: migrate requires an action, up, down or version", errUsage)
+	}
+	action := fs.Arg(0)
+	if err := parseFlags(fs, &common, fs.Args()[1:]); err != nil {
+		return err
+	}
+	if err := noArgs(fs); err != nil {
+		return err
+	}
+
+	switch action {
+	case "up", "down", "version":
+	default:
+		return fmt.Errorf("%w: unknown migrate action %q, must be up, down or version", errUsage, action)
+	}
+
+	if *all && action != "down" {
+		return fmt.Errorf("%w: -all can only be used with down", errUsage)
+	}
+
+	cfg, err := loadConfig(common.configPath)
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch {
+	case action == "up":
+		err = migration.Up(db, cfg.Database.SchemaName, migrationConfig(cfg))
+	case action == "down" && *all:
+		err = migration.Down(db, cfg.Database.SchemaName, migrationConfig(cfg))
+	case action == "down":
+		err = migration.Steps(db, cfg.Database.SchemaName, migrationConfig(cfg), -1)
+	}
+	if err != nil {
+		return err
+	}
+
+	var status migrationStatus
+	status.Version, status.Dirty, err = migration.Version(db, cfg.Database.SchemaName, migrationConfig(cfg))
+	if err != nil {
+		return err
+	}
+
+	return printReport(e.stdout, common.output, status)
+}
+
+func runStats(ctx context.Context, e *env, args []string) error {
+	var common commonFlags
+	fs := newFlagSet(e, "stats", &common)
+	if err := parseFlags(fs, &common, args); err != nil {
+		return err
+	}
+	if err := noArgs(fs); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(common.configPath)
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	stats, err := loadStats(ctx, db, cfg.Database.SchemaName)
+	if err != nil {
+		return fmt.Errorf("error loading stats: %w", err)
+	}
+
+	return printReport(e.stdout, common.output, stats)
+}
+
+func noArgs(fs *flag.FlagSet) error {
+	if fs.NArg() > 0 {
+		return fmt.Errorf("%w: unexpected arguments %s", errUsage, strings.Join(fs.Args(), " "))
+	}
+
+	return nil
+}
+
+// selectProcessors returns the processors named in the flag value, or all
+// of them when it's empty
+func selectProcessors(value string, available []string) ([]string, error) {
+	selected := splitList(value)
+	if len(selected) == 0 {
+		return available, nil
+	}
+
+	for _, name := range selected {
+		if !slices.Contains(available, name) {
+			return nil, fmt.Errorf(
+				"%w: unknown processor %q, must be one of %s",
+				errUsage, name, strings.Join(available, ", "),
+			)
+		}
+	}
+
+	return selected, nil
+}
+
+// batch holds the clients used by the import and processing commands
+type batch struct {
+	cfg     *config.Config
+	db      *sql.DB
+	buckets []*handlers.Bucket
+
+	// logs are written to stderr so that stdout only holds the report
+	logger *slog.Logger
+}
+
+// newBatch opens the configured buckets, or only bucketName when it's set
+func newBatch(e *env, common commonFlags, bucketName string) (*batch, error) {
+	cfg, err := loadConfig(common.configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	buckets, err := server.OpenBuckets(cfg, db, nil)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if bucketName != "" {
+		i := slices.IndexFunc(buckets, func(b *handlers.Bucket) bool { return b.Name == bucketName })
+		if i < 0 {
+			db.Close()
+			return nil, fmt.Errorf("%w: unknown bucket %q", errUsage, bucketName)
+		}
+
+		buckets = buckets[i : i+1]
+	}
+
+	logger, err := logging.New(e.stderr, cfg.Server.Log.Level, cfg.Server.Log.Format)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &batch{
+		cfg:     cfg,
+		db:      db,
+		buckets: buckets,
+		logger:  logger,
+	}, nil
+}
+
+func (b *batch) close() {
+	b.db.Close()
+}
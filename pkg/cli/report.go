@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+)
+
+// printReport writes a runner report, or other struct, as JSON or as a line
+// per field. Map fields have a line per key, named field.key.
+func printReport(w io.Writer, format string, report any) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(report)
+	}
+
+	v := reflect.ValueOf(report)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		_, err := fmt.Fprintln(w, report)
+		return err
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		value := v.Field(i)
+
+		if value.Kind() != reflect.Map {
+			if _, err := fmt.Fprintf(w, "%s: %v\n", field.Name, value.Interface()); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if value.Len() == 0 {
+			if _, err := fmt.Fprintf(w, "%s: none\n", field.Name); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		keys := value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+
+		for _, k := range keys {
+			_, err := fmt.Fprintf(w, "%s.%v: %v\n", field.Name, k.Interface(), value.MapIndex(k).Interface())
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// bucketReport is the report of a command run against one bucket
+type bucketReport struct {
+	bucket string
+	report any
+}
+
+// printBucketReports prints a report for each bucket. A single report is
+// printed on its own, otherwise JSON is keyed by bucket and text has a
+// [bucket] header before each report.
+func printBucketReports(w io.Writer, format string, reports []bucketReport) error {
+	if len(reports) == 1 {
+		return printReport(w, format, reports[0].report)
+	}
+
+	if format == "json" {
+		byBucket := make(map[string]any, len(reports))
+		for _, r := range reports {
+			byBucket[r.bucket] = r.report
+		}
+
+		return printReport(w, format, byBucket)
+	}
+
+	for i, r := range reports {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+
+		if _, err := fmt.Fprintf(w, "[%s]\n", r.bucket); err != nil {
+			return err
+		}
+
+		err := printReport(w, format, r.report)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
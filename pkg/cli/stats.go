@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/charlieegan3/storage-console/pkg/database"
+)
+
+// Stats summarises the state of the database
+type Stats struct {
+	Objects        int
+	DeletedObjects int
+	Blobs          int
+	BlobBytes      int64
+	// Metadata counts results by processor/result
+	Metadata map[string]int
+	// Properties counts the blobs with properties from each source
+	Properties map[string]int
+	// Errors counts the blobs each processor is failing for
+	Errors map[string]int
+}
+
+func loadStats(ctx context.Context, db *sql.DB, schema string) (*Stats, error) {
+	txn, err := database.NewTxnWithSchema(db, schema)
+	if err != nil {
+		return nil, fmt.Errorf("could not start transaction: %s", err)
+	}
+	defer txn.Rollback()
+
+	stats := Stats{
+		Metadata:   make(map[string]int),
+		Properties: make(map[string]int),
+		Errors:     make(map[string]int),
+	}
+
+	countsSQL := `
+select
+  (select count(*) from objects where deleted_at is null),
+  (select count(*) from objects where deleted_at is not null),
+  (select count(*) from blobs),
+  (select coalesce(sum(size), 0) from blobs)`
+
+	err = txn.QueryRowContext(ctx, countsSQL).Scan(
+		&stats.Objects,
+		&stats.DeletedObjects,
+		&stats.Blobs,
+		&stats.BlobBytes,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not count objects: %s", err)
+	}
+
+	for _, q := range []struct {
+		counts map[string]int
+		sql    string
+	}{
+		{
+			counts: stats.Metadata,
+			sql: `
+select processor || '/' || result, count(*)
+from blob_metadata
+group by processor, result`,
+		},
+		{
+			counts: stats.Properties,
+			sql: `
+select source, count(distinct blob_id)
+from blob_properties
+where property_type = 'Done'
+group by source`,
+		},
+		{
+			counts: stats.Errors,
+			sql: `
+select processor, count(*)
+from processing_errors
+group by processor`,
+		},
+	} {
+		err = scanCounts(ctx, txn, q.sql, q.counts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &stats, nil
+}
+
+func scanCounts(ctx context.Context, txn *sql.Tx, query string, counts map[string]int) error {
+	rows, err := txn.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("could not query counts: %s", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		var count int
+		err = rows.Scan(&name, &count)
+		if err != nil {
+			return fmt.Errorf("could not scan count: %s", err)
+		}
+
+		counts[name] = count
+	}
+
+	return rows.Err()
+}
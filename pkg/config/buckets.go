@@ -0,0 +1,191 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/minio/minio-go/v7/pkg/s3utils"
+
+	"github.com/charlieegan3/storage-console/pkg/layout"
+)
+
+// bucketNamePattern limits bucket names to those safe to use in URLs
+var bucketNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,62}$`)
+
+// resolveBuckets validates the configured buckets and fills in connection
+// settings from s3. When no buckets are configured, the one from s3 is used.
+func resolveBuckets(s3 S3, buckets []Bucket) ([]Bucket, error) {
+	if len(buckets) == 0 {
+		err := validateConnection("s3", s3)
+		if err != nil {
+			return nil, err
+		}
+
+		err = validateBucketName("s3", s3.BucketName)
+		if err != nil {
+			return nil, err
+		}
+
+		s3, err = resolveLayout("s3", s3)
+		if err != nil {
+			return nil, err
+		}
+
+		return []Bucket{{Name: s3.BucketName, Provider: "s3", Default: true, S3: s3}}, nil
+	}
+
+	var resolved []Bucket
+	var sharedChecked bool
+	names := make(map[string]bool)
+	defaultIndex := -1
+
+	for i, b := range buckets {
+		key := fmt.Sprintf("buckets[%d]", i)
+
+		switch b.Provider {
+		case "", "s3":
+			b.Provider = "s3"
+		default:
+			return nil, fmt.Errorf("%s.provider %q is unsupported, must be s3", key, b.Provider)
+		}
+
+		if b.BucketName == "" {
+			return nil, fmt.Errorf("%s.bucket_name is required", key)
+		}
+
+		err := validateBucketName(key, b.BucketName)
+		if err != nil {
+			return nil, err
+		}
+
+		if b.Name == "" {
+			b.Name = b.BucketName
+		}
+
+		if !bucketNamePattern.MatchString(b.Name) {
+			return nil, fmt.Errorf("%s.name %q must match %s", key, b.Name, bucketNamePattern)
+		}
+
+		if names[b.Name] {
+			return nil, fmt.Errorf("%s.name %q is used by another bucket", key, b.Name)
+		}
+		names[b.Name] = true
+
+		if b.Default {
+			if defaultIndex >= 0 {
+				return nil, fmt.Errorf("%s and buckets[%d] are both the default", key, defaultIndex)
+			}
+			defaultIndex = i
+		}
+
+		if b.Endpoint != "" {
+			err = validateConnection(key, b.S3)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			if !sharedChecked {
+				err = validateConnection("s3", s3)
+				if err != nil {
+					return nil, err
+				}
+				sharedChecked = true
+			}
+
+			// only the connection is shared, the layout is the bucket's own
+			own := b.S3
+			b.S3 = s3
+			b.BucketName = own.BucketName
+			b.DataPrefix = own.DataPrefix
+			b.MetaPrefix = own.MetaPrefix
+			b.MetaBucket = own.MetaBucket
+		}
+
+		b.S3, err = resolveLayout(key, b.S3)
+		if err != nil {
+			return nil, err
+		}
+
+		resolved = append(resolved, b)
+	}
+
+	if defaultIndex < 0 {
+		resolved[0].Default = true
+	}
+
+	return resolved, nil
+}
+
+// resolveLayout applies the default prefixes and checks that objects and
+// processor output are kept apart
+func resolveLayout(key string, s3 S3) (S3, error) {
+	if s3.DataPrefix == "" {
+		s3.DataPrefix = layout.Default.DataPrefix
+	} else {
+		s3.DataPrefix = layout.Prefix(s3.DataPrefix)
+	}
+
+	if s3.MetaPrefix == "" {
+		s3.MetaPrefix = layout.Default.MetaPrefix
+	} else {
+		s3.MetaPrefix = layout.Prefix(s3.MetaPrefix)
+	}
+
+	if s3.MetaBucket == s3.BucketName {
+		s3.MetaBucket = ""
+	}
+
+	if s3.MetaBucket != "" {
+		if err := s3utils.CheckValidBucketName(s3.MetaBucket); err != nil {
+			return S3{}, fmt.Errorf("%s.meta_bucket %q is invalid: %s", key, s3.MetaBucket, err)
+		}
+	}
+
+	if s3.MetaBucket == "" && s3.Layout().Overlaps() {
+		return S3{}, fmt.Errorf(
+			"%s.data_prefix %q and %s.meta_prefix %q overlap, set %s.meta_bucket or use separate prefixes",
+			key, s3.DataPrefix, key, s3.MetaPrefix, key,
+		)
+	}
+
+	return s3, nil
+}
+
+// Layout returns where objects and processor output are stored
+func (s S3) Layout() *layout.Layout {
+	return &layout.Layout{
+		DataPrefix: s.DataPrefix,
+		MetaBucket: s.MetaBucket,
+		MetaPrefix: s.MetaPrefix,
+	}
+}
+
+func validateBucketName(key, bucketName string) error {
+	if err := s3utils.CheckValidBucketName(bucketName); err != nil {
+		return fmt.Errorf("%s.bucket_name %q is invalid: %s", key, bucketName, err)
+	}
+
+	return nil
+}
+
+func validateConnection(key string, s3 S3) error {
+	switch s3.BucketLookup {
+	case "", "auto", "path", "dns":
+	default:
+		return fmt.Errorf("%s.bucket_lookup %q is unsupported, must be auto, path or dns", key, s3.BucketLookup)
+	}
+
+	if (s3.AccessKey == "") != (s3.SecretKey == "") {
+		return fmt.Errorf("%s.access_key and %s.secret_key must be set together", key, key)
+	}
+
+	if s3.SessionToken != "" && s3.AccessKey == "" {
+		return fmt.Errorf("%s.session_token requires %s.access_key and %s.secret_key", key, key, key)
+	}
+
+	if s3.CAFile != "" && !s3.Secure {
+		return fmt.Errorf("%s.ca_file requires %s.secure to be true", key, key)
+	}
+
+	return nil
+}
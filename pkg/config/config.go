@@ -1,26 +1,155 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/url"
 	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/charlieegan3/storage-console/pkg/logging"
 )
 
 type Config struct {
 	Server   Server   `yaml:"server"`
 	Database Database `yaml:"database"`
 	S3       S3       `yaml:"object_storage"`
+	// Buckets always has at least one entry, when none are configured the
+	// bucket from S3 is used
+	Buckets []Bucket `yaml:"buckets"`
+
+	Thumbnails Thumbnails `yaml:"thumbnails"`
+	Processors Processors `yaml:"processors"`
+}
+
+// Thumbnails configures the renditions generated for images. Each size is
+// the length of the longest side, and the browse pages pick between them
+// with srcset.
+type Thumbnails struct {
+	Sizes   []int  `yaml:"sizes"`
+	Format  string `yaml:"format"`
+	Quality int    `yaml:"quality"`
+}
+
+// DefaultThumbnailSizes are used when no sizes are configured, and by the
+// thumbnail processor when it isn't given any
+var DefaultThumbnailSizes = []int{300, 1200}
+
+type Processors struct {
+	OCR      OCR                `yaml:"ocr"`
+	Tags     Tags               `yaml:"tags"`
+	Places   Places             `yaml:"places"`
+	Commands []CommandProcessor `yaml:"commands"`
+	Webhooks []WebhookProcessor `yaml:"webhooks"`
 }
 
+// OCR configures text recognition with tesseract, which runs when the
+// command is installed
+type OCR struct {
+	Command   string `yaml:"command"`
+	Languages string `yaml:"languages"`
+	MaxPages  int    `yaml:"max_pages"`
+}
+
+// Tags configures the generic tags processor, which stores every EXIF, IPTC
+// and XMP tag as a property
+type Tags struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// Places configures reverse geocoding of GPS coordinates. A bundled
+// gazetteer of larger cities is used unless a GeoNames cities file, such as
+// cities15000.txt, is set.
+type Places struct {
+	Cities string `yaml:"cities"`
+	// Admin1Codes is the GeoNames admin1CodesASCII.txt file used to name
+	// regions
+	Admin1Codes string  `yaml:"admin1_codes"`
+	MaxDistance float64 `yaml:"max_distance_km"`
+}
+
+// CommandProcessor runs an external executable for each blob with one of
+// the listed content types. The executable must write a JSON object to
+// stdout.
+type CommandProcessor struct {
+	Name    string   `yaml:"name"`
+	Command []string `yaml:"command"`
+	// Input is "stdin" (the default) or "file" to pass a temp file path as
+	// the last argument
+	Input        string        `yaml:"input"`
+	Timeout      time.Duration `yaml:"timeout"`
+	ContentTypes []string      `yaml:"content_types"`
+	// Version should be increased when the command's output changes so that
+	// existing blobs are processed again
+	Version int `yaml:"version"`
+}
+
+// WebhookProcessor posts each blob with one of the listed content types to
+// a remote service, which must respond with a JSON object.
+type WebhookProcessor struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	// Send is "body" (the default) to post the blob content, or "url" to
+	// post JSON containing a presigned URL to the blob
+	Send        string            `yaml:"send"`
+	Headers     map[string]string `yaml:"headers"`
+	Timeout     time.Duration     `yaml:"timeout"`
+	Retries     int               `yaml:"retries"`
+	Concurrency int               `yaml:"concurrency"`
+	// Version should be increased when the service's output changes so that
+	// existing blobs are processed again
+	Version int `yaml:"version"`
+
+	ContentTypes []string `yaml:"content_types"`
+}
+
+var processorNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// identifierPattern limits schema and table names to unquoted postgres
+// identifiers, since they are used in SQL
+var identifierPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
+const defaultSchemaName = "storage_console"
+
+// defaultMigrationsSchema is where migrations tables were created before
+// database.migrations_schema could be set, the default search path
+const defaultMigrationsSchema = "public"
+
+// S3 configures the object storage client. When AccessKey is empty,
+// credentials are found from the AWS_* or MINIO_* environment variables,
+// ~/.aws/credentials or the instance's IAM role, in that order.
 type S3 struct {
-	Endpoint   string `yaml:"endpoint"`
-	AccessKey  string `yaml:"access_key"`
-	SecretKey  string `yaml:"secret_key"`
-	BucketName string `yaml:"bucket_name"`
+	Endpoint     string `yaml:"endpoint"`
+	AccessKey    string `yaml:"access_key"`
+	SecretKey    string `yaml:"secret_key"`
+	SessionToken string `yaml:"session_token"`
+	BucketName   string `yaml:"bucket_name"`
+
+	// Secure uses https to connect to the endpoint
+	Secure bool   `yaml:"secure"`
+	Region string `yaml:"region"`
+	// CAFile is a PEM file of certificates trusted in addition to the
+	// system's, for endpoints with a private CA
+	CAFile string `yaml:"ca_file"`
+	// BucketLookup is "path", "dns" or "auto" (the default), which uses
+	// DNS for known providers such as AWS and path style otherwise
+	BucketLookup string `yaml:"bucket_lookup"`
+
+	// DataPrefix is where objects are stored in the bucket, data/ by
+	// default. "/" is the root of the bucket.
+	DataPrefix string `yaml:"data_prefix"`
+	// MetaPrefix is where processor output is stored, meta/ by default
+	MetaPrefix string `yaml:"meta_prefix"`
+	// MetaBucket stores processor output in another bucket using the same
+	// connection, so that the data bucket is only read from
+	MetaBucket string `yaml:"meta_bucket"`
 }
 
 type Server struct {
@@ -30,57 +159,160 @@ type Server struct {
 
 	RegisterMux bool `yaml:"register_mux"`
 	RunImporter bool `yaml:"run_importer"`
+	// ReadyAfterImport reports the server as not ready until the import
+	// started by RunImporter has finished
+	ReadyAfterImport bool `yaml:"ready_after_import"`
+
+	// Logger writes to the configured output, it's never nil
+	Logger *slog.Logger
+	Log    Log
+
+	Metrics Metrics
+	Tracing Tracing
+}
+
+// Metrics serves Prometheus metrics at Path, behind the console's
+// authentication, or without it on a separate listener when Port is set
+type Metrics struct {
+	Enabled bool
+	Path    string
+	Address string
+	Port    int
+}
 
-	LoggerError *log.Logger
-	LoggerInfo  *log.Logger
+// Tracing exports OpenTelemetry spans to a collector over OTLP/HTTP
+type Tracing struct {
+	Enabled bool
+	// Endpoint is the collector's URL, such as http://localhost:4318. The
+	// OTEL_EXPORTER_OTLP_* environment variables are used when it's empty.
+	Endpoint    string
+	ServiceName string
+	// SampleRatio is the fraction of traces recorded, between 0 and 1
+	SampleRatio float64
+}
+
+// Log is the logging config, for commands which build their own logger
+type Log struct {
+	Level  slog.Level
+	Format string
 }
 
 type Database struct {
 	ConnectionString string `yaml:"connection_string"`
 	SchemaName       string `yaml:"schema_name"`
 	MigrationsTable  string `yaml:"migrations_table"`
+	// MigrationsSchema holds the migrations table, it's set explicitly
+	// since connections can be left using SchemaName
+	MigrationsSchema string `yaml:"migrations_schema"`
 }
 
+// Bucket is browsed under /b/<name>/, with its objects under data/ and
+// processor output under meta/ unless the prefixes are set. Buckets without
+// an endpoint use the connection settings from s3, otherwise all of them
+// are their own.
 type Bucket struct {
+	// Name is used in URLs and to scope the database, it defaults to the
+	// bucket name
+	Name string `yaml:"name"`
+	// Provider is the storage backend, only s3 is supported
 	Provider string `yaml:"provider"`
-	Default  bool   `yaml:"default"`
+	// Default is the bucket shown by /b/, and which is given any objects
+	// imported before buckets were configured
+	Default bool `yaml:"default"`
+
+	S3 `yaml:",inline"`
 }
 
-func LoadConfig(rawConfig io.Reader) (*Config, error) {
-	config := struct {
-		Server struct {
-			Port    int    `yaml:"port"`
+// rawConfig is the config file format. Each scalar field can also be set
+// with an environment variable, see doc.go.
+type rawConfig struct {
+	Server struct {
+		Port    int    `yaml:"port"`
+		Address string `yaml:"address"`
+		DevMode bool   `yaml:"dev_mode"`
+
+		RunImporter      bool `yaml:"run_importer"`
+		RegisterMux      bool `yaml:"register_mux"`
+		ReadyAfterImport bool `yaml:"ready_after_import"`
+
+		Log struct {
+			Level  string `yaml:"level"`
+			Format string `yaml:"format"`
+			Output string `yaml:"output"`
+
+			// Info set to stdout is the same as output: stdout, these are
+			// from before logs were leveled
+			Error string `yaml:"error"`
+			Info  string `yaml:"info"`
+		} `yaml:"log"`
+
+		Metrics struct {
+			Enabled bool   `yaml:"enabled"`
+			Path    string `yaml:"path"`
 			Address string `yaml:"address"`
-			DevMode bool   `yaml:"dev_mode"`
-
-			RunImporter bool `yaml:"run_importer"`
-			RegisterMux bool `yaml:"register_mux"`
-
-			Log struct {
-				Error string `yaml:"error"`
-				Info  string `yaml:"info"`
-			} `yaml:"log"`
-		} `yaml:"server"`
-		Database struct {
-			ConnectionString string            `yaml:"connection_string"`
-			Params           map[string]string `yaml:"params"`
-			SchemaName       string            `yaml:"schema_name"`
-			MigrationsTable  string            `yaml:"migrations_table"`
-		}
-		S3 S3 `yaml:"s3"`
-	}{}
-	if err := yaml.NewDecoder(rawConfig).Decode(&config); err != nil {
+			Port    int    `yaml:"port"`
+		} `yaml:"metrics"`
+
+		Tracing struct {
+			Enabled     bool    `yaml:"enabled"`
+			Endpoint    string  `yaml:"endpoint"`
+			ServiceName string  `yaml:"service_name"`
+			SampleRatio float64 `yaml:"sample_ratio"`
+		} `yaml:"tracing"`
+	} `yaml:"server"`
+	Database struct {
+		ConnectionString string            `yaml:"connection_string"`
+		Params           map[string]string `yaml:"params"`
+		SchemaName       string            `yaml:"schema_name"`
+		MigrationsTable  string            `yaml:"migrations_table"`
+		MigrationsSchema string            `yaml:"migrations_schema"`
+	} `yaml:"database"`
+	S3         S3         `yaml:"s3"`
+	Buckets    []Bucket   `yaml:"buckets"`
+	Thumbnails Thumbnails `yaml:"thumbnails"`
+	Processors Processors `yaml:"processors"`
+}
+
+// LoadConfig reads YAML config, expanding ${VAR} references and applying
+// environment variable overrides, then validates the result.
+func LoadConfig(rawConfig io.Reader) (*Config, error) {
+	return loadConfig(rawConfig, lookupEnv(os.LookupEnv))
+}
+
+func loadConfig(r io.Reader, lookup lookupFunc) (*Config, error) {
+	var config rawConfig
+
+	var doc yaml.Node
+	err := yaml.NewDecoder(r).Decode(&doc)
+	if err != nil && !errors.Is(err, io.EOF) {
 		return nil, fmt.Errorf("failed to decode config: %w", err)
 	}
 
-	var loggerError *log.Logger
-	if config.Server.Log.Error == "stderr" {
-		loggerError = log.New(os.Stderr, "", log.LstdFlags)
+	if err == nil {
+		err = expandNode(&doc, lookup)
+		if err != nil {
+			return nil, err
+		}
+
+		err = doc.Decode(&config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode config: %w", err)
+		}
+	}
+
+	err = applyEnv(&config, lookup)
+	if err != nil {
+		return nil, err
 	}
 
-	var loggerInfo *log.Logger
-	if config.Server.Log.Info == "stdout" {
-		loggerInfo = log.New(os.Stdout, "", log.LstdFlags)
+	logConfig, logger, err := loadLog(
+		config.Server.Log.Level,
+		config.Server.Log.Format,
+		config.Server.Log.Output,
+		config.Server.Log.Info,
+	)
+	if err != nil {
+		return nil, err
 	}
 
 	var db Database
@@ -96,19 +328,259 @@ func LoadConfig(rawConfig io.Reader) (*Config, error) {
 	}
 
 	db.SchemaName = config.Database.SchemaName
+	if db.SchemaName == "" {
+		db.SchemaName = defaultSchemaName
+	}
 	db.MigrationsTable = config.Database.MigrationsTable
+	// instances sharing a database each need their own migration history
+	if db.MigrationsTable == "" && db.SchemaName != defaultSchemaName {
+		db.MigrationsTable = "schema_migrations_" + db.SchemaName
+	}
+	db.MigrationsSchema = config.Database.MigrationsSchema
+	if db.MigrationsSchema == "" {
+		db.MigrationsSchema = defaultMigrationsSchema
+	}
+
+	required := map[string]string{
+		"database.connection_string": config.Database.ConnectionString,
+	}
+
+	// the s3 connection is only needed for buckets without their own
+	sharedConnection := len(config.Buckets) == 0
+	for _, b := range config.Buckets {
+		if b.Endpoint == "" {
+			sharedConnection = true
+		}
+	}
+
+	if sharedConnection {
+		required["s3.endpoint"] = config.S3.Endpoint
+	}
+
+	if len(config.Buckets) == 0 {
+		required["s3.bucket_name"] = config.S3.BucketName
+	}
+
+	var missing []string
+	for key, value := range required {
+		if value == "" {
+			missing = append(missing, fmt.Sprintf("%s (%s)", key, envName(key)))
+		}
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("missing required config: %s", strings.Join(missing, ", "))
+	}
+
+	if config.Server.Port < 0 || config.Server.Port > 65535 {
+		return nil, fmt.Errorf("server.port must be between 0 and 65535, got %d", config.Server.Port)
+	}
+
+	metrics := Metrics(config.Server.Metrics)
+	if metrics.Path == "" {
+		metrics.Path = "/metrics"
+	}
+
+	if !strings.HasPrefix(metrics.Path, "/") {
+		return nil, fmt.Errorf("server.metrics.path must start with /, got %q", metrics.Path)
+	}
+
+	if metrics.Port < 0 || metrics.Port > 65535 {
+		return nil, fmt.Errorf("server.metrics.port must be between 0 and 65535, got %d", metrics.Port)
+	}
+
+	if metrics.Port != 0 && metrics.Port == config.Server.Port {
+		return nil, fmt.Errorf("server.metrics.port must differ from server.port, unset it to serve metrics with the console")
+	}
+
+	tracing := Tracing(config.Server.Tracing)
+	if tracing.ServiceName == "" {
+		tracing.ServiceName = "storage-console"
+	}
+
+	// unset records every trace, tracing is turned off with enabled
+	if tracing.SampleRatio == 0 {
+		tracing.SampleRatio = 1
+	}
+
+	if tracing.SampleRatio < 0 || tracing.SampleRatio > 1 {
+		return nil, fmt.Errorf("server.tracing.sample_ratio must be between 0 and 1, got %g", tracing.SampleRatio)
+	}
+
+	if tracing.Endpoint != "" {
+		u, err := url.Parse(tracing.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("server.tracing.endpoint must be an http or https url, got %q", tracing.Endpoint)
+		}
+	}
+
+	if !identifierPattern.MatchString(db.SchemaName) {
+		return nil, fmt.Errorf("database.schema_name %q must match %s", db.SchemaName, identifierPattern)
+	}
+
+	if db.MigrationsTable != "" && !identifierPattern.MatchString(db.MigrationsTable) {
+		return nil, fmt.Errorf("database.migrations_table %q must match %s", db.MigrationsTable, identifierPattern)
+	}
+
+	if !identifierPattern.MatchString(db.MigrationsSchema) {
+		return nil, fmt.Errorf("database.migrations_schema %q must match %s", db.MigrationsSchema, identifierPattern)
+	}
+
+	buckets, err := resolveBuckets(config.S3, config.Buckets)
+	if err != nil {
+		return nil, err
+	}
+
+	thumbnails := config.Thumbnails
+	if len(thumbnails.Sizes) == 0 {
+		thumbnails.Sizes = DefaultThumbnailSizes
+	}
+	if thumbnails.Format == "" {
+		thumbnails.Format = "jpeg"
+	}
+
+	for _, size := range thumbnails.Sizes {
+		if size <= 0 {
+			return nil, fmt.Errorf("thumbnail sizes must be positive, got %d", size)
+		}
+	}
+
+	switch thumbnails.Format {
+	case "jpeg", "webp", "avif":
+	default:
+		return nil, fmt.Errorf("unsupported thumbnail format %q, must be jpeg, webp or avif", thumbnails.Format)
+	}
+
+	if thumbnails.Quality < 0 || thumbnails.Quality > 100 {
+		return nil, fmt.Errorf("thumbnail quality must be between 0 and 100, got %d", thumbnails.Quality)
+	}
+
+	if config.Processors.OCR.MaxPages < 0 {
+		return nil, fmt.Errorf("ocr max_pages must not be negative")
+	}
+
+	if config.Processors.Places.MaxDistance < 0 {
+		return nil, fmt.Errorf("places max_distance_km must not be negative")
+	}
+
+	if config.Processors.Places.Admin1Codes != "" && config.Processors.Places.Cities == "" {
+		return nil, fmt.Errorf("places admin1_codes requires cities to be set")
+	}
+
+	for i, c := range config.Processors.Commands {
+		if !processorNamePattern.MatchString(c.Name) {
+			return nil, fmt.Errorf("command processor %d has invalid name %q, must match %s", i, c.Name, processorNamePattern)
+		}
+
+		if len(c.Command) == 0 {
+			return nil, fmt.Errorf("command processor %s must set a command", c.Name)
+		}
+
+		switch c.Input {
+		case "", "stdin", "file":
+		default:
+			return nil, fmt.Errorf("command processor %s has unsupported input %q, must be stdin or file", c.Name, c.Input)
+		}
+
+		if len(c.ContentTypes) == 0 {
+			return nil, fmt.Errorf("command processor %s must list content types", c.Name)
+		}
+
+		if c.Timeout < 0 {
+			return nil, fmt.Errorf("command processor %s has negative timeout", c.Name)
+		}
+
+		if c.Version < 0 {
+			return nil, fmt.Errorf("command processor %s has negative version", c.Name)
+		}
+	}
+
+	for i, w := range config.Processors.Webhooks {
+		if !processorNamePattern.MatchString(w.Name) {
+			return nil, fmt.Errorf("webhook processor %d has invalid name %q, must match %s", i, w.Name, processorNamePattern)
+		}
+
+		u, err := url.Parse(w.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("webhook processor %s must set an http or https url", w.Name)
+		}
+
+		switch w.Send {
+		case "", "body", "url":
+		default:
+			return nil, fmt.Errorf("webhook processor %s has unsupported send mode %q, must be body or url", w.Name, w.Send)
+		}
+
+		if len(w.ContentTypes) == 0 {
+			return nil, fmt.Errorf("webhook processor %s must list content types", w.Name)
+		}
+
+		if w.Timeout < 0 || w.Retries < 0 || w.Concurrency < 0 || w.Version < 0 {
+			return nil, fmt.Errorf("webhook processor %s has a negative timeout, retries, concurrency or version", w.Name)
+		}
+	}
 
 	return &Config{
 		Server: Server{
 			Port:        config.Server.Port,
 			Address:     config.Server.Address,
 			DevMode:     config.Server.DevMode,
-			LoggerError: loggerError,
-			LoggerInfo:  loggerInfo,
+			Logger:      logger,
+			Log:         logConfig,
+			Metrics:     metrics,
+			Tracing:     tracing,
 			RegisterMux: config.Server.RegisterMux,
 			RunImporter: config.Server.RunImporter,
+
+			ReadyAfterImport: config.Server.ReadyAfterImport,
 		},
-		Database: db,
-		S3:       config.S3,
+		Database:   db,
+		S3:         config.S3,
+		Buckets:    buckets,
+		Thumbnails: thumbnails,
+		Processors: config.Processors,
 	}, nil
 }
+
+// loadLog builds the logger from the server.log keys. The deprecated info
+// key sends logs to stdout when output isn't set.
+func loadLog(level, format, output, legacyInfo string) (Log, *slog.Logger, error) {
+	var cfg Log
+
+	if level == "" {
+		level = "info"
+	}
+
+	var err error
+	cfg.Level, err = logging.ParseLevel(level)
+	if err != nil {
+		return Log{}, nil, fmt.Errorf("server.log.level: %w", err)
+	}
+
+	cfg.Format = format
+	if cfg.Format == "" {
+		cfg.Format = "text"
+	}
+
+	if output == "" && legacyInfo == "stdout" {
+		output = "stdout"
+	}
+
+	var w io.Writer
+	switch output {
+	case "", "stderr":
+		w = os.Stderr
+	case "stdout":
+		w = os.Stdout
+	default:
+		return Log{}, nil, fmt.Errorf("server.log.output %q is unsupported, must be stdout or stderr", output)
+	}
+
+	logger, err := logging.New(w, cfg.Level, cfg.Format)
+	if err != nil {
+		return Log{}, nil, fmt.Errorf("server.log.format: %w", err)
+	}
+
+	return cfg, logger, nil
+}
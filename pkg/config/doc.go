@@ -0,0 +1,125 @@
+/*
+Package config loads the storage console's YAML config.
+
+Values in the file may reference environment variables as ${VAR}, or
+${VAR:-default} to fall back to a default when VAR is unset. A literal $ is
+written as $$. References are expanded after the file is parsed, so secrets
+containing YAML syntax don't need quoting.
+
+Every scalar key can also be set with an environment variable, which takes
+precedence over the file. Lists are comma separated. Buckets, command and
+webhook processors and database params can only be set in the file.
+
+	server.port                        STORAGE_CONSOLE_SERVER_PORT
+	server.address                     STORAGE_CONSOLE_SERVER_ADDRESS
+	server.dev_mode                    STORAGE_CONSOLE_SERVER_DEV_MODE
+	server.run_importer                STORAGE_CONSOLE_SERVER_RUN_IMPORTER
+	server.register_mux                STORAGE_CONSOLE_SERVER_REGISTER_MUX
+	server.ready_after_import          STORAGE_CONSOLE_SERVER_READY_AFTER_IMPORT
+	server.log.level                   STORAGE_CONSOLE_SERVER_LOG_LEVEL
+	server.log.format                  STORAGE_CONSOLE_SERVER_LOG_FORMAT
+	server.log.output                  STORAGE_CONSOLE_SERVER_LOG_OUTPUT
+	server.log.error                   STORAGE_CONSOLE_SERVER_LOG_ERROR
+	server.log.info                    STORAGE_CONSOLE_SERVER_LOG_INFO
+	server.metrics.enabled             STORAGE_CONSOLE_SERVER_METRICS_ENABLED
+	server.metrics.path                STORAGE_CONSOLE_SERVER_METRICS_PATH
+	server.metrics.address             STORAGE_CONSOLE_SERVER_METRICS_ADDRESS
+	server.metrics.port                STORAGE_CONSOLE_SERVER_METRICS_PORT
+	server.tracing.enabled             STORAGE_CONSOLE_SERVER_TRACING_ENABLED
+	server.tracing.endpoint            STORAGE_CONSOLE_SERVER_TRACING_ENDPOINT
+	server.tracing.service_name        STORAGE_CONSOLE_SERVER_TRACING_SERVICE_NAME
+	server.tracing.sample_ratio        STORAGE_CONSOLE_SERVER_TRACING_SAMPLE_RATIO
+	database.connection_string         STORAGE_CONSOLE_DATABASE_CONNECTION_STRING
+	database.schema_name               STORAGE_CONSOLE_DATABASE_SCHEMA_NAME
+	database.migrations_table          STORAGE_CONSOLE_DATABASE_MIGRATIONS_TABLE
+	database.migrations_schema         STORAGE_CONSOLE_DATABASE_MIGRATIONS_SCHEMA
+	s3.endpoint                        STORAGE_CONSOLE_S3_ENDPOINT
+	s3.access_key                      STORAGE_CONSOLE_S3_ACCESS_KEY
+	s3.secret_key                      STORAGE_CONSOLE_S3_SECRET_KEY
+	s3.session_token                   STORAGE_CONSOLE_S3_SESSION_TOKEN
+	s3.bucket_name                     STORAGE_CONSOLE_S3_BUCKET_NAME
+	s3.secure                          STORAGE_CONSOLE_S3_SECURE
+	s3.region                          STORAGE_CONSOLE_S3_REGION
+	s3.ca_file                         STORAGE_CONSOLE_S3_CA_FILE
+	s3.bucket_lookup                   STORAGE_CONSOLE_S3_BUCKET_LOOKUP
+	s3.data_prefix                     STORAGE_CONSOLE_S3_DATA_PREFIX
+	s3.meta_prefix                     STORAGE_CONSOLE_S3_META_PREFIX
+	s3.meta_bucket                     STORAGE_CONSOLE_S3_META_BUCKET
+	thumbnails.sizes                   STORAGE_CONSOLE_THUMBNAILS_SIZES
+	thumbnails.format                  STORAGE_CONSOLE_THUMBNAILS_FORMAT
+	thumbnails.quality                 STORAGE_CONSOLE_THUMBNAILS_QUALITY
+	processors.ocr.command             STORAGE_CONSOLE_PROCESSORS_OCR_COMMAND
+	processors.ocr.languages           STORAGE_CONSOLE_PROCESSORS_OCR_LANGUAGES
+	processors.ocr.max_pages           STORAGE_CONSOLE_PROCESSORS_OCR_MAX_PAGES
+	processors.tags.enabled            STORAGE_CONSOLE_PROCESSORS_TAGS_ENABLED
+	processors.places.cities           STORAGE_CONSOLE_PROCESSORS_PLACES_CITIES
+	processors.places.admin1_codes     STORAGE_CONSOLE_PROCESSORS_PLACES_ADMIN1_CODES
+	processors.places.max_distance_km  STORAGE_CONSOLE_PROCESSORS_PLACES_MAX_DISTANCE_KM
+
+Any variable, whether referenced from the file or listed above, can instead
+be read from a file by setting VAR_FILE to its path, for example
+STORAGE_CONSOLE_S3_SECRET_KEY_FILE=/run/secrets/s3_secret_key. Trailing
+newlines are removed.
+
+database.connection_string is required, along with s3.endpoint and
+s3.bucket_name unless buckets are configured. Buckets without an endpoint
+use the connection settings from s3:
+
+	buckets:
+	- name: photos
+	  bucket_name: family-photos
+	  default: true
+	- name: backups
+	  bucket_name: backups
+	  endpoint: s3.eu-west-2.amazonaws.com
+	  region: eu-west-2
+	  secure: true
+
+Objects are read from under data/ and processor output is written under
+meta/ in the same bucket. To browse a bucket with another layout, set
+data_prefix, or "/" for the whole bucket, and keep processor output apart
+with meta_prefix or in a meta_bucket reached with the same connection:
+
+	buckets:
+	- name: archive
+	  bucket_name: archive
+	  data_prefix: /
+	  meta_bucket: archive-console
+
+Logs are written to stderr as text at info level and above by default.
+server.log.level can be debug, info, warn or error, server.log.format text
+or json and server.log.output stdout or stderr. server.log.info: stdout is
+still read as server.log.output: stdout, server.log.error is ignored.
+
+Prometheus metrics are served at /metrics when server.metrics.enabled is
+set, behind the console's authentication. Set server.metrics.port, and
+optionally server.metrics.address, to serve them without authentication on
+a separate listener instead:
+
+	server:
+	  metrics:
+	    enabled: true
+	    address: 127.0.0.1
+	    port: 9090
+
+/healthz reports that the server is running and /readyz whether it can
+reach the database and buckets, both without authentication. Set
+server.ready_after_import to also report not ready until the import started
+by server.run_importer has finished, and while any bucket's import failed.
+
+OpenTelemetry traces of requests, object storage and database calls and
+processor runs are exported over OTLP/HTTP when server.tracing.enabled is
+set. server.tracing.endpoint is the collector's URL, the standard
+OTEL_EXPORTER_OTLP_* variables are used when it's unset. Every trace is
+recorded unless server.tracing.sample_ratio is set.
+
+database.schema_name defaults to storage_console. Several consoles can share
+a database by using different schemas, each records its migrations in
+schema_migrations_<schema_name> unless database.migrations_table is set.
+Migrations tables are kept in the public schema unless
+database.migrations_schema is set.
+
+When s3.access_key is unset, S3 credentials are found from the AWS_* or
+MINIO_* environment variables, ~/.aws/credentials or the instance's IAM role.
+*/
+package config
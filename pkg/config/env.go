@@ -0,0 +1,247 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is prepended to the environment variable for each config key
+const EnvPrefix = "STORAGE_CONSOLE_"
+
+// lookupFunc returns the value of an environment variable and whether it
+// was set
+type lookupFunc func(name string) (string, bool, error)
+
+// lookupEnv wraps getenv so that NAME_FILE can be set to the path of a file
+// containing the value for NAME, as is common with mounted secrets.
+// Trailing newlines are removed from file contents.
+func lookupEnv(getenv func(string) (string, bool)) lookupFunc {
+	return func(name string) (string, bool, error) {
+		if value, ok := getenv(name); ok {
+			return value, true, nil
+		}
+
+		path, ok := getenv(name + "_FILE")
+		if !ok {
+			return "", false, nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to read %s_FILE: %w", name, err)
+		}
+
+		return strings.TrimRight(string(content), "\r\n"), true, nil
+	}
+}
+
+var envReferencePattern = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-[^}]*)?\}`)
+
+// expandNode replaces ${VAR} and ${VAR:-default} in every scalar value with
+// the environment variable's value. $$ is a literal $. Values are expanded
+// after parsing, so they don't need to be YAML safe.
+func expandNode(node *yaml.Node, lookup lookupFunc) error {
+	if node.Kind == yaml.ScalarNode {
+		if !strings.Contains(node.Value, "$") {
+			return nil
+		}
+
+		value, err := expand(node.Value, lookup)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+
+		node.Value = value
+		// plain values are resolved again so that ${PORT} can be an int
+		if node.Style == 0 {
+			node.Tag = ""
+		}
+
+		return nil
+	}
+
+	for _, child := range node.Content {
+		err := expandNode(child, lookup)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func expand(value string, lookup lookupFunc) (string, error) {
+	var err error
+
+	expanded := envReferencePattern.ReplaceAllStringFunc(value, func(ref string) string {
+		if ref == "$$" {
+			return "$"
+		}
+
+		match := envReferencePattern.FindStringSubmatch(ref)
+		name, fallback := match[1], match[2]
+
+		v, ok, lookupErr := lookup(name)
+		if lookupErr != nil {
+			err = lookupErr
+			return ""
+		}
+
+		if ok {
+			return v
+		}
+
+		if fallback != "" {
+			return strings.TrimPrefix(fallback, ":-")
+		}
+
+		if err == nil {
+			err = fmt.Errorf("config references unset environment variable %s", name)
+		}
+
+		return ""
+	})
+
+	return expanded, err
+}
+
+// envField is a config key that can be set from the environment
+type envField struct {
+	key   string
+	env   string
+	value reflect.Value
+}
+
+// envFields lists the scalar fields of the config, along with lists of
+// scalars which are comma separated in the environment. Lists of processors
+// and maps can only be set in the file, using ${VAR} for secret values.
+func envFields(config *rawConfig) []envField {
+	var fields []envField
+
+	var walk func(v reflect.Value, path []string)
+	walk = func(v reflect.Value, path []string) {
+		for i := 0; i < v.NumField(); i++ {
+			name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("yaml"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+
+			field := v.Field(i)
+			fieldPath := append(append([]string{}, path...), name)
+
+			switch {
+			case field.Kind() == reflect.Struct:
+				walk(field, fieldPath)
+			case field.Kind() == reflect.Map:
+				continue
+			case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Struct:
+				continue
+			default:
+				fields = append(fields, envField{
+					key:   strings.Join(fieldPath, "."),
+					env:   envName(strings.Join(fieldPath, ".")),
+					value: field,
+				})
+			}
+		}
+	}
+
+	walk(reflect.ValueOf(config).Elem(), nil)
+
+	return fields
+}
+
+// applyEnv overrides config file values with any environment variables set
+func applyEnv(config *rawConfig, lookup lookupFunc) error {
+	for _, f := range envFields(config) {
+		value, ok, err := lookup(f.env)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			continue
+		}
+
+		err = setValue(f.value, value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s (%s): %w", f.key, f.env, err)
+		}
+	}
+
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func setValue(v reflect.Value, value string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(int64(d))
+
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+
+		v.SetBool(b)
+	case reflect.Int:
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+
+		v.SetInt(int64(i))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+
+		v.SetFloat(f)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+
+		list := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			err := setValue(list.Index(i), item)
+			if err != nil {
+				return err
+			}
+		}
+
+		v.Set(list)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+// envName returns the environment variable for a dotted config key
+func envName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
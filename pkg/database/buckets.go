@@ -0,0 +1,50 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// EnsureBuckets creates a row for each named bucket and returns their ids by
+// name. Objects imported before buckets were configured are given to the
+// default bucket, unless it already has a row of its own.
+func EnsureBuckets(db *sql.DB, schema string, names []string, defaultName string) (map[string]int, error) {
+	txn, err := NewTxnWithSchema(db, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer txn.Rollback()
+
+	claimSQL := `
+update buckets set name = $1
+where name is null
+  and not exists (select 1 from buckets where name = $1)`
+
+	_, err = txn.Exec(claimSQL, defaultName)
+	if err != nil {
+		return nil, fmt.Errorf("could not claim unnamed bucket: %w", err)
+	}
+
+	ids := make(map[string]int)
+	for _, name := range names {
+		upsertSQL := `
+insert into buckets (name) values ($1)
+on conflict (name) do update set name = excluded.name
+returning id`
+
+		var id int
+		err = txn.QueryRow(upsertSQL, name).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("could not create bucket %s: %w", name, err)
+		}
+
+		ids[name] = id
+	}
+
+	err = txn.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("could not commit buckets: %w", err)
+	}
+
+	return ids, nil
+}
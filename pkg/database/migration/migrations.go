@@ -1,20 +1,30 @@
 package migration
 
 import (
+	"context"
 	"database/sql"
 	"embed"
+	"errors"
 	"fmt"
+	"io/fs"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/lib/pq"
+
+	"github.com/charlieegan3/storage-console/pkg/database"
 )
 
 //go:embed migrations
 var migrations embed.FS
 
-func Up(db *sql.DB, cfg *postgres.Config) error {
-	m, err := buildMigrationsDriver(db, cfg)
+// bucketsVersion scoped objects and blobs to a bucket, it can only be rolled
+// back while they all belong to one bucket
+const bucketsVersion = 12
+
+func Up(db *sql.DB, schema string, cfg *postgres.Config) error {
+	m, err := buildMigrationsDriver(db, schema, cfg)
 	if err != nil {
 		return fmt.Errorf("failed to build database driver to run up migrations: %w", err)
 	}
@@ -27,7 +37,14 @@ func Up(db *sql.DB, cfg *postgres.Config) error {
 	return nil
 }
 
-func buildMigrationsDriver(db *sql.DB, cfg *postgres.Config) (*migrate.Migrate, error) {
+// buildMigrationsDriver loads the migrations, which are rendered with the
+// schema name as they're run
+func buildMigrationsDriver(db *sql.DB, schema string, cfg *postgres.Config) (*migrate.Migrate, error) {
+	err := database.CheckSchemaName(schema)
+	if err != nil {
+		return nil, err
+	}
+
 	driver, err := postgres.WithInstance(db, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("error creating database driver: %w", err)
@@ -38,7 +55,10 @@ func buildMigrationsDriver(db *sql.DB, cfg *postgres.Config) (*migrate.Migrate,
 		return nil, fmt.Errorf("error loading migrations source: %w", err)
 	}
 
-	m, err := migrate.NewWithInstance("iofs", source, "postgres", driver)
+	m, err := migrate.NewWithInstance("iofs", &templateSource{
+		Driver: source,
+		data:   templateData{SchemaName: schema},
+	}, "postgres", driver)
 	if err != nil {
 		return nil, fmt.Errorf("error loading migrations instance: %w", err)
 	}
@@ -46,12 +66,17 @@ func buildMigrationsDriver(db *sql.DB, cfg *postgres.Config) (*migrate.Migrate,
 	return m, nil
 }
 
-func Down(db *sql.DB, cfg *postgres.Config) error {
-	m, err := buildMigrationsDriver(db, cfg)
+func Down(db *sql.DB, schema string, cfg *postgres.Config) error {
+	m, err := buildMigrationsDriver(db, schema, cfg)
 	if err != nil {
 		return fmt.Errorf("failed to build database driver to run down migrations: %w", err)
 	}
 
+	err = checkRollback(m, db, schema, 0)
+	if err != nil {
+		return err
+	}
+
 	err = m.Down()
 	if err != nil && err.Error() != migrate.ErrNoChange.Error() {
 		return fmt.Errorf("failed to run database down migrations: %w", err)
@@ -60,13 +85,162 @@ func Down(db *sql.DB, cfg *postgres.Config) error {
 	return nil
 }
 
-func Cycle(db *sql.DB, cfg *postgres.Config) error {
-	err := Down(db, cfg)
+// Steps applies the next n migrations, or rolls back the last n when n is
+// negative
+func Steps(db *sql.DB, schema string, cfg *postgres.Config, n int) error {
+	m, err := buildMigrationsDriver(db, schema, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to build database driver to step migrations: %w", err)
+	}
+
+	if n < 0 {
+		version, _, err := m.Version()
+		if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+			return fmt.Errorf("failed to get database migration version: %w", err)
+		}
+
+		err = checkRollback(m, db, schema, int(version)+n)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = m.Steps(n)
+	if err != nil && err.Error() != migrate.ErrNoChange.Error() {
+		return fmt.Errorf("failed to step database migrations: %w", err)
+	}
+
+	return nil
+}
+
+// checkRollback refuses to roll back to target while that would restore
+// constraints that the rows from several buckets break, since the migration
+// would fail and leave the database dirty
+func checkRollback(m *migrate.Migrate, db *sql.DB, schema string, target int) error {
+	version, _, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get database migration version: %w", err)
+	}
+
+	if version < bucketsVersion || target >= bucketsVersion {
+		return nil
+	}
+
+	var buckets int
+	err = db.QueryRow(fmt.Sprintf(`
+select count(*)
+from (select bucket_id from %[1]s.objects union select bucket_id from %[1]s.blobs) as bucket_ids`,
+		schema,
+	)).Scan(&buckets)
+	if err != nil {
+		return fmt.Errorf("failed to count buckets: %w", err)
+	}
+
+	if buckets > 1 {
+		return fmt.Errorf(
+			"cannot roll back migration %d while objects and blobs belong to %d buckets, delete all but one from %s.buckets first",
+			bucketsVersion, buckets, schema,
+		)
+	}
+
+	return nil
+}
+
+// Version returns the version of the last applied migration and whether it
+// failed part way through, 0 is returned when none have been applied
+func Version(db *sql.DB, schema string, cfg *postgres.Config) (uint, bool, error) {
+	m, err := buildMigrationsDriver(db, schema, cfg)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to build database driver to get migration version: %w", err)
+	}
+
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get database migration version: %w", err)
+	}
+
+	return version, dirty, nil
+}
+
+// Latest returns the version of the newest migration, which the database
+// is at once Up has run
+func Latest() (uint, error) {
+	source, err := iofs.New(migrations, "migrations")
+	if err != nil {
+		return 0, fmt.Errorf("error loading migrations source: %w", err)
+	}
+	defer source.Close()
+
+	version, err := source.First()
+	if err != nil {
+		return 0, fmt.Errorf("failed to find first migration: %w", err)
+	}
+
+	for {
+		next, err := source.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to find migration after %d: %w", version, err)
+		}
+
+		version = next
+	}
+}
+
+// AppliedVersion is Version for callers sharing db, such as health checks.
+// It reads the migrations table directly since the migrate driver holds a
+// connection until it's closed, which also closes db. cfg.SchemaName is
+// required since pooled connections can have had their schema changed.
+func AppliedVersion(ctx context.Context, db *sql.DB, cfg *postgres.Config) (uint, bool, error) {
+	if cfg.SchemaName == "" {
+		return 0, false, fmt.Errorf("migrations schema name is required")
+	}
+
+	table := cfg.MigrationsTable
+	if table == "" {
+		table = postgres.DefaultMigrationsTable
+	}
+
+	var version int64
+	var dirty bool
+	err := db.QueryRowContext(
+		ctx,
+		fmt.Sprintf(
+			`select version, dirty from %s.%s limit 1`,
+			pq.QuoteIdentifier(cfg.SchemaName),
+			pq.QuoteIdentifier(table),
+		),
+	).Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get database migration version: %w", err)
+	}
+
+	// -1 is recorded when rolling back the first migration fails
+	if version < 0 {
+		return 0, dirty, nil
+	}
+
+	return uint(version), dirty, nil
+}
+
+func Cycle(db *sql.DB, schema string, cfg *postgres.Config) error {
+	err := Down(db, schema, cfg)
 	if err != nil {
 		return fmt.Errorf("failed to run down migrations: %w", err)
 	}
 
-	err = Up(db, cfg)
+	err = Up(db, schema, cfg)
 	if err != nil {
 		return fmt.Errorf("failed to run up migrations: %w", err)
 	}
@@ -1 +1 @@
-DROP SCHEMA IF EXISTS storage_console CASCADE;
+DROP SCHEMA IF EXISTS {{ .SchemaName }} CASCADE;
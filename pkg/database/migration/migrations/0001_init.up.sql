@@ -1,6 +1,6 @@
-CREATE SCHEMA IF NOT EXISTS storage_console;
+CREATE SCHEMA IF NOT EXISTS {{ .SchemaName }};
 
-SET SCHEMA 'storage_console';
+SET SCHEMA '{{ .SchemaName }}';
 
 CREATE TABLE IF NOT EXISTS tasks (
   id SERIAL PRIMARY KEY,
@@ -33,7 +33,7 @@ CREATE OR REPLACE FUNCTION find_or_create_content_type(
 DECLARE
     ct_id INTEGER;
 BEGIN
-    SET SCHEMA 'storage_console';
+    SET SCHEMA '{{ .SchemaName }}';
     -- Check if the content type already exists
     SELECT id INTO ct_id
     FROM content_types
@@ -1,4 +1,4 @@
-SET SCHEMA 'storage_console';
+SET SCHEMA '{{ .SchemaName }}';
 
 ALTER TABLE blobs
 ALTER COLUMN md5 TYPE VARCHAR(32);
@@ -1,4 +1,4 @@
-SET SCHEMA 'storage_console';
+SET SCHEMA '{{ .SchemaName }}';
 
 ALTER TABLE blobs
 -- md5 values are 32 characters long, but minio used a -N when files have been
@@ -1,4 +1,4 @@
-SET SCHEMA 'storage_console';
+SET SCHEMA '{{ .SchemaName }}';
 ALTER TABLE blob_metadata
 DROP COLUMN exif,
 DROP COLUMN color;
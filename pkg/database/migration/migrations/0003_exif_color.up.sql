@@ -1,4 +1,4 @@
-SET SCHEMA 'storage_console';
+SET SCHEMA '{{ .SchemaName }}';
 ALTER TABLE blob_metadata
 ADD COLUMN exif BOOLEAN DEFAULT FALSE NOT NULL,
 ADD COLUMN color BOOLEAN DEFAULT FALSE NOT NULL;
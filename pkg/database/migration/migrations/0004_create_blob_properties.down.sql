@@ -1,4 +1,4 @@
-SET SCHEMA 'storage_console';
+SET SCHEMA '{{ .SchemaName }}';
 DROP TABLE IF EXISTS blob_properties;
 DROP TYPE IF EXISTS blob_property_type;
 DROP TYPE IF EXISTS blob_property_source;
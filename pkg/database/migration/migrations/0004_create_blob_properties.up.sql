@@ -1,4 +1,4 @@
-SET SCHEMA 'storage_console';
+SET SCHEMA '{{ .SchemaName }}';
 
 CREATE TYPE blob_property_source AS ENUM (
   'exif',
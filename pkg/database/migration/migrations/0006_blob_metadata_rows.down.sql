@@ -0,0 +1,40 @@
+SET SCHEMA '{{ .SchemaName }}';
+
+BEGIN;
+
+CREATE TYPE blob_property_source AS ENUM (
+  'exif',
+  'color'
+);
+
+DELETE FROM blob_properties
+WHERE source NOT IN ('exif', 'color');
+
+ALTER TABLE blob_properties
+ALTER COLUMN source TYPE blob_property_source USING source::blob_property_source;
+
+CREATE TABLE blob_metadata_columns (
+  id SERIAL PRIMARY KEY,
+  blob_id INTEGER NOT NULL,
+  thumbnail blob_metadata_result DEFAULT 'unknown',
+  exif blob_metadata_result DEFAULT 'unknown',
+  color blob_metadata_result DEFAULT 'unknown',
+  FOREIGN KEY (blob_id) REFERENCES blobs(id) ON DELETE CASCADE,
+  UNIQUE (blob_id)
+);
+
+INSERT INTO blob_metadata_columns (blob_id, thumbnail, exif, color)
+SELECT
+  blob_id,
+  COALESCE(MAX(result::text) FILTER (WHERE processor = 'thumbnail'), 'unknown')::blob_metadata_result,
+  COALESCE(MAX(result::text) FILTER (WHERE processor = 'exif'), 'unknown')::blob_metadata_result,
+  COALESCE(MAX(result::text) FILTER (WHERE processor = 'color'), 'unknown')::blob_metadata_result
+FROM blob_metadata
+GROUP BY blob_id;
+
+DROP TABLE blob_metadata;
+
+ALTER TABLE blob_metadata_columns
+RENAME TO blob_metadata;
+
+COMMIT;
@@ -0,0 +1,33 @@
+SET SCHEMA '{{ .SchemaName }}';
+
+BEGIN;
+
+-- results are stored as a row per processor so that new processors don't
+-- need a new column
+CREATE TABLE blob_metadata_results (
+  blob_id INTEGER NOT NULL,
+  processor TEXT NOT NULL,
+  result blob_metadata_result NOT NULL DEFAULT 'unknown',
+  PRIMARY KEY (blob_id, processor),
+  FOREIGN KEY (blob_id) REFERENCES blobs(id) ON DELETE CASCADE
+);
+
+INSERT INTO blob_metadata_results (blob_id, processor, result)
+SELECT blob_id, 'thumbnail', thumbnail FROM blob_metadata WHERE thumbnail IS NOT NULL
+UNION ALL
+SELECT blob_id, 'exif', exif FROM blob_metadata WHERE exif IS NOT NULL
+UNION ALL
+SELECT blob_id, 'color', color FROM blob_metadata WHERE color IS NOT NULL;
+
+DROP TABLE blob_metadata;
+
+ALTER TABLE blob_metadata_results
+RENAME TO blob_metadata;
+
+-- property sources are the names of registered processors
+ALTER TABLE blob_properties
+ALTER COLUMN source TYPE TEXT;
+
+DROP TYPE blob_property_source;
+
+COMMIT;
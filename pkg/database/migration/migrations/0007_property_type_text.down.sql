@@ -0,0 +1,63 @@
+SET SCHEMA '{{ .SchemaName }}';
+
+BEGIN;
+
+CREATE TYPE blob_property_type AS ENUM (
+  'Done',
+
+-- exif properties
+  'ApertureValue',
+  'BrightnessValue',
+  'ExposureBiasValue',
+  'GPSAltitude',
+  'Make',
+  'Model',
+  'Software',
+  'DateTimeOriginal',
+  'OffsetTimeOriginal',
+  'ExposureTime',
+  'ISOSpeedRatings',
+  'LensModel',
+  'GPSLatitude',
+  'GPSLongitude',
+  'FocalLengthIn35mmFilm',
+
+-- color properties
+  'ProminentColor1',
+  'ProminentColor2',
+  'ProminentColor3',
+  'ColorCategory1',
+  'ColorCategory2',
+  'ColorCategory3'
+);
+
+DELETE FROM blob_properties
+WHERE property_type NOT IN (
+  'Done',
+  'ApertureValue',
+  'BrightnessValue',
+  'ExposureBiasValue',
+  'GPSAltitude',
+  'Make',
+  'Model',
+  'Software',
+  'DateTimeOriginal',
+  'OffsetTimeOriginal',
+  'ExposureTime',
+  'ISOSpeedRatings',
+  'LensModel',
+  'GPSLatitude',
+  'GPSLongitude',
+  'FocalLengthIn35mmFilm',
+  'ProminentColor1',
+  'ProminentColor2',
+  'ProminentColor3',
+  'ColorCategory1',
+  'ColorCategory2',
+  'ColorCategory3'
+);
+
+ALTER TABLE blob_properties
+ALTER COLUMN property_type TYPE blob_property_type USING property_type::blob_property_type;
+
+COMMIT;
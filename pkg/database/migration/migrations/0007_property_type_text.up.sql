@@ -0,0 +1,11 @@
+SET SCHEMA '{{ .SchemaName }}';
+
+BEGIN;
+
+-- configured processors set their own property types
+ALTER TABLE blob_properties
+ALTER COLUMN property_type TYPE TEXT;
+
+DROP TYPE blob_property_type;
+
+COMMIT;
@@ -0,0 +1,3 @@
+SET SCHEMA '{{ .SchemaName }}';
+
+DROP INDEX IF EXISTS blob_properties_ocr_text_search;
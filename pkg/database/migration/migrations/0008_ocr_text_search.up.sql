@@ -0,0 +1,6 @@
+SET SCHEMA '{{ .SchemaName }}';
+
+CREATE INDEX IF NOT EXISTS blob_properties_ocr_text_search
+ON blob_properties
+USING GIN (to_tsvector('simple', value_text))
+WHERE source = 'ocr';
@@ -0,0 +1,3 @@
+SET SCHEMA '{{ .SchemaName }}';
+
+DROP TABLE IF EXISTS processing_errors;
@@ -0,0 +1,15 @@
+SET SCHEMA '{{ .SchemaName }}';
+
+-- failed processor runs are recorded here so that they can be retried with a
+-- backoff rather than stopping the whole run
+CREATE TABLE IF NOT EXISTS processing_errors (
+  blob_id INTEGER NOT NULL,
+  processor TEXT NOT NULL,
+  error TEXT NOT NULL,
+  attempts INTEGER NOT NULL DEFAULT 1,
+  last_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  -- null once the maximum number of attempts has been reached
+  next_retry_at TIMESTAMP NULL,
+  PRIMARY KEY (blob_id, processor),
+  FOREIGN KEY (blob_id) REFERENCES blobs(id) ON DELETE CASCADE
+);
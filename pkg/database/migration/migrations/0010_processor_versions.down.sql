@@ -0,0 +1,11 @@
+SET SCHEMA '{{ .SchemaName }}';
+
+BEGIN;
+
+ALTER TABLE blob_metadata
+DROP COLUMN IF EXISTS version;
+
+ALTER TABLE blob_properties
+DROP COLUMN IF EXISTS version;
+
+COMMIT;
@@ -0,0 +1,12 @@
+SET SCHEMA '{{ .SchemaName }}';
+
+BEGIN;
+
+-- results from older processor versions are processed again
+ALTER TABLE blob_metadata
+ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+ALTER TABLE blob_properties
+ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+COMMIT;
@@ -0,0 +1,3 @@
+SET SCHEMA '{{ .SchemaName }}';
+
+DROP INDEX IF EXISTS blob_properties_type_text;
@@ -0,0 +1,6 @@
+SET SCHEMA '{{ .SchemaName }}';
+
+-- properties are looked up by name and value when searching, the generic
+-- tags processor stores many property types per blob
+CREATE INDEX IF NOT EXISTS blob_properties_type_text
+ON blob_properties (property_type, lower(value_text));
@@ -0,0 +1,38 @@
+SET SCHEMA '{{ .SchemaName }}';
+
+BEGIN;
+
+-- keys and md5s are only unique within a bucket, so restoring the unique
+-- constraints could fail part way through
+DO $$
+BEGIN
+  IF (
+    SELECT count(*)
+    FROM (SELECT bucket_id FROM objects UNION SELECT bucket_id FROM blobs) AS bucket_ids
+  ) > 1 THEN
+    RAISE EXCEPTION 'objects and blobs belong to more than one bucket, delete all but one bucket from the buckets table before migrating down';
+  END IF;
+END
+$$;
+
+ALTER TABLE blobs
+DROP CONSTRAINT blobs_bucket_id_md5_key;
+
+ALTER TABLE blobs
+DROP COLUMN bucket_id;
+
+ALTER TABLE blobs
+ADD CONSTRAINT blobs_md5_key UNIQUE (md5);
+
+ALTER TABLE objects
+DROP CONSTRAINT objects_bucket_id_key_key;
+
+ALTER TABLE objects
+DROP COLUMN bucket_id;
+
+ALTER TABLE objects
+ADD CONSTRAINT objects_key_key UNIQUE (key);
+
+DROP TABLE IF EXISTS buckets;
+
+COMMIT;
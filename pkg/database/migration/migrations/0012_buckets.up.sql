@@ -0,0 +1,43 @@
+SET SCHEMA '{{ .SchemaName }}';
+
+BEGIN;
+
+-- objects and blobs belong to a configured bucket. The unnamed bucket holds
+-- rows imported before buckets were configured, it's renamed to the default
+-- bucket on startup
+CREATE TABLE IF NOT EXISTS buckets (
+  id SERIAL PRIMARY KEY,
+  name TEXT NULL,
+  UNIQUE (name)
+);
+
+INSERT INTO buckets (name) VALUES (NULL);
+
+ALTER TABLE objects
+ADD COLUMN bucket_id INTEGER NOT NULL DEFAULT 1
+REFERENCES buckets(id) ON DELETE CASCADE;
+
+ALTER TABLE objects
+ALTER COLUMN bucket_id DROP DEFAULT;
+
+ALTER TABLE objects
+DROP CONSTRAINT objects_key_key;
+
+ALTER TABLE objects
+ADD CONSTRAINT objects_bucket_id_key_key UNIQUE (bucket_id, key);
+
+-- processor output is stored in the bucket, so blobs are not shared
+ALTER TABLE blobs
+ADD COLUMN bucket_id INTEGER NOT NULL DEFAULT 1
+REFERENCES buckets(id) ON DELETE CASCADE;
+
+ALTER TABLE blobs
+ALTER COLUMN bucket_id DROP DEFAULT;
+
+ALTER TABLE blobs
+DROP CONSTRAINT blobs_md5_key;
+
+ALTER TABLE blobs
+ADD CONSTRAINT blobs_bucket_id_md5_key UNIQUE (bucket_id, md5);
+
+COMMIT;
@@ -0,0 +1,131 @@
+package migration_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+
+	"github.com/charlieegan3/storage-console/pkg/database"
+	"github.com/charlieegan3/storage-console/pkg/database/migration"
+	"github.com/charlieegan3/storage-console/pkg/test"
+)
+
+func TestAppliedVersion(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	db, postgresCleanup, err := test.InitPostgres(ctx, t)
+	defer func() {
+		if postgresCleanup == nil {
+			return
+		}
+		if err := postgresCleanup(); err != nil {
+			t.Fatalf("Could not cleanup postgres: %s", err)
+		}
+	}()
+	if err != nil {
+		t.Fatalf("Could not init database: %s", err)
+	}
+
+	// the only connection is left using the console's schema
+	db.SetMaxOpenConns(1)
+
+	txn, err := database.NewTxnWithSchema(db, "storage_console")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	err = txn.Commit()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	latest, err := migration.Latest()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	version, dirty, err := migration.AppliedVersion(ctx, db, &postgres.Config{
+		MigrationsTable: "schema_migrations_storage_console",
+		SchemaName:      "public",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if version != latest || dirty {
+		t.Fatalf("expected migration %d, got %d (dirty: %v)", latest, version, dirty)
+	}
+
+	_, _, err = migration.AppliedVersion(ctx, db, &postgres.Config{})
+	if err == nil {
+		t.Fatalf("expected an error without a schema name")
+	}
+}
+
+func TestDownWithSeveralBuckets(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	db, postgresCleanup, err := test.InitPostgres(ctx, t)
+	defer func() {
+		if postgresCleanup == nil {
+			return
+		}
+		if err := postgresCleanup(); err != nil {
+			t.Fatalf("Could not cleanup postgres: %s", err)
+		}
+	}()
+	if err != nil {
+		t.Fatalf("Could not init database: %s", err)
+	}
+
+	cfg := &postgres.Config{
+		MigrationsTable: "schema_migrations_storage_console",
+		SchemaName:      "public",
+	}
+
+	bucketIDs, err := database.EnsureBuckets(db, "storage_console", []string{"photos", "archive"}, "photos")
+	if err != nil {
+		t.Fatalf("Could not create buckets: %s", err)
+	}
+
+	// the same key in each bucket breaks the unique key the rollback restores
+	for _, id := range bucketIDs {
+		_, err = db.Exec(`insert into storage_console.objects (bucket_id, key) values ($1, 'photo.jpg')`, id)
+		if err != nil {
+			t.Fatalf("Could not insert object: %s", err)
+		}
+	}
+
+	for name, rollback := range map[string]func() error{
+		"down":  func() error { return migration.Down(db, "storage_console", cfg) },
+		"steps": func() error { return migration.Steps(db, "storage_console", cfg, -1) },
+	} {
+		err = rollback()
+		if err == nil || !strings.Contains(err.Error(), "cannot roll back migration 12") {
+			t.Fatalf("%s: expected the rollback to be refused, got %v", name, err)
+		}
+
+		version, dirty, err := migration.AppliedVersion(ctx, db, cfg)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if version != 12 || dirty {
+			t.Fatalf("%s: expected clean migration 12, got %d (dirty: %v)", name, version, dirty)
+		}
+	}
+
+	_, err = db.Exec(`delete from storage_console.buckets where id = $1`, bucketIDs["archive"])
+	if err != nil {
+		t.Fatalf("Could not delete bucket: %s", err)
+	}
+
+	err = migration.Steps(db, "storage_console", cfg, -1)
+	if err != nil {
+		t.Fatalf("unexpected error once one bucket is left: %s", err)
+	}
+}
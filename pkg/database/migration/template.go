@@ -0,0 +1,63 @@
+package migration
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"text/template"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// templateData is available to the migrations as {{ .SchemaName }}
+type templateData struct {
+	SchemaName string
+}
+
+// templateSource renders each migration as a text/template before it's
+// run, so that the tables are created in the configured schema
+type templateSource struct {
+	source.Driver
+
+	data templateData
+}
+
+func (s *templateSource) ReadUp(version uint) (io.ReadCloser, string, error) {
+	r, identifier, err := s.Driver.ReadUp(version)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return s.render(r, identifier)
+}
+
+func (s *templateSource) ReadDown(version uint) (io.ReadCloser, string, error) {
+	r, identifier, err := s.Driver.ReadDown(version)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return s.render(r, identifier)
+}
+
+func (s *templateSource) render(r io.ReadCloser, identifier string) (io.ReadCloser, string, error) {
+	defer r.Close()
+
+	bs, err := io.ReadAll(r)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read migration %s: %w", identifier, err)
+	}
+
+	tmpl, err := template.New(identifier).Option("missingkey=error").Parse(string(bs))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse migration %s: %w", identifier, err)
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, s.data)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to render migration %s: %w", identifier, err)
+	}
+
+	return io.NopCloser(&buf), identifier, nil
+}
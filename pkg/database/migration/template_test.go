@@ -0,0 +1,91 @@
+package migration
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+func TestTemplateSource(t *testing.T) {
+	t.Parallel()
+
+	driver, err := iofs.New(migrations, "migrations")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	src := &templateSource{Driver: driver, data: templateData{SchemaName: "console_two"}}
+
+	version, err := src.First()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for {
+		for _, read := range []func(uint) (io.ReadCloser, string, error){src.ReadUp, src.ReadDown} {
+			r, identifier, err := read(version)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				t.Fatalf("unexpected error for %d: %s", version, err)
+			}
+
+			bs, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			sql := string(bs)
+			if strings.Contains(sql, "storage_console") || strings.Contains(sql, "{{") {
+				t.Errorf("migration %d %s does not use the schema name:\n%s", version, identifier, sql)
+			}
+
+			if strings.Contains(sql, "SCHEMA") && !strings.Contains(sql, "console_two") {
+				t.Errorf("migration %d %s is missing the schema name", version, identifier)
+			}
+		}
+
+		version, err = src.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+}
+
+func TestLatest(t *testing.T) {
+	t.Parallel()
+
+	entries, err := fs.ReadDir(migrations, "migrations")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var expected uint
+	for _, entry := range entries {
+		var version uint
+		_, err := fmt.Sscanf(entry.Name(), "%d_", &version)
+		if err != nil {
+			t.Fatalf("unexpected migration name %s: %s", entry.Name(), err)
+		}
+
+		expected = max(expected, version)
+	}
+
+	latest, err := Latest()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if latest != expected {
+		t.Fatalf("expected latest migration %d, got %d", expected, latest)
+	}
+}
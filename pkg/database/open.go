@@ -0,0 +1,150 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// Hook is called before each database call with the operation (query, exec,
+// prepare, begin, commit or rollback) and the SQL, if any. The returned func
+// is called with the call's error once it has finished.
+type Hook func(ctx context.Context, op, query string) func(err error)
+
+// Open connects to postgres, calling each hook around the queries made on
+// the connections
+func Open(connectionString string, hooks ...Hook) (*sql.DB, error) {
+	connector, err := pq.NewConnector(connectionString)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing connection string: %w", err)
+	}
+
+	if len(hooks) == 0 {
+		return sql.OpenDB(connector), nil
+	}
+
+	return sql.OpenDB(&hookConnector{Connector: connector, hooks: hooks}), nil
+}
+
+type hookConnector struct {
+	driver.Connector
+	hooks []Hook
+}
+
+func (c *hookConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hookConn{conn: conn, hooks: c.hooks}, nil
+}
+
+type hooks []Hook
+
+// start calls each hook and returns a func finishing them all
+func (h hooks) start(ctx context.Context, op, query string) func(error) {
+	finish := make([]func(error), len(h))
+	for i, hook := range h {
+		finish[i] = hook(ctx, op, query)
+	}
+
+	return func(err error) {
+		for _, f := range finish {
+			f(err)
+		}
+	}
+}
+
+// hookConn wraps a pq connection, which implements all of the context
+// variants of the driver interfaces
+type hookConn struct {
+	conn  driver.Conn
+	hooks hooks
+}
+
+func (c *hookConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *hookConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	finish := c.hooks.start(ctx, "prepare", query)
+	stmt, err := c.conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+	finish(err)
+
+	return stmt, err
+}
+
+func (c *hookConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *hookConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *hookConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	finish := c.hooks.start(ctx, "begin", "")
+	tx, err := c.conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+	finish(err)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hookTx{tx: tx, ctx: ctx, hooks: c.hooks}, nil
+}
+
+func (c *hookConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	finish := c.hooks.start(ctx, "query", query)
+	rows, err := c.conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+	finish(err)
+
+	return rows, err
+}
+
+func (c *hookConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	finish := c.hooks.start(ctx, "exec", query)
+	res, err := c.conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+	finish(err)
+
+	return res, err
+}
+
+func (c *hookConn) Ping(ctx context.Context) error {
+	return c.conn.(driver.Pinger).Ping(ctx)
+}
+
+func (c *hookConn) ResetSession(ctx context.Context) error {
+	return c.conn.(driver.SessionResetter).ResetSession(ctx)
+}
+
+func (c *hookConn) IsValid() bool {
+	return c.conn.(driver.Validator).IsValid()
+}
+
+// hookTx reports commits and rollbacks with the context the transaction was
+// started with, since the driver doesn't pass one
+type hookTx struct {
+	tx    driver.Tx
+	ctx   context.Context
+	hooks hooks
+}
+
+func (t *hookTx) Commit() error {
+	finish := t.hooks.start(t.ctx, "commit", "")
+	err := t.tx.Commit()
+	finish(err)
+
+	return err
+}
+
+func (t *hookTx) Rollback() error {
+	finish := t.hooks.start(t.ctx, "rollback", "")
+	err := t.tx.Rollback()
+	finish(err)
+
+	return err
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"regexp"
@@ -8,17 +9,33 @@ import (
 
 var schemaNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
 
-func NewTxnWithSchema(db *sql.DB, schema string) (*sql.Tx, error) {
+// CheckSchemaName returns an error unless schema is safe to use unquoted in
+// SQL
+func CheckSchemaName(schema string) error {
 	if !schemaNamePattern.MatchString(schema) {
-		return nil, fmt.Errorf("invalid schema name: %s", schema)
+		return fmt.Errorf("invalid schema name: %s", schema)
+	}
+
+	return nil
+}
+
+func NewTxnWithSchema(db *sql.DB, schema string) (*sql.Tx, error) {
+	return NewTxnWithSchemaContext(context.Background(), db, schema)
+}
+
+// NewTxnWithSchemaContext begins a transaction using schema, the transaction
+// is rolled back if ctx is done before it's committed
+func NewTxnWithSchemaContext(ctx context.Context, db *sql.DB, schema string) (*sql.Tx, error) {
+	if err := CheckSchemaName(schema); err != nil {
+		return nil, err
 	}
 
-	txn, err := db.Begin()
+	txn, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("could not begin transaction: %w", err)
 	}
 
-	_, err = txn.Exec(fmt.Sprintf("SET SCHEMA '%s';", schema))
+	_, err = txn.ExecContext(ctx, fmt.Sprintf("SET SCHEMA '%s';", schema))
 	if err != nil {
 		return nil, fmt.Errorf("could not set schema on txn: %w", err)
 	}
@@ -0,0 +1,259 @@
+package geo
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:embed data/*.tsv
+var data embed.FS
+
+const earthRadius = 6371.0
+
+// Place is a populated place from a gazetteer
+type Place struct {
+	City        string
+	Region      string
+	Country     string
+	CountryCode string
+	Latitude    float64
+	Longitude   float64
+}
+
+// Gazetteer finds the nearest place to a coordinate without network access
+type Gazetteer struct {
+	places []Place
+	// checksum identifies the places, so that results from another
+	// gazetteer can be recomputed
+	checksum uint32
+}
+
+// Default returns the gazetteer bundled with the storage console, which
+// holds the larger cities and towns of each country
+var Default = sync.OnceValues(func() (*Gazetteer, error) {
+	f, err := data.Open("data/cities.tsv")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundled cities: %w", err)
+	}
+	defer f.Close()
+
+	return Load(f)
+})
+
+var countryNames = sync.OnceValues(func() (map[string]string, error) {
+	f, err := data.Open("data/countries.tsv")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundled countries: %w", err)
+	}
+	defer f.Close()
+
+	names := make(map[string]string)
+	err = readTSV(f, 2, func(fields []string) error {
+		names[fields[0]] = fields[1]
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundled countries: %w", err)
+	}
+
+	return names, nil
+})
+
+// Load reads a gazetteer with the columns name, region, country code,
+// latitude and longitude separated by tabs. Lines starting with # are
+// ignored.
+func Load(r io.Reader) (*Gazetteer, error) {
+	countries, err := countryNames()
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Gazetteer{}
+
+	err = readTSV(r, 5, func(fields []string) error {
+		lat, lon, err := parseCoordinates(fields[3], fields[4])
+		if err != nil {
+			return err
+		}
+
+		g.places = append(g.places, Place{
+			City:        fields[0],
+			Region:      fields[1],
+			Country:     countryName(countries, fields[2]),
+			CountryCode: fields[2],
+			Latitude:    lat,
+			Longitude:   lon,
+		})
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gazetteer: %w", err)
+	}
+
+	g.checksum = checksum(g.places)
+
+	return g, nil
+}
+
+// LoadGeoNames reads a GeoNames cities file, such as cities15000.txt, and
+// optionally the admin1CodesASCII.txt file used to name regions. Both are
+// available from https://download.geonames.org/export/dump/.
+func LoadGeoNames(cities io.Reader, admin1Codes io.Reader) (*Gazetteer, error) {
+	countries, err := countryNames()
+	if err != nil {
+		return nil, err
+	}
+
+	regions := make(map[string]string)
+	if admin1Codes != nil {
+		err = readTSV(admin1Codes, 2, func(fields []string) error {
+			regions[fields[0]] = fields[1]
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read admin1 codes: %w", err)
+		}
+	}
+
+	g := &Gazetteer{}
+
+	err = readTSV(cities, 11, func(fields []string) error {
+		lat, lon, err := parseCoordinates(fields[4], fields[5])
+		if err != nil {
+			return err
+		}
+
+		countryCode := fields[8]
+
+		g.places = append(g.places, Place{
+			City:        fields[1],
+			Region:      regions[countryCode+"."+fields[10]],
+			Country:     countryName(countries, countryCode),
+			CountryCode: countryCode,
+			Latitude:    lat,
+			Longitude:   lon,
+		})
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read geonames cities: %w", err)
+	}
+
+	g.checksum = checksum(g.places)
+
+	return g, nil
+}
+
+// Checksum changes when any of the places in the gazetteer do
+func (g *Gazetteer) Checksum() uint32 {
+	return g.checksum
+}
+
+func checksum(places []Place) uint32 {
+	h := fnv.New32a()
+	for _, p := range places {
+		fmt.Fprintf(h, "%s\t%s\t%s\t%g\t%g\n", p.City, p.Region, p.CountryCode, p.Latitude, p.Longitude)
+	}
+
+	return h.Sum32()
+}
+
+// Len returns the number of places in the gazetteer
+func (g *Gazetteer) Len() int {
+	return len(g.places)
+}
+
+// Nearest returns the closest place to the coordinate and its distance in
+// kilometres, false is returned when the gazetteer is empty
+func (g *Gazetteer) Nearest(lat, lon float64) (Place, float64, bool) {
+	if len(g.places) == 0 {
+		return Place{}, 0, false
+	}
+
+	nearest := -1
+	nearestDistance := math.Inf(1)
+
+	for i := range g.places {
+		d := Distance(lat, lon, g.places[i].Latitude, g.places[i].Longitude)
+		if d < nearestDistance {
+			nearest = i
+			nearestDistance = d
+		}
+	}
+
+	return g.places[nearest], nearestDistance, true
+}
+
+// Distance returns the great-circle distance between two coordinates in
+// kilometres
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	dLat, dLon := radians(lat2-lat1), radians(lon2-lon1)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(radians(lat1))*math.Cos(radians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+func countryName(countries map[string]string, code string) string {
+	if name, ok := countries[code]; ok {
+		return name
+	}
+
+	return code
+}
+
+func parseCoordinates(latitude, longitude string) (float64, float64, error) {
+	lat, err := strconv.ParseFloat(latitude, 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return 0, 0, fmt.Errorf("invalid latitude %q", latitude)
+	}
+
+	lon, err := strconv.ParseFloat(longitude, 64)
+	if err != nil || lon < -180 || lon > 180 {
+		return 0, 0, fmt.Errorf("invalid longitude %q", longitude)
+	}
+
+	return lat, lon, nil
+}
+
+// readTSV calls fn with the fields of each line, lines must have at least
+// the given number of fields
+func readTSV(r io.Reader, minFields int, fn func(fields []string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		text := scanner.Text()
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, "\t")
+		if len(fields) < minFields {
+			return fmt.Errorf("line %d has %d fields, expected at least %d", line, len(fields), minFields)
+		}
+
+		if err := fn(fields); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+
+	return scanner.Err()
+}
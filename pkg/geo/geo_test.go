@@ -0,0 +1,105 @@
+package geo
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestDefaultNearest(t *testing.T) {
+	t.Parallel()
+
+	g, err := Default()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	testCases := map[string]struct {
+		lat, lon float64
+		expected Place
+	}{
+		"edinburgh castle": {
+			lat: 55.9486, lon: -3.1999,
+			expected: Place{City: "Edinburgh", Region: "Scotland", Country: "United Kingdom", CountryCode: "GB"},
+		},
+		"golden gate bridge": {
+			lat: 37.8199, lon: -122.4783,
+			expected: Place{City: "San Francisco", Region: "California", Country: "United States", CountryCode: "US"},
+		},
+		"sydney opera house": {
+			lat: -33.8568, lon: 151.2153,
+			expected: Place{City: "Sydney", Region: "New South Wales", Country: "Australia", CountryCode: "AU"},
+		},
+		"matterhorn": {
+			lat: 45.9763, lon: 7.6586,
+			expected: Place{City: "Zermatt", Region: "Valais", Country: "Switzerland", CountryCode: "CH"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			place, distance, ok := g.Nearest(tc.lat, tc.lon)
+			if !ok {
+				t.Fatalf("expected a place")
+			}
+
+			if distance > 25 {
+				t.Fatalf("expected a place within 25km, got %s at %.1fkm", place.City, distance)
+			}
+
+			place.Latitude, place.Longitude = 0, 0
+			if place != tc.expected {
+				t.Fatalf("expected %+v, got %+v", tc.expected, place)
+			}
+		})
+	}
+}
+
+func TestLoadGeoNames(t *testing.T) {
+	t.Parallel()
+
+	cities := strings.Join([]string{
+		"2643743\tLondon\tLondon\t\t51.50853\t-0.12574\tP\tPPLC\tGB\t\tENG\tGLA\t\t\t8961989\t\t25\tEurope/London\t2023-01-12",
+		"2655603\tBirmingham\tBirmingham\t\t52.48142\t-1.89983\tP\tPPLA2\tGB\t\tENG\tA7\t\t\t984333\t\t149\tEurope/London\t2023-01-12",
+		"3169070\tRome\tRome\t\t41.89193\t12.51133\tP\tPPLC\tIT\t\t07\tRM\t\t\t2318895\t\t20\tEurope/Rome\t2023-01-12",
+	}, "\n")
+
+	admin1 := "GB.ENG\tEngland\tEngland\t6269131\nIT.07\tLazio\tLazio\t3174976\n"
+
+	g, err := LoadGeoNames(strings.NewReader(cities), strings.NewReader(admin1))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if exp, got := 3, g.Len(); exp != got {
+		t.Fatalf("expected %d places, got %d", exp, got)
+	}
+
+	place, _, _ := g.Nearest(41.9, 12.5)
+	if exp := (Place{
+		City: "Rome", Region: "Lazio", Country: "Italy", CountryCode: "IT",
+		Latitude: 41.89193, Longitude: 12.51133,
+	}); place != exp {
+		t.Fatalf("expected %+v, got %+v", exp, place)
+	}
+
+	_, err = LoadGeoNames(strings.NewReader("1\tNowhere\tNowhere\t\tnorth\t0\tP"), nil)
+	if err == nil {
+		t.Fatalf("expected error for a short line")
+	}
+}
+
+func TestDistance(t *testing.T) {
+	t.Parallel()
+
+	// london to paris is roughly 344km
+	if d := Distance(51.5074, -0.1278, 48.8566, 2.3522); math.Abs(d-344) > 2 {
+		t.Fatalf("unexpected distance %.1f", d)
+	}
+
+	if d := Distance(10, 20, 10, 20); d != 0 {
+		t.Fatalf("expected no distance, got %f", d)
+	}
+}
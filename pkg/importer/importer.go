@@ -5,25 +5,33 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"path"
 	"strings"
 
 	"github.com/minio/minio-go/v7"
 
 	"github.com/charlieegan3/storage-console/pkg/database"
+	"github.com/charlieegan3/storage-console/pkg/layout"
+	"github.com/charlieegan3/storage-console/pkg/logging"
+	"github.com/charlieegan3/storage-console/pkg/meta"
+	"github.com/charlieegan3/storage-console/pkg/meta/xmp"
 )
 
-const dataPath = "data/"
-
 type Options struct {
 	BucketName string
+	// BucketID is the database id of the bucket, from database.EnsureBuckets
+	BucketID   int
 	SchemaName string
 
 	Prefix string
+	// Layout is where objects are stored in the bucket, layout.Default is
+	// used when it's nil
+	Layout *layout.Layout
 
-	LoggerError *log.Logger
-	LoggerInfo  *log.Logger
+	// Logger is used for progress and errors, nothing is logged when it's
+	// nil
+	Logger *slog.Logger
 }
 
 type Report struct {
@@ -41,10 +49,19 @@ func Run(ctx context.Context, db *sql.DB, minioClient *minio.Client, opts *Optio
 		return nil, fmt.Errorf("schema name is required")
 	}
 
+	err := database.CheckSchemaName(opts.SchemaName)
+	if err != nil {
+		return nil, err
+	}
+
 	if opts.BucketName == "" {
 		return nil, fmt.Errorf("bucket name is required")
 	}
 
+	if opts.BucketID == 0 {
+		return nil, fmt.Errorf("bucket id is required")
+	}
+
 	if db == nil {
 		return nil, fmt.Errorf("database is required")
 	}
@@ -58,23 +75,31 @@ func Run(ctx context.Context, db *sql.DB, minioClient *minio.Client, opts *Optio
 		return nil, fmt.Errorf("bucket does not exist")
 	}
 
-	taskInsertSQL := `
-SET SCHEMA 'storage_console';
-INSERT INTO tasks (initiator, status) VALUES ('importer', 'starting')
+	l := layout.OrDefault(opts.Layout)
+
+	// tasks are updated outside of the import transaction so that progress
+	// can be seen, the schema is named as the connection's isn't set
+	taskInsertSQL := fmt.Sprintf(`
+INSERT INTO %s.tasks (initiator, status) VALUES ('importer', 'starting')
 RETURNING id;
-`
+`, opts.SchemaName)
 	var taskID int
 	err = db.QueryRow(taskInsertSQL).Scan(&taskID)
 	if err != nil {
 		return nil, fmt.Errorf("could not insert task: %s", err)
 	}
 
+	logger := logging.OrDiscard(opts.Logger).With(
+		logging.FieldBucket, opts.BucketName,
+		logging.FieldTaskID, taskID,
+	)
+
 	txn, err := database.NewTxnWithSchema(db, opts.SchemaName)
 	if err != nil {
 		return nil, fmt.Errorf("could not start transaction: %s", err)
 	}
 
-	err = updateTask(db, taskID, "transaction created", false, false)
+	err = updateTask(db, opts.SchemaName, taskID, "transaction created", false, false)
 	if err != nil {
 		return nil, fmt.Errorf("could not update task: %s", err)
 	}
@@ -84,7 +109,7 @@ RETURNING id;
 		if shouldRollback {
 			err := txn.Rollback()
 			if err != nil {
-				fmt.Printf("could not rollback transaction: %s", err)
+				logger.ErrorContext(ctx, "could not rollback transaction", logging.Err(err))
 			}
 		}
 	}()
@@ -92,10 +117,10 @@ RETURNING id;
 	var r Report
 
 	existingPathSQL := `
-select key from objects;
+select key from objects where bucket_id = $1;
 `
 
-	rows, err := txn.Query(existingPathSQL)
+	rows, err := txn.Query(existingPathSQL, opts.BucketID)
 	if err != nil {
 		return nil, fmt.Errorf("could not select existing paths: %s", err)
 	}
@@ -118,7 +143,7 @@ select key from objects;
 		pathsToRemove[path] = true
 	}
 
-	err = updateTask(db, taskID, "existing state scanned", false, false)
+	err = updateTask(db, opts.SchemaName, taskID, "existing state scanned", false, false)
 	if err != nil {
 		return nil, fmt.Errorf("could not update task: %s", err)
 	}
@@ -126,9 +151,9 @@ select key from objects;
 	for obj := range minioClient.ListObjects(
 		ctx,
 		opts.BucketName,
-		minio.ListObjectsOptions{Prefix: path.Join(dataPath, opts.Prefix), Recursive: true},
+		minio.ListObjectsOptions{Prefix: l.DataKey(opts.Prefix), Recursive: true},
 	) {
-		key := strings.TrimPrefix(obj.Key, dataPath)
+		key := l.ObjectKey(obj.Key)
 
 		if strings.HasSuffix(key, "/") {
 			continue
@@ -143,10 +168,10 @@ select key from objects;
 		}
 
 		objectInitSQL := `
-INSERT INTO objects (key) VALUES ($1)
-ON CONFLICT (key) DO NOTHING;
+INSERT INTO objects (bucket_id, key) VALUES ($1, $2)
+ON CONFLICT (bucket_id, key) DO NOTHING;
 `
-		result, err := txn.Exec(objectInitSQL, key)
+		result, err := txn.Exec(objectInitSQL, opts.BucketID, key)
 		if err != nil {
 			return nil, fmt.Errorf("could not create object: %s", err)
 		}
@@ -155,30 +180,30 @@ ON CONFLICT (key) DO NOTHING;
 			return nil, fmt.Errorf("could not check if object was created: %s", err)
 		}
 		if objCreated {
-			err = updateTask(db, taskID, fmt.Sprintf("object created: %s", key), true, false)
+			err = updateTask(db, opts.SchemaName, taskID, fmt.Sprintf("object created: %s", key), true, false)
 			if err != nil {
 				return nil, fmt.Errorf("could not update task: %s", err)
 			}
 
-			opts.LoggerInfo.Printf("imported %s", key)
+			logger.InfoContext(ctx, "imported object", logging.FieldKey, key)
 
 			r.ObjectsCreated++
 		}
 
 		findExistingObjectSQL := `
-select id from objects where key = $1;
+select id from objects where bucket_id = $1 and key = $2;
 `
 		var objectID int
-		err = txn.QueryRow(findExistingObjectSQL, key).Scan(&objectID)
+		err = txn.QueryRow(findExistingObjectSQL, opts.BucketID, key).Scan(&objectID)
 		if err != nil && !errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("failed to get object: %s", err)
 		}
 
 		findExistingBlobSQL := `
-select id from blobs where md5 = $1;
+select id from blobs where bucket_id = $1 and md5 = $2;
 `
 		var blobID int
-		err = txn.QueryRow(findExistingBlobSQL, obj.ETag).Scan(&blobID)
+		err = txn.QueryRow(findExistingBlobSQL, opts.BucketID, obj.ETag).Scan(&blobID)
 		if err != nil && !errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("failed checking presence of blob: %s", err)
 		}
@@ -188,7 +213,7 @@ select id from blobs where md5 = $1;
 			objData, err := minioClient.StatObject(
 				ctx,
 				opts.BucketName,
-				path.Join(dataPath, key),
+				l.DataKey(key),
 				minio.StatObjectOptions{},
 			)
 			if err != nil {
@@ -198,35 +223,38 @@ select id from blobs where md5 = $1;
 				return nil, fmt.Errorf("unexpected ETag %s: %s != %s", key, objData.ETag, obj.ETag)
 			}
 
-			opts.LoggerError.Printf(
-				"importing %s %s %d %s",
-				key,
-				objData.ContentType,
-				objData.Size,
-				objData.ETag,
+			logger.DebugContext(
+				ctx,
+				"importing blob",
+				logging.FieldKey, key,
+				logging.FieldETag, objData.ETag,
+				"content_type", objData.ContentType,
+				"size", objData.Size,
 			)
 
 			blobInitSQL := `
 INSERT INTO blobs
-	(md5, size, last_modified, content_type_id)
-VALUES ($1, $2, $3, find_or_create_content_type($4))
+	(bucket_id, md5, size, last_modified, content_type_id)
+VALUES ($1, $2, $3, $4, find_or_create_content_type($5))
 RETURNING id;
 `
 
-			err = txn.QueryRow(blobInitSQL, obj.ETag, obj.Size, obj.LastModified, objData.ContentType).Scan(&blobID)
+			contentType := meta.DetectContentType(key, objData.ContentType)
+
+			err = txn.QueryRow(blobInitSQL, opts.BucketID, obj.ETag, obj.Size, obj.LastModified, contentType).Scan(&blobID)
 			if err != nil {
 				return nil, fmt.Errorf("could not create blob: %s", err)
 			}
 
 			r.BlobsCreated++
 
-			err = updateTask(db, taskID, fmt.Sprintf("blob created: %s", obj.ETag), true, false)
+			err = updateTask(db, opts.SchemaName, taskID, fmt.Sprintf("blob created: %s", obj.ETag), true, false)
 			if err != nil {
 				return nil, fmt.Errorf("could not update task: %s", err)
 			}
 		}
 
-		err = txn.QueryRow("SELECT id FROM blobs WHERE md5 = $1", obj.ETag).Scan(&blobID)
+		err = txn.QueryRow("SELECT id FROM blobs WHERE bucket_id = $1 AND md5 = $2", opts.BucketID, obj.ETag).Scan(&blobID)
 		if err != nil {
 			return nil, fmt.Errorf("could not select blob ID: %s", err)
 		}
@@ -246,7 +274,14 @@ ON CONFLICT (object_id, blob_id) DO NOTHING;
 		if objBlobCreated {
 			r.BlobsLinked++
 
-			err = updateTask(db, taskID, fmt.Sprintf("object blob linked: %s", key), true, false)
+			if stem, ok := xmp.SidecarStem(key); ok {
+				err = resetSidecarPhotos(txn, opts.BucketID, stem)
+				if err != nil {
+					return nil, fmt.Errorf("could not reset photos for sidecar %s: %s", key, err)
+				}
+			}
+
+			err = updateTask(db, opts.SchemaName, taskID, fmt.Sprintf("object blob linked: %s", key), true, false)
 			if err != nil {
 				return nil, fmt.Errorf("could not update task: %s", err)
 			}
@@ -260,16 +295,16 @@ ON CONFLICT (object_id, blob_id) DO NOTHING;
 
 		deleteObjectSQL := `
 update objects SET deleted_at = CURRENT_TIMESTAMP
-where key = $1
+where bucket_id = $1 and key = $2
 `
-		_, err = txn.Exec(deleteObjectSQL, path)
+		_, err = txn.Exec(deleteObjectSQL, opts.BucketID, path)
 		if err != nil {
 			return nil, fmt.Errorf("could not delete object: %s", err)
 		}
 
 		r.ObjectsDeleted++
 
-		err = updateTask(db, taskID, fmt.Sprintf("object deleted: %s", path), true, false)
+		err = updateTask(db, opts.SchemaName, taskID, fmt.Sprintf("object deleted: %s", path), true, false)
 		if err != nil {
 			return nil, fmt.Errorf("could not update task: %s", err)
 		}
@@ -277,11 +312,11 @@ where key = $1
 
 	// select all objects that do not have an object blob
 	deleteDisattachedObjectsSQL := `
-delete from objects where id not in (
+delete from objects where bucket_id = $1 and id not in (
   select object_id from object_blobs
 )
 `
-	_, err = txn.Exec(deleteDisattachedObjectsSQL)
+	_, err = txn.Exec(deleteDisattachedObjectsSQL, opts.BucketID)
 	if err != nil {
 		return nil, fmt.Errorf("could not delete disattached objects: %s", err)
 	}
@@ -291,7 +326,7 @@ delete from objects where id not in (
 		return nil, fmt.Errorf("could not commit transaction: %s", err)
 	}
 
-	err = updateTask(db, taskID, "completed", false, true)
+	err = updateTask(db, opts.SchemaName, taskID, "completed", false, true)
 	if err != nil {
 		return nil, fmt.Errorf("could not update task: %s", err)
 	}
@@ -314,7 +349,7 @@ func didUpdate(result sql.Result) (bool, error) {
 	return rowsAffected > 0, nil
 }
 
-func updateTask(db *sql.DB, taskID int, status string, incOperations bool, complete bool) error {
+func updateTask(db *sql.DB, schema string, taskID int, status string, incOperations bool, complete bool) error {
 	var increment int
 	if incOperations {
 		increment = 1
@@ -325,8 +360,8 @@ func updateTask(db *sql.DB, taskID int, status string, incOperations bool, compl
 	}
 
 	updateTaskSQL := fmt.Sprintf(`
-UPDATE storage_console.tasks SET status = $1, operations = operations + $3 %s WHERE id = $2;
-`, completedAt)
+UPDATE %s.tasks SET status = $1, operations = operations + $3 %s WHERE id = $2;
+`, schema, completedAt)
 	_, err := db.Exec(updateTaskSQL, status, taskID, increment)
 	if err != nil {
 		return fmt.Errorf("could not update task: %s", err)
@@ -334,3 +369,24 @@ UPDATE storage_console.tasks SET status = $1, operations = operations + $3 %s WH
 
 	return nil
 }
+
+// resetSidecarPhotos removes the xmp metadata from the photos next to a new
+// or changed sidecar so that they're processed again
+func resetSidecarPhotos(txn *sql.Tx, bucketID int, stem string) error {
+	resetSQL := `
+delete from blob_metadata
+where processor = 'xmp'
+  and blob_id in (
+    select object_blobs.blob_id
+    from object_blobs
+    join objects on objects.id = object_blobs.object_id
+    where objects.bucket_id = $1
+      and (objects.key = $2 or objects.key like $3 escape '\')
+  )
+`
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(stem)
+
+	_, err := txn.Exec(resetSQL, bucketID, stem, escaped+".%")
+
+	return err
+}
@@ -3,12 +3,13 @@ package importer
 import (
 	"bytes"
 	"context"
-	"log"
+	"log/slog"
 	"os"
 	"testing"
 
 	"github.com/minio/minio-go/v7"
 
+	"github.com/charlieegan3/storage-console/pkg/database"
 	"github.com/charlieegan3/storage-console/pkg/test"
 )
 
@@ -48,6 +49,11 @@ select initiator, status, operations from tasks order by created_at desc limit 1
 		t.Fatalf("Could not init database: %s", err)
 	}
 
+	bucketIDs, err := database.EnsureBuckets(db, "storage_console", []string{"example"}, "example")
+	if err != nil {
+		t.Fatalf("Could not create buckets: %s", err)
+	}
+
 	// create the initial bucket state
 	err = minioClient.MakeBucket(ctx, "example", minio.MakeBucketOptions{})
 	if err != nil {
@@ -88,14 +94,14 @@ select initiator, status, operations from tasks order by created_at desc limit 1
 		}
 	}
 
-	logger := log.New(os.Stderr, "", log.LstdFlags)
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
 
 	// run the importer
 	report, err := Run(ctx, db, minioClient, &Options{
-		BucketName:  "example",
-		SchemaName:  "storage_console",
-		LoggerError: logger,
-		LoggerInfo:  logger,
+		BucketName: "example",
+		BucketID:   bucketIDs["example"],
+		SchemaName: "storage_console",
+		Logger:     logger,
 	})
 	if err != nil {
 		t.Fatalf("Could not run import: %s", err)
@@ -127,6 +133,7 @@ select initiator, status, operations from tasks order by created_at desc limit 1
 	// run again to test for idempotency
 	report, err = Run(ctx, db, minioClient, &Options{
 		BucketName: "example",
+		BucketID:   bucketIDs["example"],
 		SchemaName: "storage_console",
 	})
 	if err != nil {
@@ -222,10 +229,10 @@ select (select key from obj) as obj;`
 
 	// run the importer
 	report, err = Run(ctx, db, minioClient, &Options{
-		BucketName:  "example",
-		SchemaName:  "storage_console",
-		LoggerError: logger,
-		LoggerInfo:  logger,
+		BucketName: "example",
+		BucketID:   bucketIDs["example"],
+		SchemaName: "storage_console",
+		Logger:     logger,
 	})
 	if err != nil {
 		t.Fatalf("Could not run import: %s", err)
@@ -259,6 +266,7 @@ select (select key from obj) as obj;`
 	// run the importer
 	report, err = Run(ctx, db, minioClient, &Options{
 		BucketName: "example",
+		BucketID:   bucketIDs["example"],
 		SchemaName: "storage_console",
 	})
 	if err != nil {
@@ -0,0 +1,81 @@
+// Package layout maps object keys to where objects and processor output are
+// stored in a bucket
+package layout
+
+import (
+	"path"
+	"strings"
+)
+
+// Layout is where a bucket's objects and the processor output for them are
+// stored
+type Layout struct {
+	// DataPrefix is prepended to object keys, it's empty when the objects
+	// are at the root of the bucket
+	DataPrefix string
+	// MetaBucket holds processor output, the data bucket is used when it's
+	// empty
+	MetaBucket string
+	MetaPrefix string
+}
+
+// Default stores objects under data/ and processor output under meta/ in
+// the same bucket
+var Default = Layout{DataPrefix: "data/", MetaPrefix: "meta/"}
+
+// OrDefault returns l, or Default when l is nil
+func OrDefault(l *Layout) *Layout {
+	if l == nil {
+		return &Default
+	}
+
+	return l
+}
+
+// DataKey returns the storage key for an object key. An empty key returns
+// the data prefix, for listing every object.
+func (l *Layout) DataKey(key string) string {
+	if key == "" {
+		return l.DataPrefix
+	}
+
+	return path.Join(l.DataPrefix, key)
+}
+
+// ObjectKey returns the object key for a storage key under the data prefix
+func (l *Layout) ObjectKey(dataKey string) string {
+	return strings.TrimPrefix(dataKey, l.DataPrefix)
+}
+
+// MetaKey returns the storage key for processor output, elem is joined
+// under the meta prefix
+func (l *Layout) MetaKey(elem ...string) string {
+	return path.Join(append([]string{l.MetaPrefix}, elem...)...)
+}
+
+// MetaBucketName returns the bucket holding processor output for the
+// objects in bucketName
+func (l *Layout) MetaBucketName(bucketName string) string {
+	if l.MetaBucket == "" {
+		return bucketName
+	}
+
+	return l.MetaBucket
+}
+
+// Prefix normalises a configured prefix so that it ends in a /. "/" is the
+// root of the bucket and is returned as "".
+func Prefix(prefix string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return ""
+	}
+
+	return prefix + "/"
+}
+
+// Overlaps reports whether objects and processor output would share keys
+// when stored in the same bucket
+func (l *Layout) Overlaps() bool {
+	return strings.HasPrefix(l.DataPrefix, l.MetaPrefix) || strings.HasPrefix(l.MetaPrefix, l.DataPrefix)
+}
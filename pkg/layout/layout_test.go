@@ -0,0 +1,96 @@
+package layout
+
+import "testing"
+
+func TestLayoutKeys(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		layout     *Layout
+		dataKey    string
+		metaKey    string
+		metaBucket string
+	}{
+		"default": {
+			layout:     OrDefault(nil),
+			dataKey:    "data/a/b.jpg",
+			metaKey:    "meta/thumbnail/abc.jpg",
+			metaBucket: "photos",
+		},
+		"root data with meta bucket": {
+			layout:     &Layout{MetaBucket: "photos-meta", MetaPrefix: "console/"},
+			dataKey:    "a/b.jpg",
+			metaKey:    "console/thumbnail/abc.jpg",
+			metaBucket: "photos-meta",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.layout.DataKey("a/b.jpg"); got != tc.dataKey {
+				t.Fatalf("expected data key %q, got %q", tc.dataKey, got)
+			}
+
+			if got := tc.layout.ObjectKey(tc.dataKey); got != "a/b.jpg" {
+				t.Fatalf("expected object key %q, got %q", "a/b.jpg", got)
+			}
+
+			if got := tc.layout.MetaKey("thumbnail", "abc.jpg"); got != tc.metaKey {
+				t.Fatalf("expected meta key %q, got %q", tc.metaKey, got)
+			}
+
+			if got := tc.layout.MetaBucketName("photos"); got != tc.metaBucket {
+				t.Fatalf("expected meta bucket %q, got %q", tc.metaBucket, got)
+			}
+		})
+	}
+}
+
+func TestDataKeyListsPrefix(t *testing.T) {
+	t.Parallel()
+
+	// a sibling prefix such as photos-meta/ must not be listed as data
+	l := Layout{DataPrefix: "photos/", MetaPrefix: "photos-meta/"}
+	if got := l.DataKey(""); got != "photos/" {
+		t.Fatalf("expected %q, got %q", "photos/", got)
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	t.Parallel()
+
+	for input, expected := range map[string]string{
+		"":           "",
+		"/":          "",
+		"data":       "data/",
+		"/data/":     "data/",
+		"a/b":        "a/b/",
+		"console/x/": "console/x/",
+	} {
+		if got := Prefix(input); got != expected {
+			t.Errorf("Prefix(%q): expected %q, got %q", input, expected, got)
+		}
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		layout   Layout
+		expected bool
+	}{
+		"default":     {layout: Default, expected: false},
+		"root data":   {layout: Layout{MetaPrefix: "meta/"}, expected: true},
+		"nested meta": {layout: Layout{DataPrefix: "data/", MetaPrefix: "data/meta/"}, expected: true},
+		"siblings":    {layout: Layout{DataPrefix: "photos/", MetaPrefix: "photos-meta/"}, expected: false},
+	}
+
+	for name, tc := range testCases {
+		if got := tc.layout.Overlaps(); got != tc.expected {
+			t.Errorf("%s: expected %v, got %v", name, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Package logging builds the console's structured loggers. Log lines use
+// the field names below so that they can be filtered consistently, and the
+// request and trace IDs from the context are added to every line logged
+// with it.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// field names shared by all log lines
+const (
+	FieldError     = "error"
+	FieldBucket    = "bucket"
+	FieldKey       = "key"
+	FieldETag      = "etag"
+	FieldProcessor = "processor"
+	FieldTaskID    = "task_id"
+	FieldRequestID = "request_id"
+	FieldTraceID   = "trace_id"
+)
+
+// New returns a logger writing text or json lines at level and above
+func New(w io.Writer, level slog.Level, format string) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level}
+
+	var h slog.Handler
+	switch format {
+	case "", "text":
+		h = slog.NewTextHandler(w, opts)
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unsupported log format %q, must be text or json", format)
+	}
+
+	return slog.New(&contextHandler{Handler: h}), nil
+}
+
+// ParseLevel parses debug, info, warn or error
+func ParseLevel(level string) (slog.Level, error) {
+	var l slog.Level
+
+	err := l.UnmarshalText([]byte(strings.ToLower(level)))
+	if err != nil {
+		return 0, fmt.Errorf("unsupported log level %q, must be debug, info, warn or error", level)
+	}
+
+	return l, nil
+}
+
+// Discard returns a logger which drops everything, for callers which
+// haven't configured one
+func Discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError + 1}))
+}
+
+// OrDiscard returns l, or a logger which drops everything when it's nil
+func OrDiscard(l *slog.Logger) *slog.Logger {
+	if l == nil {
+		return Discard()
+	}
+
+	return l
+}
+
+// Err is the attribute for an error
+func Err(err error) slog.Attr {
+	return slog.Any(FieldError, err)
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the request ID, which is added to
+// lines logged with it
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID in the context, or ""
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+
+	return id
+}
+
+// contextHandler adds values from the context to each record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String(FieldRequestID, id))
+	}
+
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String(FieldTraceID, sc.TraceID().String()))
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
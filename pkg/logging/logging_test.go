@@ -0,0 +1,102 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	logger, err := New(&buf, slog.LevelInfo, "json")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	ctx := WithRequestID(context.Background(), "abc123")
+	logger.With(FieldBucket, "photos").ErrorContext(ctx, "failed", FieldKey, "a.jpg", Err(errors.New("boom")))
+	logger.DebugContext(ctx, "hidden")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected one line, got:\n%s", buf.String())
+	}
+
+	var line map[string]any
+	err = json.Unmarshal([]byte(lines[0]), &line)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for k, v := range map[string]string{
+		"msg":          "failed",
+		FieldBucket:    "photos",
+		FieldKey:       "a.jpg",
+		FieldError:     "boom",
+		FieldRequestID: "abc123",
+	} {
+		if line[k] != v {
+			t.Errorf("expected %s=%q, got %v", k, v, line[k])
+		}
+	}
+
+	_, err = New(&buf, slog.LevelInfo, "xml")
+	if err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	t.Parallel()
+
+	for input, expected := range map[string]slog.Level{
+		"debug": slog.LevelDebug,
+		"INFO":  slog.LevelInfo,
+		"warn":  slog.LevelWarn,
+		"error": slog.LevelError,
+	} {
+		level, err := ParseLevel(input)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", input, err)
+		}
+
+		if level != expected {
+			t.Errorf("expected %s for %q, got %s", expected, input, level)
+		}
+	}
+
+	_, err := ParseLevel("loud")
+	if err == nil {
+		t.Fatal("expected error for unknown level")
+	}
+}
+
+func TestNewTraceID(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	logger, err := New(&buf, slog.LevelInfo, "text")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x01},
+		SpanID:  trace.SpanID{0x01},
+	})
+
+	logger.InfoContext(trace.ContextWithSpanContext(context.Background(), sc), "traced")
+
+	expected := "trace_id=" + sc.TraceID().String()
+	if !strings.Contains(buf.String(), expected) {
+		t.Fatalf("expected %q in %q", expected, buf.String())
+	}
+}
@@ -1,16 +1,14 @@
 package color
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"image"
-	_ "image/jpeg"
 	"path"
 
 	"github.com/EdlinOrg/prominentcolor"
 	"github.com/charlieegan3/storage-console/pkg/meta"
+	"github.com/charlieegan3/storage-console/pkg/meta/decode"
 	"github.com/minio/minio-go/v7"
 )
 
@@ -20,8 +18,12 @@ func (c *ColorAnalysisProcessor) Name() string {
 	return "color"
 }
 
+func (c *ColorAnalysisProcessor) Version() int {
+	return 1
+}
+
 func (t *ColorAnalysisProcessor) ContentTypes() []string {
-	return []string{"image/jpeg", "image/jpg", "image/jp2"}
+	return meta.PhotoContentTypes
 }
 
 func (c *ColorAnalysisProcessor) Process(
@@ -29,7 +31,7 @@ func (c *ColorAnalysisProcessor) Process(
 	objectInfo *minio.ObjectInfo,
 	content []byte,
 ) ([]meta.PutMetadata, error) {
-	img, _, err := image.Decode(bytes.NewReader(content))
+	img, err := decode.Image(content)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode image: %w", err)
 	}
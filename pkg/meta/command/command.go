@@ -0,0 +1,139 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/charlieegan3/storage-console/pkg/meta"
+)
+
+const (
+	InputStdin = "stdin"
+	InputFile  = "file"
+
+	DefaultTimeout = time.Minute
+)
+
+// CommandProcessor runs an external executable for each blob. The blob is
+// passed on stdin, or as a temp file path appended to the arguments, and
+// the command must write a JSON object to stdout. The output is stored as
+// metadata and the keys of the object are loaded as properties.
+type CommandProcessor struct {
+	ProcessorName string
+	// Command is the executable and its arguments
+	Command []string
+	// Input is either InputStdin or InputFile
+	Input   string
+	Timeout time.Duration
+	// ProcessorVersion should be increased when the command changes to
+	// process existing blobs again, defaults to 1
+	ProcessorVersion int
+
+	ProcessorContentTypes []string
+}
+
+func (c *CommandProcessor) Name() string {
+	return c.ProcessorName
+}
+
+func (c *CommandProcessor) Version() int {
+	return max(c.ProcessorVersion, 1)
+}
+
+func (c *CommandProcessor) ContentTypes() []string {
+	return c.ProcessorContentTypes
+}
+
+func (c *CommandProcessor) Process(
+	ctx context.Context,
+	objectInfo *minio.ObjectInfo,
+	content []byte,
+) ([]meta.PutMetadata, error) {
+	if len(c.Command) == 0 {
+		return nil, fmt.Errorf("no command configured for processor %s", c.ProcessorName)
+	}
+
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	args := c.Command[1:]
+
+	var stdin *bytes.Reader
+	switch c.Input {
+	case "", InputStdin:
+		stdin = bytes.NewReader(content)
+	case InputFile:
+		f, err := os.CreateTemp("", "storage-console-*"+path.Ext(objectInfo.Key))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temp file: %w", err)
+		}
+		defer os.Remove(f.Name())
+
+		_, err = f.Write(content)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to write temp file: %w", err)
+		}
+
+		err = f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to close temp file: %w", err)
+		}
+
+		args = append(append([]string{}, args...), f.Name())
+	default:
+		return nil, fmt.Errorf("unknown input mode: %q", c.Input)
+	}
+
+	cmd := exec.CommandContext(ctx, c.Command[0], args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+
+	stdout := bytes.NewBuffer([]byte{})
+	stderr := bytes.NewBuffer([]byte{})
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("command timed out after %s", timeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("command failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var output map[string]any
+	err = json.Unmarshal(stdout.Bytes(), &output)
+	if err != nil {
+		return nil, fmt.Errorf("command output must be a JSON object: %w", err)
+	}
+
+	jsonData, err := json.Marshal(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal command output: %w", err)
+	}
+
+	return []meta.PutMetadata{
+		{
+			Path:        path.Join(c.Name(), objectInfo.ETag+".json"),
+			ContentType: meta.JSON,
+			Content:     jsonData,
+		},
+	}, nil
+}
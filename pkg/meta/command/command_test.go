@@ -0,0 +1,105 @@
+package command_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/charlieegan3/storage-console/pkg/meta"
+	"github.com/charlieegan3/storage-console/pkg/meta/command"
+)
+
+func TestCommandProcessor(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		processor     command.CommandProcessor
+		expected      map[string]any
+		expectedError bool
+	}{
+		"stdin": {
+			processor: command.CommandProcessor{
+				ProcessorName: "bytes",
+				Command:       []string{"sh", "-c", `printf '{"bytes": %d}' "$(wc -c)"`},
+			},
+			expected: map[string]any{"bytes": float64(11)},
+		},
+		"file": {
+			processor: command.CommandProcessor{
+				ProcessorName: "ext",
+				Command:       []string{"sh", "-c", `printf '{"ext": "%s", "content": "%s"}' "${0##*.}" "$(cat "$0")"`},
+				Input:         command.InputFile,
+			},
+			expected: map[string]any{"ext": "txt", "content": "hello world"},
+		},
+		"invalid output": {
+			processor: command.CommandProcessor{
+				ProcessorName: "invalid",
+				Command:       []string{"echo", "not json"},
+			},
+			expectedError: true,
+		},
+		"non-zero exit": {
+			processor: command.CommandProcessor{
+				ProcessorName: "fail",
+				Command:       []string{"sh", "-c", "echo broken >&2; exit 1"},
+			},
+			expectedError: true,
+		},
+		"timeout": {
+			processor: command.CommandProcessor{
+				ProcessorName: "slow",
+				Command:       []string{"sleep", "5"},
+				Timeout:       100 * time.Millisecond,
+			},
+			expectedError: true,
+		},
+	}
+
+	for testCase, testData := range tests {
+		t.Run(testCase, func(t *testing.T) {
+			t.Parallel()
+
+			metadata, err := testData.processor.Process(context.Background(), &minio.ObjectInfo{
+				ETag: "foobar",
+				Key:  "data/hello.txt",
+			}, []byte("hello world"))
+			if testData.expectedError {
+				if err == nil {
+					t.Fatalf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if len(metadata) != 1 {
+				t.Fatalf("expected 1 metadata entry, got %d", len(metadata))
+			}
+
+			if exp, got := testData.processor.ProcessorName+"/foobar.json", metadata[0].Path; exp != got {
+				t.Fatalf("expected path %q, got %q", exp, got)
+			}
+
+			if metadata[0].ContentType != meta.JSON {
+				t.Fatalf("expected content type 'json', got '%v'", metadata[0].ContentType)
+			}
+
+			var output map[string]any
+			err = json.Unmarshal(metadata[0].Content, &output)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			for k, v := range testData.expected {
+				if output[k] != v {
+					t.Fatalf("expected %s to be %v, got %v", k, v, output[k])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package meta
+
+import (
+	"path"
+	"strings"
+)
+
+// RawContentTypes are the content types used for camera RAW files
+var RawContentTypes = []string{
+	"image/x-sony-arw",
+	"image/x-canon-cr2",
+	"image/x-canon-cr3",
+	"image/x-nikon-nef",
+	"image/x-adobe-dng",
+	"image/x-fuji-raf",
+	"image/x-panasonic-rw2",
+	"image/x-olympus-orf",
+}
+
+// PhotoContentTypes are the content types of photos which processors can
+// read, see decode.Image.
+var PhotoContentTypes = append([]string{
+	"image/jpeg", "image/jpg", "image/jp2",
+	"image/png",
+	"image/gif",
+	"image/webp",
+	"image/tiff",
+	"image/heic",
+	"image/heif",
+	"image/avif",
+}, RawContentTypes...)
+
+// SidecarContentType is used for XMP sidecar files, which hold the edits
+// and metadata for the photo with the same name
+const SidecarContentType = "application/rdf+xml"
+
+var photoExtensions = map[string]string{
+	".arw":  "image/x-sony-arw",
+	".cr2":  "image/x-canon-cr2",
+	".cr3":  "image/x-canon-cr3",
+	".nef":  "image/x-nikon-nef",
+	".dng":  "image/x-adobe-dng",
+	".raf":  "image/x-fuji-raf",
+	".rw2":  "image/x-panasonic-rw2",
+	".orf":  "image/x-olympus-orf",
+	".heic": "image/heic",
+	".heif": "image/heif",
+	".avif": "image/avif",
+	".webp": "image/webp",
+	".xmp":  SidecarContentType,
+}
+
+// DetectContentType returns a more specific content type for photos that
+// object stores don't recognise and report as generic binary data.
+func DetectContentType(key, reported string) string {
+	switch reported {
+	case "", "application/octet-stream", "binary/octet-stream":
+	default:
+		return reported
+	}
+
+	if ct, ok := photoExtensions[strings.ToLower(path.Ext(key))]; ok {
+		return ct
+	}
+
+	return reported
+}
@@ -0,0 +1,47 @@
+package meta
+
+import "testing"
+
+func TestDetectContentType(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		key      string
+		reported string
+		expected string
+	}{
+		"reported type is kept": {
+			key:      "photo.jpg",
+			reported: "image/jpeg",
+			expected: "image/jpeg",
+		},
+		"raw file from octet stream": {
+			key:      "2024/DSC0001.ARW",
+			reported: "application/octet-stream",
+			expected: "image/x-sony-arw",
+		},
+		"heic with missing type": {
+			key:      "IMG_0001.heic",
+			reported: "",
+			expected: "image/heic",
+		},
+		"xmp sidecar": {
+			key:      "2024/DSC0001.xmp",
+			reported: "application/octet-stream",
+			expected: "application/rdf+xml",
+		},
+		"unknown extension": {
+			key:      "backup.bin",
+			reported: "application/octet-stream",
+			expected: "application/octet-stream",
+		},
+	}
+
+	for testCase, testData := range tests {
+		t.Run(testCase, func(t *testing.T) {
+			if got := DetectContentType(testData.key, testData.reported); got != testData.expected {
+				t.Fatalf("expected %q, got %q", testData.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package decode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+// Image decodes a photo into an image.Image. Formats supported by Go's image
+// packages are decoded directly, RAW files use their embedded JPEG preview
+// and anything else (e.g. HEIC) is converted by vips.
+func Image(content []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err == nil {
+		return img, nil
+	}
+
+	if preview := EmbeddedJPEG(content); preview != nil {
+		img, err := jpeg.Decode(bytes.NewReader(preview))
+		if err == nil {
+			return img, nil
+		}
+	}
+
+	vipsImage, err := vips.NewImageFromBuffer(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load image with vips: %w", err)
+	}
+	defer vipsImage.Close()
+
+	img, err = vipsImage.ToImage(vips.NewDefaultJPEGExportParams())
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert image with vips: %w", err)
+	}
+
+	return img, nil
+}
+
+// EmbeddedJPEG returns the highest resolution JPEG stream found inside
+// content, or nil. RAW formats store a full size JPEG preview alongside the
+// sensor data which is much simpler to use than decoding the RAW data itself.
+func EmbeddedJPEG(content []byte) []byte {
+	soi := []byte{0xFF, 0xD8, 0xFF}
+	eoi := []byte{0xFF, 0xD9}
+
+	end := bytes.LastIndex(content, eoi)
+	if end < 0 {
+		return nil
+	}
+	end += len(eoi)
+
+	var largest []byte
+	var largestArea int
+
+	// the file itself is skipped, a plain JPEG will already have decoded
+	for offset := 1; offset < end; {
+		start := bytes.Index(content[offset:end], soi)
+		if start < 0 {
+			break
+		}
+		start += offset
+
+		// the decoder ignores trailing data so the stream can run to the last
+		// EOI marker. Reading the header also skips SOI marker sequences which
+		// appear by chance in sensor data.
+		candidate := content[start:end]
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(candidate))
+		if err == nil && cfg.Width*cfg.Height > largestArea {
+			largest = candidate
+			largestArea = cfg.Width * cfg.Height
+		}
+
+		offset = start + len(soi)
+	}
+
+	return largest
+}
@@ -0,0 +1,81 @@
+package decode
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encodeJPEG(t *testing.T, width, height int) []byte {
+	buf := bytes.NewBuffer([]byte{})
+
+	err := jpeg.Encode(buf, image.NewRGBA(image.Rect(0, 0, width, height)), nil)
+	if err != nil {
+		t.Fatalf("failed to encode jpeg: %s", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestEmbeddedJPEG(t *testing.T) {
+	t.Parallel()
+
+	// this is laid out like a RAW file, with a header, a small thumbnail,
+	// some sensor data and the full size preview
+	var content []byte
+	content = append(content, []byte("II*\x00fake raw header")...)
+	content = append(content, encodeJPEG(t, 16, 16)...)
+	content = append(content, []byte{0xFF, 0xD8, 0xFF, 0x00, 0x01, 0x02}...)
+	content = append(content, encodeJPEG(t, 64, 32)...)
+	content = append(content, []byte("trailing sensor data")...)
+
+	preview := EmbeddedJPEG(content)
+	if preview == nil {
+		t.Fatalf("expected a preview to be found")
+	}
+
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(preview))
+	if err != nil {
+		t.Fatalf("failed to decode preview: %s", err)
+	}
+
+	if cfg.Width != 64 || cfg.Height != 32 {
+		t.Fatalf("expected largest preview, got %dx%d", cfg.Width, cfg.Height)
+	}
+
+	if EmbeddedJPEG([]byte("no jpeg here")) != nil {
+		t.Fatalf("expected no preview to be found")
+	}
+}
+
+func TestImage(t *testing.T) {
+	t.Parallel()
+
+	buf := bytes.NewBuffer([]byte{})
+	err := png.Encode(buf, image.NewRGBA(image.Rect(0, 0, 4, 2)))
+	if err != nil {
+		t.Fatalf("failed to encode png: %s", err)
+	}
+
+	img, err := Image(buf.Bytes())
+	if err != nil {
+		t.Fatalf("failed to decode png: %s", err)
+	}
+
+	if exp, got := 4, img.Bounds().Dx(); exp != got {
+		t.Fatalf("expected width %d, got %d", exp, got)
+	}
+
+	raw := append([]byte("II*\x00fake raw header"), encodeJPEG(t, 8, 8)...)
+
+	img, err = Image(raw)
+	if err != nil {
+		t.Fatalf("failed to decode raw: %s", err)
+	}
+
+	if exp, got := 8, img.Bounds().Dx(); exp != got {
+		t.Fatalf("expected width %d, got %d", exp, got)
+	}
+}
@@ -19,10 +19,14 @@ func (p *ExifMetadataProcessor) Name() string {
 	return "exif"
 }
 
+func (p *ExifMetadataProcessor) Version() int {
+	return 1
+}
+
 func (t *ExifMetadataProcessor) ContentTypes() []string {
-	return []string{
-		"image/jpeg", "image/jpg", "image/jp2",
-	}
+	// exif data is found by searching for the TIFF header, which works for
+	// the container formats used by HEIC, WebP, PNG and TIFF based RAW files
+	return meta.PhotoContentTypes
 }
 
 func (p *ExifMetadataProcessor) Process(
@@ -30,11 +34,37 @@ func (p *ExifMetadataProcessor) Process(
 	objectInfo *minio.ObjectInfo,
 	content []byte,
 ) ([]meta.PutMetadata, error) {
+	metadata, err := Collect(content)
+	if err != nil {
+		return nil, err
+	}
+
+	if metadata == nil {
+		return []meta.PutMetadata{}, nil
+	}
+
+	jsonData, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("error converting EXIF data to JSON: %w", err)
+	}
+
+	putMetadata := meta.PutMetadata{
+		Path:        path.Join(p.Name(), objectInfo.ETag+".json"),
+		ContentType: meta.JSON,
+		Content:     jsonData,
+	}
+
+	return []meta.PutMetadata{putMetadata}, nil
+}
+
+// Collect returns the value of each exif tag in content by tag name, nil is
+// returned when there is no exif data
+func Collect(content []byte) (map[string]interface{}, error) {
 	metadata := make(map[string]interface{})
 
 	rawExif, err := exif.SearchAndExtractExif(content)
 	if err == exif.ErrNoExif {
-		return []meta.PutMetadata{}, nil
+		return nil, nil
 	} else if err != nil {
 		return nil, fmt.Errorf("failed to get raw exif data: %w", err)
 	}
@@ -67,16 +97,5 @@ func (p *ExifMetadataProcessor) Process(
 		return nil, fmt.Errorf("failed to walk exif data tree: %w", err)
 	}
 
-	jsonData, err := json.Marshal(metadata)
-	if err != nil {
-		return nil, fmt.Errorf("error converting EXIF data to JSON: %w", err)
-	}
-
-	putMetadata := meta.PutMetadata{
-		Path:        path.Join(p.Name(), objectInfo.ETag+".json"),
-		ContentType: meta.JSON,
-		Content:     jsonData,
-	}
-
-	return []meta.PutMetadata{putMetadata}, nil
+	return metadata, nil
 }
@@ -0,0 +1,167 @@
+package iptc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"unicode/utf8"
+)
+
+const (
+	photoshopHeader = "Photoshop 3.0\x00"
+	// iptcResourceID is the image resource holding the IPTC-IIM records
+	iptcResourceID = 0x0404
+	// applicationRecord holds the descriptive datasets
+	applicationRecord = 2
+)
+
+// datasetNames are the names of the application record datasets, others
+// are named by number
+var datasetNames = map[byte]string{
+	4:   "ObjectAttributeReference",
+	5:   "ObjectName",
+	7:   "EditStatus",
+	10:  "Urgency",
+	12:  "SubjectReference",
+	15:  "Category",
+	20:  "SupplementalCategories",
+	22:  "FixtureIdentifier",
+	25:  "Keywords",
+	26:  "ContentLocationCode",
+	27:  "ContentLocationName",
+	30:  "ReleaseDate",
+	35:  "ReleaseTime",
+	37:  "ExpirationDate",
+	38:  "ExpirationTime",
+	40:  "SpecialInstructions",
+	55:  "DateCreated",
+	60:  "TimeCreated",
+	62:  "DigitalCreationDate",
+	63:  "DigitalCreationTime",
+	65:  "OriginatingProgram",
+	70:  "ProgramVersion",
+	75:  "ObjectCycle",
+	80:  "By-line",
+	85:  "By-lineTitle",
+	90:  "City",
+	92:  "Sub-location",
+	95:  "Province-State",
+	100: "Country-PrimaryLocationCode",
+	101: "Country-PrimaryLocationName",
+	103: "OriginalTransmissionReference",
+	105: "Headline",
+	110: "Credit",
+	115: "Source",
+	116: "CopyrightNotice",
+	118: "Contact",
+	120: "Caption-Abstract",
+	122: "Writer-Editor",
+	135: "LanguageIdentifier",
+}
+
+// Parse returns the IPTC application record datasets stored in the
+// Photoshop image resources of a JPEG, keyed by dataset name. Repeatable
+// datasets, such as Keywords, have a value per occurrence. nil is returned
+// when there is no IPTC data.
+func Parse(content []byte) (map[string][]string, error) {
+	start := bytes.Index(content, []byte(photoshopHeader))
+	if start == -1 {
+		return nil, nil
+	}
+
+	resources := content[start+len(photoshopHeader):]
+
+	for len(resources) >= 12 && string(resources[:4]) == "8BIM" {
+		id := binary.BigEndian.Uint16(resources[4:6])
+
+		// the resource name is a pascal string padded to an even length
+		nameLength := int(resources[6]) + 1
+		if nameLength%2 != 0 {
+			nameLength++
+		}
+
+		offset := 6 + nameLength
+		if len(resources) < offset+4 {
+			return nil, fmt.Errorf("truncated image resource %#04x", id)
+		}
+
+		size := int(binary.BigEndian.Uint32(resources[offset : offset+4]))
+		offset += 4
+
+		if len(resources) < offset+size {
+			return nil, fmt.Errorf("truncated image resource %#04x", id)
+		}
+
+		if id == iptcResourceID {
+			return parseRecords(resources[offset : offset+size])
+		}
+
+		if size%2 != 0 {
+			size++
+		}
+
+		if offset+size > len(resources) {
+			break
+		}
+
+		resources = resources[offset+size:]
+	}
+
+	return nil, nil
+}
+
+func parseRecords(data []byte) (map[string][]string, error) {
+	datasets := make(map[string][]string)
+
+	for len(data) >= 5 && data[0] == 0x1c {
+		record, dataset := data[1], data[2]
+		size := int(binary.BigEndian.Uint16(data[3:5]))
+
+		// extended datasets are only used for large binary values
+		if size&0x8000 != 0 {
+			break
+		}
+
+		if len(data) < 5+size {
+			return nil, fmt.Errorf("truncated dataset %d:%d", record, dataset)
+		}
+
+		value := data[5 : 5+size]
+		data = data[5+size:]
+
+		// dataset 0 is the binary record version
+		if record != applicationRecord || dataset == 0 || size == 0 {
+			continue
+		}
+
+		name, ok := datasetNames[dataset]
+		if !ok {
+			name = fmt.Sprintf("Dataset%d", dataset)
+		}
+
+		datasets[name] = append(datasets[name], decode(value))
+	}
+
+	if len(datasets) == 0 {
+		return nil, nil
+	}
+
+	return datasets, nil
+}
+
+// decode returns the value as a string, values which aren't UTF-8 are
+// assumed to be Latin-1 as this was the common default
+func decode(value []byte) string {
+	value = bytes.TrimRight(value, "\x00")
+
+	if utf8.Valid(value) {
+		return string(value)
+	}
+
+	runes := make([]rune, len(value))
+	for i, b := range value {
+		runes[i] = rune(b)
+	}
+
+	return string(runes)
+}
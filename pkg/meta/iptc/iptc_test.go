@@ -0,0 +1,80 @@
+package iptc_test
+
+import (
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/charlieegan3/storage-console/pkg/meta/iptc"
+)
+
+func TestParseFixture(t *testing.T) {
+	content, err := os.ReadFile("../fixtures/rx100-landscape.jpg")
+	if err != nil {
+		t.Fatalf("failed to read image file: %v", err)
+	}
+
+	datasets, err := iptc.Parse(content)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := map[string][]string{
+		"DateCreated":         {"20241222"},
+		"TimeCreated":         {"144429+0000"},
+		"DigitalCreationDate": {"20241222"},
+		"DigitalCreationTime": {"144429+0000"},
+	}
+
+	if len(datasets) != len(expected) {
+		t.Fatalf("expected %d datasets, got %v", len(expected), datasets)
+	}
+
+	for name, values := range expected {
+		if got := datasets[name]; !slices.Equal(got, values) {
+			t.Errorf("expected %s to be %v, got %v", name, values, got)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	dataset := func(number byte, value string) []byte {
+		return append([]byte{0x1c, 2, number, 0, byte(len(value))}, value...)
+	}
+
+	var records []byte
+	records = append(records, dataset(25, "alps")...)
+	records = append(records, dataset(25, "snow")...)
+	records = append(records, dataset(120, "Caf\xe9 by the lake")...)
+
+	content := []byte("\xff\xd8\xff\xedPhotoshop 3.0\x00")
+	// an unrelated resource with a name, which must be skipped
+	content = append(content, "8BIM\x03\xed\x03abc\x00\x00\x00\x02\x00\x00"...)
+	content = append(content, "8BIM\x04\x04\x00\x00\x00\x00\x00"...)
+	content = append(content, byte(len(records)))
+	content = append(content, records...)
+
+	datasets, err := iptc.Parse(content)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if got := datasets["Keywords"]; !slices.Equal(got, []string{"alps", "snow"}) {
+		t.Errorf("unexpected keywords: %v", got)
+	}
+
+	if got := datasets["Caption-Abstract"]; !slices.Equal(got, []string{"Café by the lake"}) {
+		t.Errorf("unexpected caption: %v", got)
+	}
+}
+
+func TestParseMissing(t *testing.T) {
+	datasets, err := iptc.Parse([]byte("\xff\xd8\xff\xd9"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if datasets != nil {
+		t.Fatalf("expected no datasets, got %v", datasets)
+	}
+}
@@ -0,0 +1,175 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/minio/minio-go/v7"
+
+	"github.com/charlieegan3/storage-console/pkg/meta"
+)
+
+const (
+	DefaultCommand  = "tesseract"
+	DefaultMaxPages = 10
+
+	// pdfDensity is the DPI used to render PDF pages, tesseract works best
+	// with text at around 300 DPI
+	pdfDensity = 300
+)
+
+// Available reports whether the tesseract command can be found
+func Available(command string) bool {
+	if command == "" {
+		command = DefaultCommand
+	}
+
+	_, err := exec.LookPath(command)
+
+	return err == nil
+}
+
+// OCRProcessor extracts text from images and scanned PDFs using tesseract
+type OCRProcessor struct {
+	// Command is the tesseract executable, DefaultCommand if unset
+	Command string
+	// Languages are passed to tesseract's -l flag, e.g. "eng+deu"
+	Languages string
+	// MaxPages limits the number of PDF pages which are recognised
+	MaxPages int
+}
+
+func (o *OCRProcessor) Name() string {
+	return "ocr"
+}
+
+func (o *OCRProcessor) Version() int {
+	return 1
+}
+
+func (o *OCRProcessor) ContentTypes() []string {
+	return []string{
+		"image/jpeg", "image/jpg",
+		"image/png",
+		"image/tiff",
+		"image/gif",
+		"image/webp",
+		"image/bmp",
+		"application/pdf",
+	}
+}
+
+func (o *OCRProcessor) Process(
+	ctx context.Context,
+	objectInfo *minio.ObjectInfo,
+	content []byte,
+) ([]meta.PutMetadata, error) {
+	pages := [][]byte{content}
+
+	if objectInfo.ContentType == "application/pdf" {
+		var err error
+		pages, err = o.renderPDF(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render pdf: %w", err)
+		}
+	}
+
+	var texts []string
+	for i, page := range pages {
+		text, err := o.recognise(ctx, page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to recognise text on page %d: %w", i+1, err)
+		}
+
+		if text != "" {
+			texts = append(texts, text)
+		}
+	}
+
+	// nothing is stored for images without text
+	if len(texts) == 0 {
+		return []meta.PutMetadata{}, nil
+	}
+
+	return []meta.PutMetadata{
+		{
+			Path:        path.Join(o.Name(), objectInfo.ETag+"."+meta.ContentTypeToFileExt(meta.TEXT)),
+			ContentType: meta.TEXT,
+			Content:     []byte(strings.Join(texts, "\n\n")),
+		},
+	}, nil
+}
+
+// renderPDF returns each page of a PDF as a PNG image
+func (o *OCRProcessor) renderPDF(content []byte) ([][]byte, error) {
+	doc, err := vips.NewImageFromBuffer(content)
+	if err != nil {
+		return nil, fmt.Errorf("could not load pdf: %w", err)
+	}
+	numPages := doc.Pages()
+	doc.Close()
+
+	maxPages := o.MaxPages
+	if maxPages == 0 {
+		maxPages = DefaultMaxPages
+	}
+
+	if numPages > maxPages {
+		numPages = maxPages
+	}
+
+	var pages [][]byte
+	for i := 0; i < numPages; i++ {
+		params := vips.NewImportParams()
+		params.Page.Set(i)
+		params.Density.Set(pdfDensity)
+
+		page, err := vips.LoadImageFromBuffer(content, params)
+		if err != nil {
+			return nil, fmt.Errorf("could not load page %d: %w", i+1, err)
+		}
+
+		bs, _, err := page.ExportPng(vips.NewPngExportParams())
+		page.Close()
+		if err != nil {
+			return nil, fmt.Errorf("could not export page %d: %w", i+1, err)
+		}
+
+		pages = append(pages, bs)
+	}
+
+	return pages, nil
+}
+
+// recognise runs tesseract on a single image, passing it on stdin
+func (o *OCRProcessor) recognise(ctx context.Context, image []byte) (string, error) {
+	command := o.Command
+	if command == "" {
+		command = DefaultCommand
+	}
+
+	args := []string{"stdin", "stdout"}
+	if o.Languages != "" {
+		args = append(args, "-l", o.Languages)
+	}
+
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Stdin = bytes.NewReader(image)
+
+	stdout := bytes.NewBuffer([]byte{})
+	stderr := bytes.NewBuffer([]byte{})
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("tesseract failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,109 @@
+package ocr_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/charlieegan3/storage-console/pkg/meta"
+	"github.com/charlieegan3/storage-console/pkg/meta/ocr"
+)
+
+// fakeTesseract writes a script which behaves like tesseract reading from
+// stdin and writing to stdout
+func fakeTesseract(t *testing.T, script string) string {
+	p := filepath.Join(t.TempDir(), "tesseract")
+
+	err := os.WriteFile(p, []byte("#!/bin/sh\n"+script), 0o755)
+	if err != nil {
+		t.Fatalf("failed to write fake tesseract: %s", err)
+	}
+
+	return p
+}
+
+func TestOCRProcessor(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		script           string
+		expectedText     string
+		expectedMetadata int
+		expectedError    bool
+	}{
+		"text found": {
+			// echo the args so they can be checked along with the input
+			script:           `printf '%s\n' "$*"; cat`,
+			expectedText:     "stdin stdout -l eng\nRECEIPT TOTAL 12.50",
+			expectedMetadata: 1,
+		},
+		"no text": {
+			script:           `printf '  \n'`,
+			expectedMetadata: 0,
+		},
+		"tesseract fails": {
+			script:        `echo "bad image" >&2; exit 1`,
+			expectedError: true,
+		},
+	}
+
+	for testCase, testData := range tests {
+		t.Run(testCase, func(t *testing.T) {
+			t.Parallel()
+
+			processor := ocr.OCRProcessor{
+				Command:   fakeTesseract(t, testData.script),
+				Languages: "eng",
+			}
+
+			metadata, err := processor.Process(context.Background(), &minio.ObjectInfo{
+				ETag:        "foobar",
+				ContentType: "image/png",
+			}, []byte("RECEIPT TOTAL 12.50"))
+			if testData.expectedError {
+				if err == nil {
+					t.Fatalf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if exp, got := testData.expectedMetadata, len(metadata); exp != got {
+				t.Fatalf("expected %d metadata entries, got %d", exp, got)
+			}
+
+			if len(metadata) == 0 {
+				return
+			}
+
+			if exp, got := "ocr/foobar.txt", metadata[0].Path; exp != got {
+				t.Fatalf("expected path %q, got %q", exp, got)
+			}
+
+			if metadata[0].ContentType != meta.TEXT {
+				t.Fatalf("expected content type 'text', got '%v'", metadata[0].ContentType)
+			}
+
+			if exp, got := testData.expectedText, string(metadata[0].Content); exp != got {
+				t.Fatalf("expected %q, got %q", exp, got)
+			}
+		})
+	}
+}
+
+func TestAvailable(t *testing.T) {
+	t.Parallel()
+
+	if ocr.Available(filepath.Join(t.TempDir(), "missing")) {
+		t.Fatalf("expected missing command to be unavailable")
+	}
+
+	if !ocr.Available(fakeTesseract(t, "")) {
+		t.Fatalf("expected command to be available")
+	}
+}
@@ -2,6 +2,8 @@ package meta
 
 import (
 	"context"
+	"fmt"
+	"hash/fnv"
 
 	"github.com/minio/minio-go/v7"
 )
@@ -11,6 +13,9 @@ type ContentType int
 const (
 	JPG ContentType = iota
 	JSON
+	WEBP
+	AVIF
+	TEXT
 )
 
 func ContentTypeToString(contentType ContentType) string {
@@ -19,6 +24,12 @@ func ContentTypeToString(contentType ContentType) string {
 		return "image/jpeg"
 	case JSON:
 		return "application/json"
+	case WEBP:
+		return "image/webp"
+	case AVIF:
+		return "image/avif"
+	case TEXT:
+		return "text/plain; charset=utf-8"
 	default:
 		return ""
 	}
@@ -30,11 +41,32 @@ func ContentTypeToFileExt(contentType ContentType) string {
 		return "jpg"
 	case JSON:
 		return "json"
+	case WEBP:
+		return "webp"
+	case AVIF:
+		return "avif"
+	case TEXT:
+		return "txt"
 	default:
 		return ""
 	}
 }
 
+// ImageFormatFromString returns the content type for an image output format
+// name as used in config, e.g. "webp".
+func ImageFormatFromString(format string) (ContentType, error) {
+	switch format {
+	case "", "jpeg", "jpg":
+		return JPG, nil
+	case "webp":
+		return WEBP, nil
+	case "avif":
+		return AVIF, nil
+	default:
+		return 0, fmt.Errorf("unsupported image format: %q", format)
+	}
+}
+
 type PutMetadata struct {
 	Path        string
 	ContentType ContentType
@@ -43,6 +75,9 @@ type PutMetadata struct {
 
 type MetadataOperationProcessor interface {
 	Name() string
+	// Version is stored with each result, it must change when the output
+	// changes so that existing blobs are processed again
+	Version() int
 	ContentTypes() []string
 	Process(
 		ctx context.Context,
@@ -50,3 +85,16 @@ type MetadataOperationProcessor interface {
 		content []byte,
 	) ([]PutMetadata, error)
 }
+
+// ConfigVersion combines a processor's version with the config its output
+// depends on, so that blobs are processed again when either changes. The
+// result is positive and fits in the database's version column.
+func ConfigVersion(version int, config ...any) int {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%d", version)
+	for _, c := range config {
+		fmt.Fprintf(h, ":%v", c)
+	}
+
+	return max(int(h.Sum32()&0x7fffffff), 1)
+}
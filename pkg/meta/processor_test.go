@@ -0,0 +1,28 @@
+package meta
+
+import "testing"
+
+func TestConfigVersion(t *testing.T) {
+	t.Parallel()
+
+	base := ConfigVersion(2, []int{300, 1200}, JPG, 0)
+
+	if base <= 0 {
+		t.Fatalf("expected a positive version, got %d", base)
+	}
+
+	if got := ConfigVersion(2, []int{300, 1200}, JPG, 0); got != base {
+		t.Fatalf("expected the same config to give the same version, got %d and %d", base, got)
+	}
+
+	for name, version := range map[string]int{
+		"processor version": ConfigVersion(3, []int{300, 1200}, JPG, 0),
+		"sizes":             ConfigVersion(2, []int{300}, JPG, 0),
+		"format":            ConfigVersion(2, []int{300, 1200}, WEBP, 0),
+		"quality":           ConfigVersion(2, []int{300, 1200}, JPG, 80),
+	} {
+		if version == base {
+			t.Errorf("expected a change in %s to change the version", name)
+		}
+	}
+}
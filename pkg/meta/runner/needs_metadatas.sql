@@ -1,4 +1,3 @@
-SET SCHEMA 'storage_console';
 WITH needs_metadatas AS (
     SELECT
         blobs.id,
@@ -11,13 +10,23 @@ WITH needs_metadatas AS (
         ON object_blobs.blob_id = blobs.id
     LEFT JOIN blob_metadata
         ON blob_metadata.blob_id = blobs.id
+        AND blob_metadata.processor = $1
+    LEFT JOIN processing_errors
+        ON processing_errors.blob_id = blobs.id
+        AND processing_errors.processor = $1
     WHERE
+      objects.bucket_id = $4 AND
       objects.deleted_at IS NULL AND
-      (blob_metadata.%s = 'unknown' OR blob_metadata.%s is null) AND
+      (
+        blob_metadata.result = 'unknown' OR
+        blob_metadata.result is null OR
+        blob_metadata.version <> $3
+      ) AND
+      (processing_errors.blob_id IS NULL OR processing_errors.next_retry_at <= now()) AND
       blobs.content_type_id IN (
         SELECT id
         FROM content_types
-        WHERE name IN (%s)
+        WHERE name = ANY($2)
     )
 )
 SELECT
@@ -25,4 +34,3 @@ SELECT
     md5,
     key
 FROM needs_metadatas
-
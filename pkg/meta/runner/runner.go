@@ -8,40 +8,72 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
-	"path"
+	"log/slog"
 	"strings"
+	"time"
 
 	"github.com/charlieegan3/storage-console/pkg/database"
+	"github.com/charlieegan3/storage-console/pkg/layout"
+	"github.com/charlieegan3/storage-console/pkg/logging"
 	"github.com/charlieegan3/storage-console/pkg/meta"
-	"github.com/charlieegan3/storage-console/pkg/meta/color"
-	"github.com/charlieegan3/storage-console/pkg/meta/exif"
-	"github.com/charlieegan3/storage-console/pkg/meta/thumbnail"
+	"github.com/charlieegan3/storage-console/pkg/metrics"
+	"github.com/charlieegan3/storage-console/pkg/processors"
+	"github.com/charlieegan3/storage-console/pkg/tracing"
+	"github.com/lib/pq"
 	"github.com/minio/minio-go/v7"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 //go:embed needs_metadatas.sql
 var needsMetadatasSQL string
 
+//go:embed set_error.sql
+var setErrorSQL string
+
 const (
-	metaPath = "meta/"
-	dataPath = "data/"
+	defaultMaxAttempts  = 5
+	defaultRetryBackoff = time.Minute
+	maxRetryBackoff     = 24 * time.Hour
 )
 
 type Report struct {
 	Counts map[string]int
+	// Errors is the number of blobs each processor failed to process
+	Errors map[string]int
 }
 
 type Options struct {
 	SchemaName string
 	BucketName string
+	// BucketID is the database id of the bucket, from database.EnsureBuckets
+	BucketID int
 
 	Prefix string
+	// Layout is where objects are read from and processor output is
+	// written, layout.Default is used when it's nil
+	Layout *layout.Layout
 
 	EnabledProcessors []string
 
-	LoggerError *log.Logger
-	LoggerInfo  *log.Logger
+	// MaxAttempts is the number of times a failing blob is tried before
+	// it's left for a manual retry, defaults to 5
+	MaxAttempts int
+	// RetryBackoff is the wait before the first retry, it doubles with each
+	// failed attempt up to a day. Defaults to a minute
+	RetryBackoff time.Duration
+
+	// Registry holds the processors which can be enabled, the built-in
+	// processors are used when this is nil
+	Registry *processors.Registry
+
+	// Logger is used for progress and errors, nothing is logged when it's
+	// nil
+	Logger *slog.Logger
+	// Metrics counts processor results, it may be nil
+	Metrics *metrics.Metrics
+	// MetricsBucket labels the counts, it's the configured bucket's name
+	// since several can share an S3 bucket. BucketName is used when unset
+	MetricsBucket string
 }
 
 type blob struct {
@@ -56,33 +88,106 @@ func Run(
 	minioClient *minio.Client,
 	opts *Options,
 ) (*Report, error) {
-	var processors []meta.MetadataOperationProcessor
+	l := layout.OrDefault(opts.Layout)
+	logger := logging.OrDiscard(opts.Logger).With(logging.FieldBucket, opts.BucketName)
+
+	registry := opts.Registry
+	if registry == nil {
+		var err error
+		registry, err = processors.NewBuiltinRegistry(processors.BuiltinOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("could not build processor registry: %s", err)
+		}
+	}
+
+	var enabledProcessors []meta.MetadataOperationProcessor
 	for _, processorName := range opts.EnabledProcessors {
-		processor, err := processorForName(processorName)
+		processor, err := registry.Meta(processorName)
 		if err != nil {
 			return nil, fmt.Errorf("could not get processor: %s", err)
 		}
 
-		processors = append(processors, processor)
+		enabledProcessors = append(enabledProcessors, processor)
 	}
 
-	txn, err := database.NewTxnWithSchema(db, opts.SchemaName)
+	txn, err := database.NewTxnWithSchemaContext(ctx, db, opts.SchemaName)
 	if err != nil {
 		return nil, fmt.Errorf("could not start transaction: %s", err)
 	}
+	defer txn.Rollback()
 
 	var rpt Report
 	rpt.Counts = make(map[string]int)
+	rpt.Errors = make(map[string]int)
+
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	retryBackoff := opts.RetryBackoff
+	if retryBackoff <= 0 {
+		retryBackoff = defaultRetryBackoff
+	}
+
+	// failures are recorded against the blob and processor rather than
+	// stopping the run, they are picked up again once the backoff has passed.
+	// They're committed straight away so that the backoff still applies when
+	// the rest of the run fails.
+	recordError := func(b blob, processorName string, processErr error) error {
+		errTxn, err := database.NewTxnWithSchemaContext(ctx, db, opts.SchemaName)
+		if err != nil {
+			return fmt.Errorf("could not start transaction to record processing error: %s", err)
+		}
+		defer errTxn.Rollback()
+
+		var attempts int
+		err = errTxn.QueryRowContext(
+			ctx,
+			setErrorSQL,
+			b.ID,
+			processorName,
+			processErr.Error(),
+			retryBackoff.Seconds(),
+			maxAttempts,
+			maxRetryBackoff.Seconds(),
+		).Scan(&attempts)
+		if err != nil {
+			return fmt.Errorf("could not record processing error: %s", err)
+		}
+
+		err = errTxn.Commit()
+		if err != nil {
+			return fmt.Errorf("could not commit processing error: %s", err)
+		}
+
+		logger.WarnContext(
+			ctx,
+			"processor failed",
+			logging.FieldProcessor, processorName,
+			logging.FieldKey, b.Key,
+			logging.FieldETag, b.MD5,
+			"attempt", attempts,
+			logging.Err(processErr),
+		)
+
+		rpt.Errors[processorName]++
+		opts.Metrics.CountProcessed("meta", processorName, opts.metricsBucket(), processErr)
+
+		return nil
+	}
 
 	blobProcessors := make(map[string][]string)
 	blobs := make(map[string]blob)
 
-	for _, processor := range processors {
-		contentTypes := "'" + strings.Join(processor.ContentTypes(), "', '") + "'"
-
+	for _, processor := range enabledProcessors {
 		rows, err := txn.QueryContext(
 			ctx,
-			fmt.Sprintf(needsMetadatasSQL, processor.Name(), processor.Name(), contentTypes),
+			needsMetadatasSQL,
+			processor.Name(),
+			pq.Array(processor.ContentTypes()),
+			processor.Version(),
+			opts.BucketID,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("could not select missing blobs: %s", err)
@@ -109,37 +214,56 @@ func Run(
 
 	var putMetadatas []meta.PutMetadata
 	for _, blob := range blobs {
-		obj, err := minioClient.GetObject(
-			ctx,
-			opts.BucketName,
-			path.Join(dataPath, blob.Key),
-			minio.GetObjectOptions{},
-		)
-		if err != nil {
-			return nil, fmt.Errorf("could not get object %s: %s", blob.Key, err)
-		}
+		bs, objStat, readErr := readBlob(ctx, minioClient, opts.BucketName, l, blob.Key)
+		if readErr != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
 
-		objStat, err := obj.Stat()
-		if err != nil {
-			return nil, fmt.Errorf("could not stat object %s: %s", blob.Key, err)
-		}
+			for _, processorName := range blobProcessors[blob.MD5] {
+				err := recordError(blob, processorName, readErr)
+				if err != nil {
+					return nil, err
+				}
+			}
 
-		bs, err := io.ReadAll(obj)
-		if err != nil {
-			return nil, fmt.Errorf("could not read object: %s", err)
+			continue
 		}
 
-		opts.LoggerInfo.Printf("processing metadata for blob %s", blob.Key)
+		logger.InfoContext(
+			ctx,
+			"processing metadata",
+			logging.FieldKey, blob.Key,
+			logging.FieldETag, blob.MD5,
+			logging.FieldProcessor, blobProcessors[blob.MD5],
+		)
 
 		for _, processorName := range blobProcessors[blob.MD5] {
-			processor, err := processorForName(processorName)
+			processor, err := registry.Meta(processorName)
 			if err != nil {
 				return nil, fmt.Errorf("could not get processor: %s", err)
 			}
 
-			pms, err := processor.Process(ctx, &objStat, bs)
+			processCtx, span := tracing.Start(
+				ctx,
+				"process "+processorName,
+				attribute.String(logging.FieldProcessor, processorName),
+				attribute.String(logging.FieldKey, blob.Key),
+				attribute.String(logging.FieldETag, blob.MD5),
+			)
+			pms, err := processor.Process(processCtx, objStat, bs)
+			tracing.End(span, err)
 			if err != nil {
-				return nil, fmt.Errorf("could not process blob: %s", err)
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+
+				err = recordError(blob, processorName, fmt.Errorf("could not process blob: %s", err))
+				if err != nil {
+					return nil, err
+				}
+
+				continue
 			}
 
 			result := "unknown"
@@ -150,17 +274,37 @@ func Run(
 			}
 
 			setMetaSQL := `
-INSERT INTO blob_metadata (blob_id, %s)
-VALUES ($1, $2)
-ON CONFLICT (blob_id)
-DO UPDATE SET %s = $2;`
+INSERT INTO blob_metadata (blob_id, processor, result, version)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (blob_id, processor)
+DO UPDATE SET result = $3, version = $4;`
 
-			_, err = txn.Exec(fmt.Sprintf(setMetaSQL, processor.Name(), processor.Name()), blob.ID, result)
+			_, err = txn.Exec(setMetaSQL, blob.ID, processor.Name(), result, processor.Version())
 			if err != nil {
 				return nil, fmt.Errorf("could not set metadata: %s", err)
 			}
 
+			// properties loaded from the previous output must be loaded again
+			_, err = txn.Exec(
+				`DELETE FROM blob_properties WHERE blob_id = $1 AND source = ANY($2) AND property_type = 'Done';`,
+				blob.ID,
+				pq.Array(registry.Dependents(processor.Name())),
+			)
+			if err != nil {
+				return nil, fmt.Errorf("could not reset dependent properties: %s", err)
+			}
+
+			_, err = txn.Exec(
+				`DELETE FROM processing_errors WHERE blob_id = $1 AND processor = $2;`,
+				blob.ID,
+				processor.Name(),
+			)
+			if err != nil {
+				return nil, fmt.Errorf("could not clear processing error: %s", err)
+			}
+
 			rpt.Counts[processorName] += len(pms)
+			opts.Metrics.CountProcessed("meta", processorName, opts.metricsBucket(), nil)
 
 			putMetadatas = append(putMetadatas, pms...)
 		}
@@ -173,8 +317,8 @@ DO UPDATE SET %s = $2;`
 
 		_, err := minioClient.PutObject(
 			ctx,
-			opts.BucketName,
-			path.Join(metaPath, putMetadata.Path),
+			l.MetaBucketName(opts.BucketName),
+			l.MetaKey(putMetadata.Path),
 			bytes.NewReader(putMetadata.Content),
 			int64(len(putMetadata.Content)),
 			minio.PutObjectOptions{
@@ -194,17 +338,65 @@ DO UPDATE SET %s = $2;`
 	return &rpt, nil
 }
 
-func processorForName(name string) (meta.MetadataOperationProcessor, error) {
-	switch name {
-	case "thumbnail":
-		return &thumbnail.ThumbnailProcessor{
-			MaxSize: 300,
-		}, nil
-	case "color":
-		return &color.ColorAnalysisProcessor{}, nil
-	case "exif":
-		return &exif.ExifMetadataProcessor{}, nil
-	default:
-		return nil, fmt.Errorf("unknown processor: %s", name)
+func readBlob(
+	ctx context.Context,
+	minioClient *minio.Client,
+	bucketName string,
+	l *layout.Layout,
+	key string,
+) ([]byte, *minio.ObjectInfo, error) {
+	obj, err := minioClient.GetObject(
+		ctx,
+		bucketName,
+		l.DataKey(key),
+		minio.GetObjectOptions{},
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not get object %s: %s", key, err)
 	}
+	defer obj.Close()
+
+	objStat, err := obj.Stat()
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not stat object %s: %s", key, err)
+	}
+
+	bs, err := io.ReadAll(obj)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read object: %s", err)
+	}
+
+	return bs, &objStat, nil
+}
+
+// Backlog counts the blobs in a bucket which the processor would process on
+// the next run, including failed blobs due for a retry
+func Backlog(
+	ctx context.Context,
+	txn *sql.Tx,
+	bucketID int,
+	processor meta.MetadataOperationProcessor,
+) (int, error) {
+	var count int
+	err := txn.QueryRowContext(
+		ctx,
+		"select count(distinct id) from ("+needsMetadatasSQL+") as needs_metadatas",
+		processor.Name(),
+		pq.Array(processor.ContentTypes()),
+		processor.Version(),
+		bucketID,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("could not count backlog: %s", err)
+	}
+
+	return count, nil
+}
+
+func (o *Options) metricsBucket() string {
+	if o.MetricsBucket != "" {
+		return o.MetricsBucket
+	}
+
+	return o.BucketName
 }
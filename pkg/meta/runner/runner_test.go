@@ -2,15 +2,21 @@ package runner_test
 
 import (
 	"context"
-	"log"
+	"database/sql"
+	"fmt"
+	"log/slog"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/minio/minio-go/v7"
 
 	"github.com/charlieegan3/storage-console/pkg/database"
 	"github.com/charlieegan3/storage-console/pkg/importer"
+	"github.com/charlieegan3/storage-console/pkg/layout"
+	"github.com/charlieegan3/storage-console/pkg/meta"
 	"github.com/charlieegan3/storage-console/pkg/meta/runner"
+	"github.com/charlieegan3/storage-console/pkg/processors"
 	"github.com/charlieegan3/storage-console/pkg/test"
 )
 
@@ -44,6 +50,11 @@ func TestRun(t *testing.T) {
 		t.Fatalf("Could not init database: %s", err)
 	}
 
+	bucketIDs, err := database.EnsureBuckets(db, "storage_console", []string{"example"}, "example")
+	if err != nil {
+		t.Fatalf("Could not create buckets: %s", err)
+	}
+
 	// create the initial bucket state
 	err = minioClient.MakeBucket(ctx, "example", minio.MakeBucketOptions{})
 	if err != nil {
@@ -86,14 +97,14 @@ func TestRun(t *testing.T) {
 		}
 	}
 
-	logger := log.New(test.NewTLogWriter(t), "", 0)
+	logger := slog.New(slog.NewTextHandler(test.NewTLogWriter(t), nil))
 
 	// run the importer to set the initial state
 	importReport, err := importer.Run(ctx, db, minioClient, &importer.Options{
-		BucketName:  "example",
-		SchemaName:  "storage_console",
-		LoggerError: logger,
-		LoggerInfo:  logger,
+		BucketName: "example",
+		BucketID:   bucketIDs["example"],
+		SchemaName: "storage_console",
+		Logger:     logger,
 	})
 	if err != nil {
 		t.Fatalf("Could not run import: %s", err)
@@ -104,10 +115,10 @@ func TestRun(t *testing.T) {
 
 	rpt, err := runner.Run(ctx, db, minioClient, &runner.Options{
 		BucketName:        "example",
+		BucketID:          bucketIDs["example"],
 		SchemaName:        "storage_console",
 		EnabledProcessors: []string{"thumbnail", "exif", "color"},
-		LoggerError:       logger,
-		LoggerInfo:        logger,
+		Logger:            logger,
 	})
 	if err != nil {
 		t.Fatalf("Could not run runner: %s", err)
@@ -127,7 +138,7 @@ func TestRun(t *testing.T) {
 
 	paths := []string{
 		"meta/exif/adeea67e40e72105d42aa44edf6b155c.json",
-		"meta/thumbnail/adeea67e40e72105d42aa44edf6b155c.jpg",
+		"meta/thumbnail/300/adeea67e40e72105d42aa44edf6b155c.jpg",
 		"meta/color/adeea67e40e72105d42aa44edf6b155c.json",
 	}
 
@@ -148,7 +159,7 @@ func TestRun(t *testing.T) {
 		t.Fatalf("Could not start transaction: %s", err)
 	}
 
-	row := txn.QueryRow(`select count(*) from blob_metadata where thumbnail = 'success' group by thumbnail;`)
+	row := txn.QueryRow(`select count(*) from blob_metadata where processor = 'thumbnail' and result = 'success';`)
 
 	var count int64
 	err = row.Scan(&count)
@@ -160,3 +171,438 @@ func TestRun(t *testing.T) {
 		t.Fatalf("Expected count to be 3, got %d", count)
 	}
 }
+
+type brokenProcessor struct{}
+
+func (brokenProcessor) Name() string { return "broken" }
+
+func (brokenProcessor) Version() int { return 1 }
+
+func (brokenProcessor) ContentTypes() []string { return []string{"image/jpeg"} }
+
+func (brokenProcessor) Process(context.Context, *minio.ObjectInfo, []byte) ([]meta.PutMetadata, error) {
+	return nil, fmt.Errorf("corrupt image")
+}
+
+// importFixtures loads the fixtures into a new bucket and database, the
+// bucket's id is returned for the runner options
+func importFixtures(ctx context.Context, t *testing.T) (*sql.DB, *minio.Client, int, *slog.Logger) {
+	t.Helper()
+
+	minioClient, minioCleanup, err := test.InitMinio(ctx, t)
+	t.Cleanup(func() {
+		if minioCleanup == nil {
+			return
+		}
+		if err := minioCleanup(); err != nil {
+			t.Errorf("Could not cleanup minio: %s", err)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Could not init minio: %s", err)
+	}
+
+	db, postgresCleanup, err := test.InitPostgres(ctx, t)
+	t.Cleanup(func() {
+		if postgresCleanup == nil {
+			return
+		}
+		if err := postgresCleanup(); err != nil {
+			t.Errorf("Could not cleanup postgres: %s", err)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Could not init database: %s", err)
+	}
+
+	bucketIDs, err := database.EnsureBuckets(db, "storage_console", []string{"example"}, "example")
+	if err != nil {
+		t.Fatalf("Could not create buckets: %s", err)
+	}
+
+	err = minioClient.MakeBucket(ctx, "example", minio.MakeBucketOptions{})
+	if err != nil {
+		t.Fatalf("Could not create bucket: %s", err)
+	}
+
+	files, err := os.ReadDir("fixtures")
+	if err != nil {
+		t.Fatalf("Could not read fixtures: %s", err)
+	}
+
+	for _, f := range files {
+		_, err = minioClient.FPutObject(
+			ctx,
+			"example",
+			"data/"+f.Name(),
+			"fixtures/"+f.Name(),
+			minio.PutObjectOptions{ContentType: "image/jpeg"},
+		)
+		if err != nil {
+			t.Fatalf("Could not put object: %s", err)
+		}
+	}
+
+	logger := slog.New(slog.NewTextHandler(test.NewTLogWriter(t), nil))
+
+	_, err = importer.Run(ctx, db, minioClient, &importer.Options{
+		BucketName: "example",
+		BucketID:   bucketIDs["example"],
+		SchemaName: "storage_console",
+		Logger:     logger,
+	})
+	if err != nil {
+		t.Fatalf("Could not run import: %s", err)
+	}
+
+	return db, minioClient, bucketIDs["example"], logger
+}
+
+func TestRunRecordsErrors(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	db, minioClient, bucketID, logger := importFixtures(ctx, t)
+
+	registry, err := processors.NewBuiltinRegistry(processors.BuiltinOptions{})
+	if err != nil {
+		t.Fatalf("Could not build registry: %s", err)
+	}
+
+	err = registry.RegisterMeta(brokenProcessor{})
+	if err != nil {
+		t.Fatalf("Could not register processor: %s", err)
+	}
+
+	opts := &runner.Options{
+		BucketName:        "example",
+		BucketID:          bucketID,
+		SchemaName:        "storage_console",
+		Registry:          registry,
+		EnabledProcessors: []string{"broken", "exif"},
+		MaxAttempts:       2,
+		RetryBackoff:      time.Millisecond,
+		Logger:            logger,
+	}
+
+	rpt, err := runner.Run(ctx, db, minioClient, opts)
+	if err != nil {
+		t.Fatalf("Expected failures not to stop the run: %s", err)
+	}
+
+	if rpt.Errors["broken"] != 3 {
+		t.Fatalf("Expected 3 errors, got %d", rpt.Errors["broken"])
+	}
+
+	if rpt.Counts["exif"] != 3 {
+		t.Fatalf("Expected other processors to complete, got %d", rpt.Counts["exif"])
+	}
+
+	// once the backoff has passed, the blobs are tried again and then given up on
+	time.Sleep(10 * time.Millisecond)
+
+	rpt, err = runner.Run(ctx, db, minioClient, opts)
+	if err != nil {
+		t.Fatalf("Could not run runner: %s", err)
+	}
+
+	if rpt.Errors["broken"] != 3 {
+		t.Fatalf("Expected 3 retried errors, got %d", rpt.Errors["broken"])
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	rpt, err = runner.Run(ctx, db, minioClient, opts)
+	if err != nil {
+		t.Fatalf("Could not run runner: %s", err)
+	}
+
+	if rpt.Errors["broken"] != 0 {
+		t.Fatalf("Expected no more attempts, got %d", rpt.Errors["broken"])
+	}
+
+	txn, err := database.NewTxnWithSchema(db, "storage_console")
+	if err != nil {
+		t.Fatalf("Could not start transaction: %s", err)
+	}
+	defer txn.Rollback()
+
+	var count int64
+	err = txn.QueryRow(`
+select count(*) from processing_errors
+where processor = 'broken'
+and attempts = 2
+and next_retry_at is null
+and error = 'could not process blob: corrupt image';`).Scan(&count)
+	if err != nil {
+		t.Fatalf("Could not scan: %s", err)
+	}
+
+	if count != 3 {
+		t.Fatalf("Expected 3 given up errors, got %d", count)
+	}
+}
+
+func TestRunRecordsReadErrors(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	db, minioClient, bucketID, logger := importFixtures(ctx, t)
+
+	files, err := os.ReadDir("fixtures")
+	if err != nil {
+		t.Fatalf("Could not read fixtures: %s", err)
+	}
+
+	// the object is removed after it was imported, so it can't be read
+	err = minioClient.RemoveObject(ctx, "example", "data/"+files[0].Name(), minio.RemoveObjectOptions{})
+	if err != nil {
+		t.Fatalf("Could not remove object: %s", err)
+	}
+
+	rpt, err := runner.Run(ctx, db, minioClient, &runner.Options{
+		BucketName:        "example",
+		BucketID:          bucketID,
+		SchemaName:        "storage_console",
+		EnabledProcessors: []string{"exif", "color"},
+		Logger:            logger,
+	})
+	if err != nil {
+		t.Fatalf("Expected the missing object not to stop the run: %s", err)
+	}
+
+	for _, processorName := range []string{"exif", "color"} {
+		if rpt.Errors[processorName] != 1 {
+			t.Fatalf("Expected 1 %s error, got %d", processorName, rpt.Errors[processorName])
+		}
+	}
+
+	txn, err := database.NewTxnWithSchema(db, "storage_console")
+	if err != nil {
+		t.Fatalf("Could not start transaction: %s", err)
+	}
+	defer txn.Rollback()
+
+	var count int64
+	err = txn.QueryRow(`
+select count(*) from processing_errors
+where processor in ('exif', 'color')
+and error like 'could not stat object%';`).Scan(&count)
+	if err != nil {
+		t.Fatalf("Could not scan: %s", err)
+	}
+
+	if count != 2 {
+		t.Fatalf("Expected an error for each processor, got %d", count)
+	}
+}
+
+func TestRunKeepsErrorsWhenRunFails(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	db, minioClient, bucketID, logger := importFixtures(ctx, t)
+
+	registry, err := processors.NewBuiltinRegistry(processors.BuiltinOptions{})
+	if err != nil {
+		t.Fatalf("Could not build registry: %s", err)
+	}
+
+	err = registry.RegisterMeta(brokenProcessor{})
+	if err != nil {
+		t.Fatalf("Could not register processor: %s", err)
+	}
+
+	// exif output can't be written since the meta bucket doesn't exist
+	_, err = runner.Run(ctx, db, minioClient, &runner.Options{
+		BucketName:        "example",
+		BucketID:          bucketID,
+		SchemaName:        "storage_console",
+		Layout:            &layout.Layout{DataPrefix: "data/", MetaBucket: "missing", MetaPrefix: "meta/"},
+		Registry:          registry,
+		EnabledProcessors: []string{"broken", "exif"},
+		Logger:            logger,
+	})
+	if err == nil {
+		t.Fatalf("Expected the run to fail")
+	}
+
+	txn, err := database.NewTxnWithSchema(db, "storage_console")
+	if err != nil {
+		t.Fatalf("Could not start transaction: %s", err)
+	}
+	defer txn.Rollback()
+
+	var errors, metadata int64
+	err = txn.QueryRow(`
+select
+  (select count(*) from processing_errors where processor = 'broken' and next_retry_at > now()),
+  (select count(*) from blob_metadata where processor = 'exif');`).Scan(&errors, &metadata)
+	if err != nil {
+		t.Fatalf("Could not scan: %s", err)
+	}
+
+	if errors != 3 {
+		t.Fatalf("Expected the 3 errors to be kept with a backoff, got %d", errors)
+	}
+
+	if metadata != 0 {
+		t.Fatalf("Expected the failed run's metadata to be rolled back, got %d", metadata)
+	}
+}
+
+type versionedProcessor struct {
+	version int
+}
+
+func (v *versionedProcessor) Name() string { return "versioned" }
+
+func (v *versionedProcessor) Version() int { return v.version }
+
+func (v *versionedProcessor) ContentTypes() []string { return []string{"image/jpeg"} }
+
+func (v *versionedProcessor) Process(_ context.Context, objectInfo *minio.ObjectInfo, _ []byte) ([]meta.PutMetadata, error) {
+	return []meta.PutMetadata{
+		{
+			Path:        "versioned/" + objectInfo.ETag + ".json",
+			ContentType: meta.JSON,
+			Content:     []byte(fmt.Sprintf(`{"version": %d}`, v.version)),
+		},
+	}, nil
+}
+
+func TestRunReprocessesOtherVersions(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	db, minioClient, bucketID, logger := importFixtures(ctx, t)
+
+	processor := &versionedProcessor{version: 1}
+
+	registry := processors.NewRegistry()
+	err := registry.RegisterWithProperties(processor)
+	if err != nil {
+		t.Fatalf("Could not register processor: %s", err)
+	}
+
+	opts := &runner.Options{
+		BucketName:        "example",
+		BucketID:          bucketID,
+		SchemaName:        "storage_console",
+		Registry:          registry,
+		EnabledProcessors: []string{"versioned"},
+		Logger:            logger,
+	}
+
+	for i, expected := range []int{3, 0} {
+		rpt, err := runner.Run(ctx, db, minioClient, opts)
+		if err != nil {
+			t.Fatalf("Could not run runner: %s", err)
+		}
+
+		if rpt.Counts["versioned"] != expected {
+			t.Fatalf("run %d: expected %d results, got %d", i, expected, rpt.Counts["versioned"])
+		}
+	}
+
+	processor.version = 2
+
+	rpt, err := runner.Run(ctx, db, minioClient, opts)
+	if err != nil {
+		t.Fatalf("Could not run runner: %s", err)
+	}
+
+	if rpt.Counts["versioned"] != 3 {
+		t.Fatalf("Expected blobs to be processed again, got %d", rpt.Counts["versioned"])
+	}
+
+	txn, err := database.NewTxnWithSchema(db, "storage_console")
+	if err != nil {
+		t.Fatalf("Could not start transaction: %s", err)
+	}
+	defer txn.Rollback()
+
+	var count int64
+	err = txn.QueryRow(`
+select count(*) from blob_metadata
+where processor = 'versioned' and result = 'success' and version = 2;`).Scan(&count)
+	if err != nil {
+		t.Fatalf("Could not scan: %s", err)
+	}
+
+	if count != 3 {
+		t.Fatalf("Expected 3 results at version 2, got %d", count)
+	}
+
+	// versions can come from config, so any change is processed again
+	processor.version = 1
+
+	rpt, err = runner.Run(ctx, db, minioClient, opts)
+	if err != nil {
+		t.Fatalf("Could not run runner: %s", err)
+	}
+
+	if rpt.Counts["versioned"] != 3 {
+		t.Fatalf("Expected blobs to be processed again after a rollback, got %d", rpt.Counts["versioned"])
+	}
+}
+
+func TestBacklog(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	db, minioClient, bucketID, logger := importFixtures(ctx, t)
+
+	processor := &versionedProcessor{version: 1}
+
+	registry := processors.NewRegistry()
+	err := registry.RegisterWithProperties(processor)
+	if err != nil {
+		t.Fatalf("Could not register processor: %s", err)
+	}
+
+	opts := &runner.Options{
+		BucketName:        "example",
+		BucketID:          bucketID,
+		SchemaName:        "storage_console",
+		Registry:          registry,
+		EnabledProcessors: []string{"versioned"},
+		Logger:            logger,
+	}
+
+	backlog := func() int {
+		t.Helper()
+
+		txn, err := database.NewTxnWithSchema(db, "storage_console")
+		if err != nil {
+			t.Fatalf("Could not start transaction: %s", err)
+		}
+		defer txn.Rollback()
+
+		count, err := runner.Backlog(ctx, txn, bucketID, processor)
+		if err != nil {
+			t.Fatalf("Could not count backlog: %s", err)
+		}
+
+		return count
+	}
+
+	if count := backlog(); count != 3 {
+		t.Fatalf("Expected 3 blobs before the first run, got %d", count)
+	}
+
+	_, err = runner.Run(ctx, db, minioClient, opts)
+	if err != nil {
+		t.Fatalf("Could not run runner: %s", err)
+	}
+
+	if count := backlog(); count != 0 {
+		t.Fatalf("Expected no blobs after a run, got %d", count)
+	}
+
+	processor.version = 2
+
+	if count := backlog(); count != 3 {
+		t.Fatalf("Expected 3 blobs after a version change, got %d", count)
+	}
+}
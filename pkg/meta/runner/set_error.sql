@@ -0,0 +1,24 @@
+INSERT INTO processing_errors (blob_id, processor, error, attempts, last_attempt_at, next_retry_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    1,
+    now(),
+    CASE
+        WHEN 1 >= $5::integer THEN NULL
+        ELSE now() + make_interval(secs => $4::float8)
+    END
+)
+ON CONFLICT (blob_id, processor)
+DO UPDATE SET
+    error = $3,
+    attempts = processing_errors.attempts + 1,
+    last_attempt_at = now(),
+    next_retry_at = CASE
+        WHEN processing_errors.attempts + 1 >= $5::integer THEN NULL
+        ELSE now() + make_interval(
+            secs => least($4::float8 * power(2, processing_errors.attempts), $6::float8)
+        )
+    END
+RETURNING attempts;
@@ -1,4 +0,0 @@
-INSERT INTO blob_metadata (blob_id, thumbnail)
-VALUES ($1, true)
-ON CONFLICT (blob_id) 
-DO UPDATE SET thumbnail = true;
\ No newline at end of file
@@ -0,0 +1,81 @@
+package tags
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/charlieegan3/storage-console/pkg/meta"
+	"github.com/charlieegan3/storage-console/pkg/meta/exif"
+	"github.com/charlieegan3/storage-console/pkg/meta/iptc"
+	"github.com/charlieegan3/storage-console/pkg/meta/xmp"
+)
+
+// Tags is every EXIF, IPTC and XMP tag found in a blob
+type Tags struct {
+	EXIF map[string]any      `json:"exif,omitempty"`
+	IPTC map[string][]string `json:"iptc,omitempty"`
+	XMP  xmp.Properties      `json:"xmp,omitempty"`
+}
+
+// TagsProcessor stores all the embedded metadata in an image rather than
+// the selection used by the exif processor
+type TagsProcessor struct{}
+
+func (t *TagsProcessor) Name() string {
+	return "tags"
+}
+
+func (t *TagsProcessor) Version() int {
+	return 1
+}
+
+func (t *TagsProcessor) ContentTypes() []string {
+	return meta.PhotoContentTypes
+}
+
+func (t *TagsProcessor) Process(
+	ctx context.Context,
+	objectInfo *minio.ObjectInfo,
+	content []byte,
+) ([]meta.PutMetadata, error) {
+	var tags Tags
+	var err error
+
+	tags.EXIF, err = exif.Collect(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect exif tags: %w", err)
+	}
+
+	tags.IPTC, err = iptc.Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect iptc tags: %w", err)
+	}
+
+	if packet := xmp.Extract(content); packet != nil {
+		tags.XMP, err = xmp.Parse(packet)
+		if err != nil {
+			return nil, fmt.Errorf("failed to collect xmp tags: %w", err)
+		}
+	}
+
+	if len(tags.EXIF) == 0 && len(tags.IPTC) == 0 && len(tags.XMP) == 0 {
+		return []meta.PutMetadata{}, nil
+	}
+
+	bs, err := json.Marshal(tags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tags: %w", err)
+	}
+
+	return []meta.PutMetadata{
+		{
+			Path:        path.Join(t.Name(), objectInfo.ETag+".json"),
+			ContentType: meta.JSON,
+			Content:     bs,
+		},
+	}, nil
+}
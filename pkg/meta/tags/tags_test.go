@@ -0,0 +1,77 @@
+package tags_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/charlieegan3/storage-console/pkg/meta/tags"
+)
+
+func TestTagsProcessor(t *testing.T) {
+	t.Parallel()
+
+	content, err := os.ReadFile("../fixtures/rx100-landscape.jpg")
+	if err != nil {
+		t.Fatalf("failed to read image file: %v", err)
+	}
+
+	processor := tags.TagsProcessor{}
+
+	metadata, err := processor.Process(context.Background(), &minio.ObjectInfo{
+		ETag: "foobar",
+	}, content)
+	if err != nil {
+		t.Fatalf("failed to process image: %v", err)
+	}
+
+	if len(metadata) != 1 {
+		t.Fatalf("expected 1 metadata entry, got %d", len(metadata))
+	}
+
+	if metadata[0].Path != "tags/foobar.json" {
+		t.Fatalf("unexpected path: %s", metadata[0].Path)
+	}
+
+	var result tags.Tags
+	err = json.Unmarshal(metadata[0].Content, &result)
+	if err != nil {
+		t.Fatalf("failed to unmarshal JSON content: %v", err)
+	}
+
+	if result.EXIF["Make"] != "SONY" {
+		t.Errorf("expected exif Make to be SONY, got %v", result.EXIF["Make"])
+	}
+
+	if _, ok := result.EXIF["WhiteBalance"]; !ok {
+		t.Errorf("expected exif WhiteBalance to be set")
+	}
+
+	if v := result.IPTC["DateCreated"]; len(v) != 1 || v[0] != "20241222" {
+		t.Errorf("unexpected iptc DateCreated: %v", v)
+	}
+
+	if v := result.XMP["crs:WhiteBalance"]; len(v) != 1 || v[0] != "As Shot" {
+		t.Errorf("unexpected xmp crs:WhiteBalance: %v", v)
+	}
+}
+
+func TestTagsProcessorNoTags(t *testing.T) {
+	t.Parallel()
+
+	processor := tags.TagsProcessor{}
+
+	metadata, err := processor.Process(context.Background(), &minio.ObjectInfo{
+		ETag: "foobar",
+	}, []byte("not an image"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(metadata) != 0 {
+		t.Fatalf("expected no metadata, got %d", len(metadata))
+	}
+}
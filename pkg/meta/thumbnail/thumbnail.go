@@ -4,20 +4,43 @@ import (
 	"context"
 	"fmt"
 	"path"
+	"strconv"
 
-	"github.com/charlieegan3/storage-console/pkg/meta"
 	"github.com/davidbyttow/govips/v2/vips"
 	"github.com/minio/minio-go/v7"
+
+	"github.com/charlieegan3/storage-console/pkg/config"
+	"github.com/charlieegan3/storage-console/pkg/meta"
 )
 
 type ThumbnailProcessor struct {
-	MaxSize int
+	// Sizes are the lengths of the longest side of each thumbnail, one
+	// rendition is generated per size at thumbnail/<size>/<etag>.<ext>
+	Sizes []int
+	// Format is one of meta.JPG, meta.WEBP or meta.AVIF
+	Format meta.ContentType
+	// Quality overrides the default quality for the format when set
+	Quality int
 }
 
 func (t *ThumbnailProcessor) Name() string {
 	return "thumbnail"
 }
 
+// version 2 stores a rendition per configured size, the renditions are
+// generated again when the sizes, format or quality change
+func (t *ThumbnailProcessor) Version() int {
+	return meta.ConfigVersion(2, t.sizes(), t.Format, t.Quality)
+}
+
+func (t *ThumbnailProcessor) sizes() []int {
+	if len(t.Sizes) == 0 {
+		return config.DefaultThumbnailSizes
+	}
+
+	return t.Sizes
+}
+
 func (t *ThumbnailProcessor) ContentTypes() []string {
 	return []string{
 		"image/jpeg", "image/jpg", "image/jp2",
@@ -35,6 +58,17 @@ func init() {
 	vips.Startup(nil)
 }
 
+// Ready checks that libvips started and can create images
+func Ready() error {
+	image, err := vips.Black(1, 1)
+	if err != nil {
+		return fmt.Errorf("vips is not ready: %w", err)
+	}
+	image.Close()
+
+	return nil
+}
+
 func (t *ThumbnailProcessor) Process(
 	ctx context.Context,
 	objectInfo *minio.ObjectInfo,
@@ -50,6 +84,35 @@ func (t *ThumbnailProcessor) Process(
 		return nil, fmt.Errorf("could not auto-rotate image: %w", err)
 	}
 
+	var putMetadatas []meta.PutMetadata
+	for _, size := range t.sizes() {
+		thumbnailBytes, err := t.render(image, size)
+		if err != nil {
+			return nil, fmt.Errorf("could not render %dpx thumbnail: %w", size, err)
+		}
+
+		putMetadatas = append(putMetadatas, meta.PutMetadata{
+			Path:        Path(size, objectInfo.ETag, t.Format),
+			ContentType: t.Format,
+			Content:     thumbnailBytes,
+		})
+	}
+
+	return putMetadatas, nil
+}
+
+// Path returns the path of a thumbnail relative to the meta path
+func Path(size int, etag string, format meta.ContentType) string {
+	return path.Join("thumbnail", strconv.Itoa(size), etag+"."+meta.ContentTypeToFileExt(format))
+}
+
+func (t *ThumbnailProcessor) render(original *vips.ImageRef, size int) ([]byte, error) {
+	image, err := original.Copy()
+	if err != nil {
+		return nil, fmt.Errorf("could not copy image: %w", err)
+	}
+	defer image.Close()
+
 	width := image.Width()
 	height := image.Height()
 	longestSide := width
@@ -57,24 +120,39 @@ func (t *ThumbnailProcessor) Process(
 		longestSide = height
 	}
 
-	if longestSide > t.MaxSize {
-		scale := float64(t.MaxSize) / float64(longestSide)
+	if longestSide > size {
+		scale := float64(size) / float64(longestSide)
 		if err := image.Resize(scale, vips.KernelLanczos3); err != nil {
 			return nil, fmt.Errorf("could not resize image: %w", err)
 		}
 	}
 
-	exportParams := vips.NewDefaultJPEGExportParams()
-	thumbnailBytes, _, err := image.Export(exportParams)
+	var thumbnailBytes []byte
+	switch t.Format {
+	case meta.JPG:
+		exportParams := vips.NewDefaultJPEGExportParams()
+		if t.Quality > 0 {
+			exportParams.Quality = t.Quality
+		}
+		thumbnailBytes, _, err = image.Export(exportParams)
+	case meta.WEBP:
+		exportParams := vips.NewWebpExportParams()
+		if t.Quality > 0 {
+			exportParams.Quality = t.Quality
+		}
+		thumbnailBytes, _, err = image.ExportWebp(exportParams)
+	case meta.AVIF:
+		exportParams := vips.NewAvifExportParams()
+		if t.Quality > 0 {
+			exportParams.Quality = t.Quality
+		}
+		thumbnailBytes, _, err = image.ExportAvif(exportParams)
+	default:
+		return nil, fmt.Errorf("unsupported thumbnail format: %q", meta.ContentTypeToString(t.Format))
+	}
 	if err != nil {
 		return nil, fmt.Errorf("could not export thumbnail: %w", err)
 	}
 
-	putMetadata := meta.PutMetadata{
-		Path:        path.Join(t.Name(), objectInfo.ETag+".jpg"),
-		ContentType: meta.JPG,
-		Content:     thumbnailBytes,
-	}
-
-	return []meta.PutMetadata{putMetadata}, nil
+	return thumbnailBytes, nil
 }
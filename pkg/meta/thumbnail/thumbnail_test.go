@@ -8,6 +8,7 @@ import (
 
 	"github.com/minio/minio-go/v7"
 
+	"github.com/charlieegan3/storage-console/pkg/config"
 	"github.com/charlieegan3/storage-console/pkg/meta"
 	"github.com/charlieegan3/storage-console/pkg/meta/thumbnail"
 )
@@ -26,7 +27,7 @@ func TestThumbnailProcessor(t *testing.T) {
 		t.Fatalf("failed to read expected thumbnail file: %v", err)
 	}
 
-	processor := thumbnail.ThumbnailProcessor{MaxSize: 100}
+	processor := thumbnail.ThumbnailProcessor{Sizes: []int{100}}
 
 	metadata, err := processor.Process(context.Background(), &minio.ObjectInfo{
 		ETag: "foobar",
@@ -39,8 +40,8 @@ func TestThumbnailProcessor(t *testing.T) {
 		t.Fatalf("expected 1 metadata entry, got %d", len(metadata))
 	}
 
-	if metadata[0].Path != "thumbnail/foobar.jpg" {
-		t.Fatalf("expected path 'thumbnail/foobar.jpg', got '%v'", metadata[0].Path)
+	if metadata[0].Path != "thumbnail/100/foobar.jpg" {
+		t.Fatalf("expected path 'thumbnail/100/foobar.jpg', got '%v'", metadata[0].Path)
 	}
 
 	if metadata[0].ContentType != meta.JPG {
@@ -51,3 +52,57 @@ func TestThumbnailProcessor(t *testing.T) {
 		t.Errorf("thumbnail content does not match expected output")
 	}
 }
+
+func TestThumbnailProcessorSizes(t *testing.T) {
+	content, err := os.ReadFile("../fixtures/rx100-landscape.jpg")
+	if err != nil {
+		t.Fatalf("failed to read image file: %v", err)
+	}
+
+	processor := thumbnail.ThumbnailProcessor{
+		Sizes:   []int{50, 100},
+		Format:  meta.WEBP,
+		Quality: 60,
+	}
+
+	metadata, err := processor.Process(context.Background(), &minio.ObjectInfo{
+		ETag: "foobar",
+	}, content)
+	if err != nil {
+		t.Fatalf("failed to process image: %v", err)
+	}
+
+	expectedPaths := []string{"thumbnail/50/foobar.webp", "thumbnail/100/foobar.webp"}
+	if len(metadata) != len(expectedPaths) {
+		t.Fatalf("expected %d metadata entries, got %d", len(expectedPaths), len(metadata))
+	}
+
+	for i, m := range metadata {
+		if m.Path != expectedPaths[i] {
+			t.Fatalf("expected path %q, got %q", expectedPaths[i], m.Path)
+		}
+
+		if m.ContentType != meta.WEBP {
+			t.Fatalf("expected content type 'webp', got '%v'", m.ContentType)
+		}
+
+		if !bytes.HasPrefix(m.Content, []byte("RIFF")) {
+			t.Fatalf("expected webp content for %s", m.Path)
+		}
+	}
+}
+
+func TestThumbnailProcessorVersion(t *testing.T) {
+	processor := thumbnail.ThumbnailProcessor{}
+	defaults := processor.Version()
+
+	processor.Sizes = config.DefaultThumbnailSizes
+	if processor.Version() != defaults {
+		t.Fatalf("expected the default sizes to give the same version")
+	}
+
+	processor.Format = meta.WEBP
+	if processor.Version() == defaults {
+		t.Fatalf("expected a new format to change the version")
+	}
+}
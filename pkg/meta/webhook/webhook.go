@@ -0,0 +1,250 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/charlieegan3/storage-console/pkg/meta"
+)
+
+const (
+	// SendBody posts the blob content to the endpoint
+	SendBody = "body"
+	// SendURL posts a JSON document with a presigned URL to the blob
+	SendURL = "url"
+
+	DefaultTimeout   = 30 * time.Second
+	DefaultRetryWait = time.Second
+)
+
+// Presigner returns a URL which the endpoint can use to download an object
+type Presigner func(ctx context.Context, key string) (string, error)
+
+// Request is the body posted to the endpoint in SendURL mode
+type Request struct {
+	URL         string `json:"url"`
+	Key         string `json:"key"`
+	ETag        string `json:"etag"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
+// WebhookProcessor posts each blob, or a link to it, to a remote service.
+// The service must respond with a JSON object which is stored as metadata,
+// and the keys of the object are loaded as properties.
+type WebhookProcessor struct {
+	ProcessorName string
+	URL           string
+	// Send is either SendBody or SendURL
+	Send    string
+	Headers map[string]string
+	Timeout time.Duration
+	// Retries is the number of times to retry failed requests. Requests are
+	// only retried for connection errors, 429s and 5xx responses.
+	Retries int
+	// RetryWait is the time to wait before the first retry, it doubles for
+	// each subsequent retry
+	RetryWait time.Duration
+	// Concurrency limits the number of requests in flight at once, 0 is
+	// unlimited
+	Concurrency int
+	// ProcessorVersion should be increased when the service changes to
+	// process existing blobs again, defaults to 1
+	ProcessorVersion int
+
+	ProcessorContentTypes []string
+
+	Presign Presigner
+	Client  *http.Client
+
+	semOnce sync.Once
+	sem     chan struct{}
+}
+
+func (w *WebhookProcessor) Name() string {
+	return w.ProcessorName
+}
+
+func (w *WebhookProcessor) Version() int {
+	return max(w.ProcessorVersion, 1)
+}
+
+func (w *WebhookProcessor) ContentTypes() []string {
+	return w.ProcessorContentTypes
+}
+
+func (w *WebhookProcessor) Process(
+	ctx context.Context,
+	objectInfo *minio.ObjectInfo,
+	content []byte,
+) ([]meta.PutMetadata, error) {
+	body, contentType, err := w.requestBody(ctx, objectInfo, content)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := w.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	retryWait := w.RetryWait
+	if retryWait == 0 {
+		retryWait = DefaultRetryWait
+	}
+
+	var respBody []byte
+	for attempt := 0; ; attempt++ {
+		var retryable bool
+		respBody, retryable, err = w.post(ctx, body, contentType)
+		if err == nil {
+			break
+		}
+
+		if !retryable || attempt >= w.Retries {
+			return nil, fmt.Errorf("request failed after %d attempts: %w", attempt+1, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(retryWait):
+		}
+
+		retryWait *= 2
+	}
+
+	var output map[string]any
+	err = json.Unmarshal(respBody, &output)
+	if err != nil {
+		return nil, fmt.Errorf("response must be a JSON object: %w", err)
+	}
+
+	jsonData, err := json.Marshal(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal response: %w", err)
+	}
+
+	return []meta.PutMetadata{
+		{
+			Path:        path.Join(w.Name(), objectInfo.ETag+".json"),
+			ContentType: meta.JSON,
+			Content:     jsonData,
+		},
+	}, nil
+}
+
+func (w *WebhookProcessor) requestBody(
+	ctx context.Context,
+	objectInfo *minio.ObjectInfo,
+	content []byte,
+) ([]byte, string, error) {
+	switch w.Send {
+	case "", SendBody:
+		contentType := objectInfo.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		return content, contentType, nil
+	case SendURL:
+		if w.Presign == nil {
+			return nil, "", fmt.Errorf("processor %s has no presigner", w.ProcessorName)
+		}
+
+		u, err := w.Presign(ctx, objectInfo.Key)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to presign object: %w", err)
+		}
+
+		bs, err := json.Marshal(Request{
+			URL:         u,
+			Key:         objectInfo.Key,
+			ETag:        objectInfo.ETag,
+			ContentType: objectInfo.ContentType,
+			Size:        objectInfo.Size,
+		})
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to marshal request: %w", err)
+		}
+
+		return bs, "application/json", nil
+	}
+
+	return nil, "", fmt.Errorf("unknown send mode: %q", w.Send)
+}
+
+// acquire waits for a concurrency slot and returns a func to release it
+func (w *WebhookProcessor) acquire(ctx context.Context) (func(), error) {
+	if w.Concurrency <= 0 {
+		return func() {}, nil
+	}
+
+	w.semOnce.Do(func() {
+		w.sem = make(chan struct{}, w.Concurrency)
+	})
+
+	select {
+	case w.sem <- struct{}{}:
+		return func() { <-w.sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// post makes a single request and reports whether a failure is worth
+// retrying
+func (w *WebhookProcessor) post(ctx context.Context, body []byte, contentType string) ([]byte, bool, error) {
+	timeout := w.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", "application/json")
+	for k, v := range w.Headers {
+		req.Header.Set(k, v)
+	}
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+
+		return nil, retryable, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(respBody))
+	}
+
+	return respBody, false, nil
+}
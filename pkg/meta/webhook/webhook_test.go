@@ -0,0 +1,218 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/charlieegan3/storage-console/pkg/meta"
+	"github.com/charlieegan3/storage-console/pkg/meta/webhook"
+)
+
+var objectInfo = &minio.ObjectInfo{
+	Key:         "data/photo.jpg",
+	ETag:        "foobar",
+	ContentType: "image/jpeg",
+	Size:        11,
+}
+
+func TestWebhookProcessorBody(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != "image/jpeg" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		bs, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]any{"tag": "cat", "bytes": len(bs)})
+	}))
+	defer srv.Close()
+
+	processor := webhook.WebhookProcessor{
+		ProcessorName: "tagger",
+		URL:           srv.URL,
+		Headers:       map[string]string{"Authorization": "Bearer secret"},
+	}
+
+	metadata, err := processor.Process(context.Background(), objectInfo, []byte("hello world"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(metadata) != 1 {
+		t.Fatalf("expected 1 metadata entry, got %d", len(metadata))
+	}
+
+	if exp, got := "tagger/foobar.json", metadata[0].Path; exp != got {
+		t.Fatalf("expected path %q, got %q", exp, got)
+	}
+
+	if metadata[0].ContentType != meta.JSON {
+		t.Fatalf("expected content type 'json', got '%v'", metadata[0].ContentType)
+	}
+
+	if exp, got := `{"bytes":11,"tag":"cat"}`, string(metadata[0].Content); exp != got {
+		t.Fatalf("expected %s, got %s", exp, got)
+	}
+}
+
+func TestWebhookProcessorURL(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req webhook.Request
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]any{"url": req.URL, "etag": req.ETag})
+	}))
+	defer srv.Close()
+
+	processor := webhook.WebhookProcessor{
+		ProcessorName: "tagger",
+		URL:           srv.URL,
+		Send:          webhook.SendURL,
+		Presign: func(ctx context.Context, key string) (string, error) {
+			return "https://example.com/" + key + "?signature=abc", nil
+		},
+	}
+
+	metadata, err := processor.Process(context.Background(), objectInfo, []byte("hello world"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if exp, got := `{"etag":"foobar","url":"https://example.com/data/photo.jpg?signature=abc"}`, string(metadata[0].Content); exp != got {
+		t.Fatalf("expected %s, got %s", exp, got)
+	}
+}
+
+func TestWebhookProcessorRetries(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		statuses         []int
+		retries          int
+		expectedAttempts int32
+		expectedError    bool
+	}{
+		"succeeds after server errors": {
+			statuses:         []int{http.StatusBadGateway, http.StatusTooManyRequests, http.StatusOK},
+			retries:          2,
+			expectedAttempts: 3,
+		},
+		"gives up after retries": {
+			statuses:         []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK},
+			retries:          1,
+			expectedAttempts: 2,
+			expectedError:    true,
+		},
+		"client errors are not retried": {
+			statuses:         []int{http.StatusBadRequest, http.StatusOK},
+			retries:          3,
+			expectedAttempts: 1,
+			expectedError:    true,
+		},
+	}
+
+	for testCase, testData := range tests {
+		t.Run(testCase, func(t *testing.T) {
+			t.Parallel()
+
+			var attempts atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := testData.statuses[attempts.Add(1)-1]
+				w.WriteHeader(status)
+				_, _ = w.Write([]byte(`{"ok": true}`))
+			}))
+			defer srv.Close()
+
+			processor := webhook.WebhookProcessor{
+				ProcessorName: "tagger",
+				URL:           srv.URL,
+				Retries:       testData.retries,
+				RetryWait:     time.Millisecond,
+			}
+
+			_, err := processor.Process(context.Background(), objectInfo, []byte("hello world"))
+			if testData.expectedError && err == nil {
+				t.Fatalf("expected error, got nil")
+			}
+			if !testData.expectedError && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if exp, got := testData.expectedAttempts, attempts.Load(); exp != got {
+				t.Fatalf("expected %d attempts, got %d", exp, got)
+			}
+		})
+	}
+}
+
+func TestWebhookProcessorConcurrency(t *testing.T) {
+	t.Parallel()
+
+	var inFlight, maxInFlight atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	processor := webhook.WebhookProcessor{
+		ProcessorName: "tagger",
+		URL:           srv.URL,
+		Concurrency:   2,
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := processor.Process(context.Background(), objectInfo, []byte("hello world"))
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := maxInFlight.Load(); got > 2 {
+		t.Fatalf("expected at most 2 requests in flight, got %d", got)
+	}
+}
@@ -0,0 +1,121 @@
+package xmp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/charlieegan3/storage-console/pkg/meta"
+)
+
+// SidecarLoader returns the content of the object with the given key, or
+// nil if it doesn't exist
+type SidecarLoader func(ctx context.Context, key string) ([]byte, error)
+
+// XMPProcessor reads the XMP embedded in a photo along with the .xmp
+// sidecar file next to it, where Lightroom and other editors store ratings,
+// labels, keywords and captions for RAW files. Values in the sidecar take
+// precedence over the embedded ones.
+type XMPProcessor struct {
+	// Sidecar loads sidecar files, they are ignored when this is nil
+	Sidecar SidecarLoader
+}
+
+func (x *XMPProcessor) Name() string {
+	return "xmp"
+}
+
+func (x *XMPProcessor) Version() int {
+	return 1
+}
+
+func (x *XMPProcessor) ContentTypes() []string {
+	return meta.PhotoContentTypes
+}
+
+func (x *XMPProcessor) Process(
+	ctx context.Context,
+	objectInfo *minio.ObjectInfo,
+	content []byte,
+) ([]meta.PutMetadata, error) {
+	props := make(Properties)
+
+	if packet := Extract(content); packet != nil {
+		embedded, err := Parse(packet)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse embedded xmp: %w", err)
+		}
+
+		for name, values := range embedded {
+			props[name] = values
+		}
+	}
+
+	if x.Sidecar != nil {
+		for _, key := range SidecarKeys(objectInfo.Key) {
+			sidecar, err := x.Sidecar(ctx, key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load sidecar %s: %w", key, err)
+			}
+
+			if sidecar == nil {
+				continue
+			}
+
+			fromSidecar, err := Parse(sidecar)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse sidecar %s: %w", key, err)
+			}
+
+			for name, values := range fromSidecar {
+				props[name] = values
+			}
+
+			break
+		}
+	}
+
+	if len(props) == 0 {
+		return []meta.PutMetadata{}, nil
+	}
+
+	bs, err := json.Marshal(props)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal xmp: %w", err)
+	}
+
+	return []meta.PutMetadata{
+		{
+			Path:        path.Join(x.Name(), objectInfo.ETag+".json"),
+			ContentType: meta.JSON,
+			Content:     bs,
+		},
+	}, nil
+}
+
+// SidecarKeys returns the keys a sidecar for the object could have, in the
+// order they are checked. Lightroom replaces the extension while darktable
+// and others append to it.
+func SidecarKeys(key string) []string {
+	stem := strings.TrimSuffix(key, path.Ext(key))
+
+	return []string{
+		stem + ".xmp",
+		stem + ".XMP",
+		key + ".xmp",
+	}
+}
+
+// SidecarStem returns the key, without an extension, of the photos that a
+// sidecar belongs to and whether the key is a sidecar at all
+func SidecarStem(key string) (string, bool) {
+	if !strings.EqualFold(path.Ext(key), ".xmp") {
+		return "", false
+	}
+
+	return strings.TrimSuffix(key, path.Ext(key)), true
+}
@@ -0,0 +1,91 @@
+package xmp_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/charlieegan3/storage-console/pkg/meta/xmp"
+)
+
+func TestXMPProcessor(t *testing.T) {
+	content, err := os.ReadFile("../fixtures/rx100-landscape.jpg")
+	if err != nil {
+		t.Fatalf("failed to read image file: %v", err)
+	}
+
+	var loaded []string
+	processor := xmp.XMPProcessor{
+		Sidecar: func(ctx context.Context, key string) ([]byte, error) {
+			loaded = append(loaded, key)
+
+			if key != "data/2024/DSC0001.XMP" {
+				return nil, nil
+			}
+
+			return []byte(`<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about="" xmlns:xmp="http://ns.adobe.com/xap/1.0/"
+   xmp:Rating="5" xmp:CreatorTool="darktable"/>
+ </rdf:RDF>
+</x:xmpmeta>`), nil
+		},
+	}
+
+	results, err := processor.Process(
+		context.Background(),
+		&minio.ObjectInfo{Key: "data/2024/DSC0001.jpg", ETag: "abc"},
+		content,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if exp, got := []string{"data/2024/DSC0001.xmp", "data/2024/DSC0001.XMP"}, loaded; !slices.Equal(exp, got) {
+		t.Fatalf("expected sidecars %v to be loaded, got %v", exp, got)
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+
+	if exp, got := "xmp/abc.json", results[0].Path; exp != got {
+		t.Fatalf("expected path %s, got %s", exp, got)
+	}
+
+	var props xmp.Properties
+	err = json.Unmarshal(results[0].Content, &props)
+	if err != nil {
+		t.Fatalf("failed to unmarshal result: %s", err)
+	}
+
+	expected := xmp.Properties{
+		"xmp:Rating":      {"5"},
+		"xmp:CreatorTool": {"darktable"},
+		"aux:Lens":        {"24-200mm F2.8-4.5"},
+	}
+
+	for name, values := range expected {
+		if got := props[name]; !slices.Equal(got, values) {
+			t.Errorf("expected %s to be %v, got %v", name, values, got)
+		}
+	}
+}
+
+func TestSidecarStem(t *testing.T) {
+	if stem, ok := xmp.SidecarStem("data/2024/DSC0001.XMP"); !ok || stem != "data/2024/DSC0001" {
+		t.Fatalf("unexpected stem %q, %v", stem, ok)
+	}
+
+	if stem, ok := xmp.SidecarStem("data/2024/DSC0001.ARW.xmp"); !ok || stem != "data/2024/DSC0001.ARW" {
+		t.Fatalf("unexpected stem %q, %v", stem, ok)
+	}
+
+	if _, ok := xmp.SidecarStem("data/2024/DSC0001.ARW"); ok {
+		t.Fatalf("expected a photo not to be a sidecar")
+	}
+}
@@ -0,0 +1,180 @@
+package xmp
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+const rdfNS = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+
+// prefixes used when a packet doesn't declare a namespace on the element
+// where it's used
+var knownPrefixes = map[string]string{
+	"http://purl.org/dc/elements/1.1/":               "dc",
+	"http://ns.adobe.com/xap/1.0/":                   "xmp",
+	"http://ns.adobe.com/xap/1.0/mm/":                "xmpMM",
+	"http://ns.adobe.com/xap/1.0/rights/":            "xmpRights",
+	"http://ns.adobe.com/photoshop/1.0/":             "photoshop",
+	"http://ns.adobe.com/lightroom/1.0/":             "lr",
+	"http://ns.adobe.com/exif/1.0/":                  "exif",
+	"http://ns.adobe.com/exif/1.0/aux/":              "aux",
+	"http://ns.adobe.com/tiff/1.0/":                  "tiff",
+	"http://iptc.org/std/Iptc4xmpCore/1.0/xmlns/":    "Iptc4xmpCore",
+	"http://iptc.org/std/Iptc4xmpExt/2008-02-29/":    "Iptc4xmpExt",
+	"http://ns.adobe.com/camera-raw-settings/1.0/":   "crs",
+	"http://cipa.jp/exif/1.0/":                       "exifEX",
+	"http://ns.adobe.com/xap/1.0/sType/ResourceRef#": "stRef",
+}
+
+// Properties maps qualified property names, such as dc:subject, to their
+// values. Lists have a value per item and fields of structures are named
+// parent/field.
+type Properties map[string][]string
+
+// Extract returns the first XMP packet embedded in content, or nil if there
+// isn't one. Packets are stored as plain text in JPEG, TIFF, PNG and most
+// RAW formats so they can be found without parsing the container.
+func Extract(content []byte) []byte {
+	start := bytes.Index(content, []byte("<x:xmpmeta"))
+	if start == -1 {
+		return nil
+	}
+
+	endTag := []byte("</x:xmpmeta>")
+	end := bytes.Index(content[start:], endTag)
+	if end == -1 {
+		return nil
+	}
+
+	return content[start : start+end+len(endTag)]
+}
+
+type node struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Nodes   []node     `xml:",any"`
+	Text    string     `xml:",chardata"`
+}
+
+// Parse reads the properties from an XMP packet, or a sidecar file
+func Parse(packet []byte) (Properties, error) {
+	var root node
+	err := xml.Unmarshal(packet, &root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse xmp: %w", err)
+	}
+
+	prefixes := make(map[string]string)
+	for uri, prefix := range knownPrefixes {
+		prefixes[uri] = prefix
+	}
+	collectPrefixes(&root, prefixes)
+
+	p := &parser{prefixes: prefixes, props: make(Properties)}
+	p.walk(&root)
+
+	return p.props, nil
+}
+
+func collectPrefixes(n *node, prefixes map[string]string) {
+	for _, a := range n.Attrs {
+		if a.Name.Space == "xmlns" {
+			prefixes[a.Value] = a.Name.Local
+		}
+	}
+
+	for i := range n.Nodes {
+		collectPrefixes(&n.Nodes[i], prefixes)
+	}
+}
+
+type parser struct {
+	prefixes map[string]string
+	props    Properties
+}
+
+func (p *parser) name(n xml.Name) string {
+	if prefix, ok := p.prefixes[n.Space]; ok {
+		return prefix + ":" + n.Local
+	}
+
+	return n.Local
+}
+
+// walk finds the rdf:Description elements which hold the properties
+func (p *parser) walk(n *node) {
+	if n.XMLName.Space == rdfNS && n.XMLName.Local == "Description" {
+		p.description(n, "")
+		return
+	}
+
+	for i := range n.Nodes {
+		p.walk(&n.Nodes[i])
+	}
+}
+
+func (p *parser) description(n *node, parent string) {
+	for _, a := range n.Attrs {
+		if a.Name.Space == "xmlns" || a.Name.Space == rdfNS || a.Name.Space == "xml" || a.Name.Space == "" {
+			continue
+		}
+
+		p.add(join(parent, p.name(a.Name)), a.Value)
+	}
+
+	for i := range n.Nodes {
+		p.property(&n.Nodes[i], join(parent, p.name(n.Nodes[i].XMLName)))
+	}
+}
+
+func (p *parser) property(n *node, name string) {
+	for _, a := range n.Attrs {
+		if a.Name.Space == rdfNS && a.Name.Local == "resource" {
+			p.add(name, a.Value)
+			return
+		}
+	}
+
+	if len(n.Nodes) == 0 {
+		p.add(name, n.Text)
+		return
+	}
+
+	for i := range n.Nodes {
+		c := &n.Nodes[i]
+
+		if c.XMLName.Space != rdfNS {
+			// structures using rdf:parseType="Resource"
+			p.property(c, join(name, p.name(c.XMLName)))
+			continue
+		}
+
+		switch c.XMLName.Local {
+		case "Bag", "Seq", "Alt":
+			for j := range c.Nodes {
+				p.property(&c.Nodes[j], name)
+			}
+		case "Description":
+			p.description(c, name)
+		}
+	}
+}
+
+func (p *parser) add(name, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+
+	p.props[name] = append(p.props[name], value)
+}
+
+func join(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+
+	return parent + "/" + name
+}
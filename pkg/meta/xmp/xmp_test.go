@@ -0,0 +1,98 @@
+package xmp_test
+
+import (
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/charlieegan3/storage-console/pkg/meta/xmp"
+)
+
+func TestParseEmbedded(t *testing.T) {
+	content, err := os.ReadFile("../fixtures/rx100-landscape.jpg")
+	if err != nil {
+		t.Fatalf("failed to read image file: %v", err)
+	}
+
+	packet := xmp.Extract(content)
+	if packet == nil {
+		t.Fatalf("expected to find an xmp packet")
+	}
+
+	props, err := xmp.Parse(packet)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := map[string]string{
+		"xmp:CreatorTool":  "Adobe Lightroom 8.1 (Macintosh)",
+		"aux:Lens":         "24-200mm F2.8-4.5",
+		"crs:WhiteBalance": "As Shot",
+		"dc:format":        "image/jpeg",
+	}
+
+	for name, value := range expected {
+		if got := props[name]; !slices.Equal(got, []string{value}) {
+			t.Errorf("expected %s to be %q, got %v", name, value, got)
+		}
+	}
+}
+
+func TestParseSidecar(t *testing.T) {
+	sidecar := []byte(`<?xpacket begin="" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about=""
+    xmlns:xmp="http://ns.adobe.com/xap/1.0/"
+    xmlns:dc="http://purl.org/dc/elements/1.1/"
+    xmlns:Iptc4xmpCore="http://iptc.org/std/Iptc4xmpCore/1.0/xmlns/"
+   xmp:Rating="4"
+   xmp:Label="Red">
+   <dc:subject>
+    <rdf:Bag>
+     <rdf:li>mountains</rdf:li>
+     <rdf:li>snow</rdf:li>
+    </rdf:Bag>
+   </dc:subject>
+   <dc:description>
+    <rdf:Alt>
+     <rdf:li xml:lang="x-default">Morning above the valley</rdf:li>
+    </rdf:Alt>
+   </dc:description>
+   <Iptc4xmpCore:CreatorContactInfo rdf:parseType="Resource">
+    <Iptc4xmpCore:CiAdrCity>Zermatt</Iptc4xmpCore:CiAdrCity>
+   </Iptc4xmpCore:CreatorContactInfo>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`)
+
+	props, err := xmp.Parse(xmp.Extract(sidecar))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := xmp.Properties{
+		"xmp:Rating":     {"4"},
+		"xmp:Label":      {"Red"},
+		"dc:subject":     {"mountains", "snow"},
+		"dc:description": {"Morning above the valley"},
+		"Iptc4xmpCore:CreatorContactInfo/Iptc4xmpCore:CiAdrCity": {"Zermatt"},
+	}
+
+	if len(props) != len(expected) {
+		t.Fatalf("expected %d properties, got %v", len(expected), props)
+	}
+
+	for name, values := range expected {
+		if got := props[name]; !slices.Equal(got, values) {
+			t.Errorf("expected %s to be %v, got %v", name, values, got)
+		}
+	}
+}
+
+func TestExtractMissing(t *testing.T) {
+	if packet := xmp.Extract([]byte("no metadata here")); packet != nil {
+		t.Fatalf("expected no packet, got %q", packet)
+	}
+}
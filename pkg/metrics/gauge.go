@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectTimeout bounds the collection of each GaugeFunc, since collectors
+// aren't given the scrape's context
+const collectTimeout = 10 * time.Second
+
+// Sample is a value collected by a GaugeFunc, Labels are the values for the
+// gauge's labels
+type Sample struct {
+	Labels []string
+	Value  float64
+}
+
+type gaugeFunc struct {
+	desc    *prometheus.Desc
+	collect func(ctx context.Context) ([]Sample, error)
+}
+
+// NewGaugeFunc registers a gauge whose samples are collected each time the
+// metrics are scraped, for values such as counts held in the database
+func (m *Metrics) NewGaugeFunc(name, help string, labels []string, collect func(ctx context.Context) ([]Sample, error)) {
+	m.Registry.MustRegister(&gaugeFunc{
+		desc:    prometheus.NewDesc(name, help, labels, nil),
+		collect: collect,
+	})
+}
+
+func (g *gaugeFunc) Describe(ch chan<- *prometheus.Desc) {
+	ch <- g.desc
+}
+
+func (g *gaugeFunc) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	samples, err := g.collect(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(g.desc, fmt.Errorf("failed to collect: %w", err))
+		return
+	}
+
+	for _, s := range samples {
+		ch <- prometheus.MustNewConstMetric(g.desc, prometheus.GaugeValue, s.Value, s.Labels...)
+	}
+}
@@ -0,0 +1,183 @@
+// Package metrics records the console's Prometheus metrics. A nil *Metrics
+// records nothing, so callers needn't check whether metrics are enabled.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/charlieegan3/storage-console/pkg/database"
+	"github.com/charlieegan3/storage-console/pkg/logging"
+)
+
+// Namespace prefixes the console's metric names
+const Namespace = "storage_console_"
+
+// runBuckets are histogram buckets in seconds for importer and runner
+// durations, which can take a long time for large buckets
+var runBuckets = []float64{1, 5, 15, 30, 60, 300, 900, 1800, 3600, 7200}
+
+type Metrics struct {
+	// Registry holds the console's metrics and the Go runtime and process
+	// metrics
+	Registry *prometheus.Registry
+
+	httpRequests  *prometheus.CounterVec
+	httpDuration  *prometheus.HistogramVec
+	runs          *prometheus.CounterVec
+	runDuration   *prometheus.HistogramVec
+	processorRuns *prometheus.CounterVec
+	s3Duration    *prometheus.HistogramVec
+	dbDuration    *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	r := prometheus.NewRegistry()
+	factory := promauto.With(r)
+
+	r.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return &Metrics{
+		Registry: r,
+
+		httpRequests: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: Namespace + "http_requests_total",
+			Help: "HTTP requests by route, method and status code.",
+		}, []string{"route", "method", "code"}),
+		httpDuration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    Namespace + "http_request_duration_seconds",
+			Help:    "HTTP request latency by route and method.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		runs: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: Namespace + "runs_total",
+			Help: "Importer and runner runs by bucket and result.",
+		}, []string{"runner", "bucket", "result"}),
+		runDuration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    Namespace + "run_duration_seconds",
+			Help:    "Importer and runner durations by bucket.",
+			Buckets: runBuckets,
+		}, []string{"runner", "bucket"}),
+		processorRuns: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: Namespace + "processor_runs_total",
+			Help: "Blobs processed by stage (meta or properties), processor, bucket and result.",
+		}, []string{"stage", "processor", "bucket", "result"}),
+		s3Duration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    Namespace + "s3_request_duration_seconds",
+			Help:    "Object storage request latency by bucket and HTTP method.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"bucket", "method"}),
+		dbDuration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    Namespace + "db_call_duration_seconds",
+			Help:    "Database call latency by operation.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"operation"}),
+	}
+}
+
+// Handler serves the metrics in the Prometheus exposition format. Metrics
+// which can't be collected, such as gauges read from an unavailable
+// database, are logged and left out.
+func (m *Metrics) Handler(logger *slog.Logger) http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{
+		ErrorLog:      errorLog{logger: logging.OrDiscard(logger)},
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+}
+
+// errorLog adapts a logger to promhttp.Logger
+type errorLog struct {
+	logger *slog.Logger
+}
+
+func (l errorLog) Println(v ...any) {
+	l.logger.Error("failed to collect metrics", "error", fmt.Sprint(v...))
+}
+
+// ObserveHTTP records a request handled by the server, route is the mux
+// pattern which matched it
+func (m *Metrics) ObserveHTTP(route, method string, code int, d time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.httpRequests.WithLabelValues(route, method, strconv.Itoa(code)).Inc()
+	m.httpDuration.WithLabelValues(route, method).Observe(d.Seconds())
+}
+
+// ObserveRun records a run of the importer or one of the runners
+func (m *Metrics) ObserveRun(runner, bucket string, d time.Duration, err error) {
+	if m == nil {
+		return
+	}
+
+	m.runs.WithLabelValues(runner, bucket, result(err)).Inc()
+	m.runDuration.WithLabelValues(runner, bucket).Observe(d.Seconds())
+}
+
+// CountProcessed records a processor's result for one blob, stage is meta
+// or properties since processors of each can share a name
+func (m *Metrics) CountProcessed(stage, processor, bucket string, err error) {
+	if m == nil {
+		return
+	}
+
+	m.processorRuns.WithLabelValues(stage, processor, bucket, result(err)).Inc()
+}
+
+// RoundTripper records the latency of the object storage requests made
+// with rt
+func (m *Metrics) RoundTripper(bucket string, rt http.RoundTripper) http.RoundTripper {
+	if m == nil {
+		return rt
+	}
+
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		start := time.Now()
+		resp, err := rt.RoundTrip(req)
+		m.s3Duration.WithLabelValues(bucket, req.Method).Observe(time.Since(start).Seconds())
+
+		return resp, err
+	})
+}
+
+// DBHook records the latency of database calls, for database.Open
+func (m *Metrics) DBHook() database.Hook {
+	return func(_ context.Context, op, _ string) func(error) {
+		start := time.Now()
+
+		return func(error) {
+			if m == nil {
+				return
+			}
+
+			m.dbDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+		}
+	}
+}
+
+func result(err error) string {
+	if err != nil {
+		return "failure"
+	}
+
+	return "success"
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNilMetrics(t *testing.T) {
+	t.Parallel()
+
+	var m *Metrics
+
+	// nothing is recorded, and nothing panics, when metrics are disabled
+	m.ObserveHTTP("/", http.MethodGet, http.StatusOK, time.Second)
+	m.ObserveRun("importer", "photos", time.Second, nil)
+	m.CountProcessed("meta", "exif", "photos", errors.New("boom"))
+	m.DBHook()(context.Background(), "query", "select 1")(nil)
+
+	if rt := m.RoundTripper("photos", http.DefaultTransport); rt != http.DefaultTransport {
+		t.Fatalf("expected the transport to be returned unchanged")
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	m := New()
+
+	m.ObserveHTTP("/b/", http.MethodGet, http.StatusNotFound, time.Millisecond)
+	m.ObserveRun("importer", "photos", 2*time.Second, nil)
+	m.CountProcessed("meta", "exif", "photos", nil)
+	m.CountProcessed("properties", "exif", "photos", errors.New("boom"))
+	m.DBHook()(context.Background(), "exec", "delete from objects")(nil)
+
+	client := &http.Client{Transport: m.RoundTripper("photos", http.DefaultTransport)}
+	resp, err := client.Head(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	resp.Body.Close()
+
+	body := scrape(t, m.Handler(nil))
+
+	for _, line := range []string{
+		`storage_console_http_requests_total{code="404",method="GET",route="/b/"} 1`,
+		`storage_console_runs_total{bucket="photos",result="success",runner="importer"} 1`,
+		`storage_console_run_duration_seconds_sum{bucket="photos",runner="importer"} 2`,
+		`storage_console_processor_runs_total{bucket="photos",processor="exif",result="success",stage="meta"} 1`,
+		`storage_console_processor_runs_total{bucket="photos",processor="exif",result="failure",stage="properties"} 1`,
+		`storage_console_s3_request_duration_seconds_count{bucket="photos",method="HEAD"} 1`,
+		`storage_console_db_call_duration_seconds_count{operation="exec"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected %q in:\n%s", line, body)
+		}
+	}
+}
+
+func TestGaugeFunc(t *testing.T) {
+	t.Parallel()
+
+	m := New()
+	m.NewGaugeFunc("objects", "Objects.", []string{"bucket"}, func(context.Context) ([]Sample, error) {
+		return []Sample{
+			{Labels: []string{"photos"}, Value: 12},
+			{Labels: []string{`say "hi"`}, Value: 1},
+		}, nil
+	})
+	m.NewGaugeFunc("backlog", "Backlog.", nil, func(context.Context) ([]Sample, error) {
+		return nil, errors.New("database unavailable")
+	})
+
+	body := scrape(t, m.Handler(nil))
+
+	expected := `# HELP objects Objects.
+# TYPE objects gauge
+objects{bucket="photos"} 12
+objects{bucket="say \"hi\""} 1
+`
+	if !strings.Contains(body, expected) {
+		t.Fatalf("expected %q in:\n%s", expected, body)
+	}
+
+	// the gauge which failed is left out, and the others are still served
+	if strings.Contains(body, "backlog") || !strings.Contains(body, "go_goroutines ") {
+		t.Fatalf("unexpected output:\n%s", body)
+	}
+}
+
+// scrape returns the metrics served by h
+func scrape(t *testing.T, h http.Handler) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status code %d: %s", rec.Code, rec.Body.String())
+	}
+
+	return rec.Body.String()
+}
@@ -0,0 +1,124 @@
+package processors
+
+import (
+	"fmt"
+
+	"github.com/charlieegan3/storage-console/pkg/meta"
+	metaColor "github.com/charlieegan3/storage-console/pkg/meta/color"
+	"github.com/charlieegan3/storage-console/pkg/meta/command"
+	metaExif "github.com/charlieegan3/storage-console/pkg/meta/exif"
+	"github.com/charlieegan3/storage-console/pkg/meta/ocr"
+	metaTags "github.com/charlieegan3/storage-console/pkg/meta/tags"
+	"github.com/charlieegan3/storage-console/pkg/meta/thumbnail"
+	"github.com/charlieegan3/storage-console/pkg/meta/webhook"
+	metaXMP "github.com/charlieegan3/storage-console/pkg/meta/xmp"
+	propertiesColor "github.com/charlieegan3/storage-console/pkg/properties/color"
+	propertiesExif "github.com/charlieegan3/storage-console/pkg/properties/exif"
+	propertiesOCR "github.com/charlieegan3/storage-console/pkg/properties/ocr"
+	propertiesPlaces "github.com/charlieegan3/storage-console/pkg/properties/places"
+	propertiesTags "github.com/charlieegan3/storage-console/pkg/properties/tags"
+	propertiesXMP "github.com/charlieegan3/storage-console/pkg/properties/xmp"
+)
+
+// BuiltinOptions configures the processors which are included in the
+// storage console
+type BuiltinOptions struct {
+	ThumbnailSizes   []int
+	ThumbnailFormat  meta.ContentType
+	ThumbnailQuality int
+
+	// OCR configures text recognition, which is only enabled when the
+	// tesseract command is available
+	OCR ocr.OCRProcessor
+
+	// Places configures reverse geocoding, the bundled gazetteer is used
+	// when it has none set
+	Places propertiesPlaces.PlacesProcessor
+
+	// XMPSidecar loads the .xmp sidecar files for photos, only embedded XMP
+	// is read when it's nil
+	XMPSidecar metaXMP.SidecarLoader
+
+	// AllTags enables the tags processors, which store every EXIF, IPTC and
+	// XMP tag as a property rather than a selection of EXIF tags
+	AllTags bool
+
+	// Commands are external executables to run as processors
+	Commands []command.CommandProcessor
+	// Webhooks are remote services to use as processors
+	Webhooks []*webhook.WebhookProcessor
+}
+
+// NewBuiltinRegistry returns a registry containing the built-in processors,
+// other processors can be registered into it before it's passed to the
+// runners.
+func NewBuiltinRegistry(opts BuiltinOptions) (*Registry, error) {
+	r := NewRegistry()
+
+	for _, p := range []meta.MetadataOperationProcessor{
+		&thumbnail.ThumbnailProcessor{
+			Sizes:   opts.ThumbnailSizes,
+			Format:  opts.ThumbnailFormat,
+			Quality: opts.ThumbnailQuality,
+		},
+		&metaExif.ExifMetadataProcessor{},
+		&metaColor.ColorAnalysisProcessor{},
+		&metaXMP.XMPProcessor{Sidecar: opts.XMPSidecar},
+	} {
+		if err := r.RegisterMeta(p); err != nil {
+			return nil, fmt.Errorf("failed to register metadata processor: %w", err)
+		}
+	}
+
+	if err := r.RegisterProperties(&propertiesExif.ExifProcessor{}); err != nil {
+		return nil, fmt.Errorf("failed to register properties processor: %w", err)
+	}
+
+	if err := r.RegisterProperties(&propertiesColor.ColorProcessor{}); err != nil {
+		return nil, fmt.Errorf("failed to register properties processor: %w", err)
+	}
+
+	if err := r.RegisterProperties(&propertiesXMP.XMPProcessor{}); err != nil {
+		return nil, fmt.Errorf("failed to register properties processor: %w", err)
+	}
+
+	placesProcessor := opts.Places
+	if err := r.RegisterProperties(&placesProcessor); err != nil {
+		return nil, fmt.Errorf("failed to register properties processor: %w", err)
+	}
+
+	if opts.AllTags {
+		if err := r.RegisterMeta(&metaTags.TagsProcessor{}); err != nil {
+			return nil, fmt.Errorf("failed to register metadata processor: %w", err)
+		}
+
+		if err := r.RegisterProperties(&propertiesTags.TagsProcessor{}); err != nil {
+			return nil, fmt.Errorf("failed to register properties processor: %w", err)
+		}
+	}
+
+	if ocr.Available(opts.OCR.Command) {
+		ocrProcessor := opts.OCR
+		if err := r.RegisterMeta(&ocrProcessor); err != nil {
+			return nil, fmt.Errorf("failed to register metadata processor: %w", err)
+		}
+
+		if err := r.RegisterProperties(&propertiesOCR.OCRProcessor{}); err != nil {
+			return nil, fmt.Errorf("failed to register properties processor: %w", err)
+		}
+	}
+
+	for i := range opts.Commands {
+		if err := r.RegisterWithProperties(&opts.Commands[i]); err != nil {
+			return nil, fmt.Errorf("failed to register command processor: %w", err)
+		}
+	}
+
+	for _, w := range opts.Webhooks {
+		if err := r.RegisterWithProperties(w); err != nil {
+			return nil, fmt.Errorf("failed to register webhook processor: %w", err)
+		}
+	}
+
+	return r, nil
+}
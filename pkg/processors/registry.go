@@ -0,0 +1,125 @@
+package processors
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/charlieegan3/storage-console/pkg/meta"
+	"github.com/charlieegan3/storage-console/pkg/properties"
+	"github.com/charlieegan3/storage-console/pkg/properties/keyvalue"
+)
+
+// Registry holds the metadata and properties processors available to the
+// runners. Properties processors must be registered after the metadata
+// processors they depend on.
+type Registry struct {
+	meta       map[string]meta.MetadataOperationProcessor
+	properties map[string]properties.Processor
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		meta:       make(map[string]meta.MetadataOperationProcessor),
+		properties: make(map[string]properties.Processor),
+	}
+}
+
+func (r *Registry) RegisterMeta(p meta.MetadataOperationProcessor) error {
+	if _, ok := r.meta[p.Name()]; ok {
+		return fmt.Errorf("metadata processor %q is already registered", p.Name())
+	}
+
+	r.meta[p.Name()] = p
+
+	return nil
+}
+
+func (r *Registry) RegisterProperties(p properties.Processor) error {
+	if _, ok := r.properties[p.Name()]; ok {
+		return fmt.Errorf("properties processor %q is already registered", p.Name())
+	}
+
+	if len(p.DependsOn()) == 0 {
+		return fmt.Errorf("properties processor %q must depend on a metadata processor", p.Name())
+	}
+
+	for _, dep := range p.DependsOn() {
+		if _, ok := r.meta[dep]; !ok {
+			return fmt.Errorf("properties processor %q depends on unknown metadata processor %q", p.Name(), dep)
+		}
+	}
+
+	r.properties[p.Name()] = p
+
+	return nil
+}
+
+// RegisterWithProperties registers a metadata processor which writes a JSON
+// object, along with a properties processor of the same name which loads
+// each key in the object as a property.
+func (r *Registry) RegisterWithProperties(p meta.MetadataOperationProcessor) error {
+	if err := r.RegisterMeta(p); err != nil {
+		return err
+	}
+
+	return r.RegisterProperties(&keyvalue.KeyValueProcessor{ProcessorName: p.Name()})
+}
+
+func (r *Registry) Meta(name string) (meta.MetadataOperationProcessor, error) {
+	p, ok := r.meta[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown metadata processor: %s", name)
+	}
+
+	return p, nil
+}
+
+func (r *Registry) Properties(name string) (properties.Processor, error) {
+	p, ok := r.properties[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown properties processor: %s", name)
+	}
+
+	return p, nil
+}
+
+// Dependents returns the names of the properties processors which depend on
+// the named metadata processor in alphabetical order
+func (r *Registry) Dependents(metaName string) []string {
+	var names []string
+	for name, p := range r.properties {
+		if slices.Contains(p.DependsOn(), metaName) {
+			names = append(names, name)
+		}
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// MetaNames returns the names of the registered metadata processors in
+// alphabetical order
+func (r *Registry) MetaNames() []string {
+	var names []string
+	for name := range r.meta {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// PropertiesNames returns the names of the registered properties processors
+// in alphabetical order
+func (r *Registry) PropertiesNames() []string {
+	var names []string
+	for name := range r.properties {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
@@ -0,0 +1,146 @@
+package processors
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/charlieegan3/storage-console/pkg/meta"
+	"github.com/charlieegan3/storage-console/pkg/meta/command"
+	"github.com/charlieegan3/storage-console/pkg/meta/ocr"
+	"github.com/charlieegan3/storage-console/pkg/properties"
+)
+
+type fakeMetaProcessor struct {
+	name string
+}
+
+func (f *fakeMetaProcessor) Name() string           { return f.name }
+func (f *fakeMetaProcessor) Version() int           { return 1 }
+func (f *fakeMetaProcessor) ContentTypes() []string { return []string{"text/plain"} }
+func (f *fakeMetaProcessor) Process(context.Context, *minio.ObjectInfo, []byte) ([]meta.PutMetadata, error) {
+	return nil, nil
+}
+
+type fakePropertiesProcessor struct {
+	name      string
+	dependsOn []string
+}
+
+func (f *fakePropertiesProcessor) Name() string        { return f.name }
+func (f *fakePropertiesProcessor) DependsOn() []string { return f.dependsOn }
+func (f *fakePropertiesProcessor) Version() int        { return 1 }
+func (f *fakePropertiesProcessor) Process(context.Context, []byte) ([]properties.BlobProperties, error) {
+	return nil, nil
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+
+	err := r.RegisterMeta(&fakeMetaProcessor{name: "words"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	err = r.RegisterMeta(&fakeMetaProcessor{name: "words"})
+	if err == nil {
+		t.Fatalf("expected error registering duplicate processor")
+	}
+
+	err = r.RegisterProperties(&fakePropertiesProcessor{name: "words", dependsOn: []string{"missing"}})
+	if err == nil {
+		t.Fatalf("expected error registering processor with unknown dependency")
+	}
+
+	err = r.RegisterProperties(&fakePropertiesProcessor{name: "words"})
+	if err == nil {
+		t.Fatalf("expected error registering processor without dependencies")
+	}
+
+	err = r.RegisterProperties(&fakePropertiesProcessor{name: "words", dependsOn: []string{"words"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := r.Meta("words"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := r.Properties("missing"); err == nil {
+		t.Fatalf("expected error getting unknown processor")
+	}
+
+	if exp, got := []string{"words"}, r.PropertiesNames(); !slices.Equal(exp, got) {
+		t.Fatalf("expected %v, got %v", exp, got)
+	}
+
+	err = r.RegisterWithProperties(&fakeMetaProcessor{name: "faces"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	p, err := r.Properties("faces")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if exp, got := []string{"faces"}, p.DependsOn(); !slices.Equal(exp, got) {
+		t.Fatalf("expected %v, got %v", exp, got)
+	}
+
+	if exp, got := []string{"words"}, r.Dependents("words"); !slices.Equal(exp, got) {
+		t.Fatalf("expected %v, got %v", exp, got)
+	}
+
+	if got := r.Dependents("missing"); len(got) != 0 {
+		t.Fatalf("expected no dependents, got %v", got)
+	}
+}
+
+func TestNewBuiltinRegistry(t *testing.T) {
+	r, err := NewBuiltinRegistry(BuiltinOptions{
+		Commands: []command.CommandProcessor{
+			{ProcessorName: "faces", Command: []string{"faces"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expectedMeta := []string{"color", "exif", "faces", "thumbnail", "xmp"}
+	expectedProperties := []string{"color", "exif", "faces", "places", "xmp"}
+
+	// ocr is only registered where tesseract is installed
+	if ocr.Available("") {
+		expectedMeta = []string{"color", "exif", "faces", "ocr", "thumbnail", "xmp"}
+		expectedProperties = []string{"color", "exif", "faces", "ocr", "places", "xmp"}
+	}
+
+	if exp, got := expectedMeta, r.MetaNames(); !slices.Equal(exp, got) {
+		t.Fatalf("expected %v, got %v", exp, got)
+	}
+
+	if exp, got := expectedProperties, r.PropertiesNames(); !slices.Equal(exp, got) {
+		t.Fatalf("expected %v, got %v", exp, got)
+	}
+
+	r, err = NewBuiltinRegistry(BuiltinOptions{AllTags: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := r.Properties("tags"); err != nil {
+		t.Fatalf("expected tags processor to be registered: %s", err)
+	}
+
+	_, err = NewBuiltinRegistry(BuiltinOptions{
+		Commands: []command.CommandProcessor{
+			{ProcessorName: "exif", Command: []string{"exiftool"}},
+		},
+	})
+	if err == nil {
+		t.Fatalf("expected error when a command shadows a built-in processor")
+	}
+}
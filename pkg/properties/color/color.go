@@ -5,6 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"sort"
+
+	"github.com/lucasb-eyer/go-colorful"
 
 	"github.com/charlieegan3/storage-console/pkg/properties"
 )
@@ -15,6 +18,16 @@ func (e *ColorProcessor) Name() string {
 	return "color"
 }
 
+// version 2 matches categories in CIELAB against the named palette and
+// stores their weights
+func (e *ColorProcessor) Version() int {
+	return 2
+}
+
+func (e *ColorProcessor) DependsOn() []string {
+	return []string{"color"}
+}
+
 func (e *ColorProcessor) Process(
 	ctx context.Context,
 	content []byte,
@@ -35,7 +48,7 @@ func (e *ColorProcessor) Process(
 
 		props = append(props, properties.BlobProperties{
 			ValueType:      "Text",
-			ValueText:      &[]string{fmt.Sprintf("%d,%d,%d", v.Color.R, v.Color.G, v.Color.B)}[0],
+			ValueText:      &[]string{v.Color.RGB()}[0],
 			PropertyType:   fmt.Sprintf("ProminentColor%d", i+1),
 			PropertySource: "color",
 		})
@@ -48,9 +61,14 @@ func (e *ColorProcessor) Process(
 
 		props = append(props, properties.BlobProperties{
 			ValueType:      "Text",
-			ValueText:      &v,
+			ValueText:      &v.Name,
 			PropertyType:   fmt.Sprintf("ColorCategory%d", i+1),
 			PropertySource: "color",
+		}, properties.BlobProperties{
+			ValueType:      "Float",
+			ValueFloat:     &v.Weight,
+			PropertyType:   fmt.Sprintf("ColorCategory%dWeight", i+1),
+			PropertySource: "color",
 		})
 	}
 
@@ -62,39 +80,71 @@ type colorData struct {
 	Cnt   int              `json:"Cnt"`
 }
 
-func distance(c1, c2 properties.Color) float64 {
-	return math.Sqrt(
-		math.Pow(float64(c1.R-c2.R), 2) +
-			math.Pow(float64(c1.G-c2.G), 2) +
-			math.Pow(float64(c1.B-c2.B), 2),
-	)
+// category is a palette color and the share of the image's pixels which
+// are closest to it
+type category struct {
+	Name   string
+	Weight float64
+}
+
+func toColorful(c properties.Color) colorful.Color {
+	return colorful.Color{
+		R: float64(c.R) / 255,
+		G: float64(c.G) / 255,
+		B: float64(c.B) / 255,
+	}
 }
 
+// findNearestColor returns the palette color which is perceptually closest
+// using the CIEDE2000 color difference
 func findNearestColor(c properties.Color) string {
 	minDist := math.MaxFloat64
 	nearestColor := ""
 
-	for name, predefined := range properties.PredefinedColors {
-		dist := distance(c, predefined)
+	target := toColorful(c)
+
+	for _, predefined := range properties.Palette {
+		dist := target.DistanceCIEDE2000(toColorful(predefined.Color))
 		if dist < minDist {
 			minDist = dist
-			nearestColor = name
+			nearestColor = predefined.Name
 		}
 	}
 
 	return nearestColor
 }
 
-func mapColors(colors []colorData) []string {
-	result := make(map[string]struct{})
-	orderedColors := []string{}
+// mapColors returns the palette categories of the colors, weighted by the
+// number of pixels in each cluster and ordered by weight
+func mapColors(colors []colorData) []category {
+	total := 0
+	for _, data := range colors {
+		total += data.Cnt
+	}
+
+	var categories []category
+	index := make(map[string]int)
 
 	for _, data := range colors {
+		// clusters without counts are weighted equally
+		weight := 1 / float64(len(colors))
+		if total > 0 {
+			weight = float64(data.Cnt) / float64(total)
+		}
+
 		nc := findNearestColor(data.Color)
-		if _, ok := result[nc]; !ok {
-			orderedColors = append(orderedColors, nc)
+		if i, ok := index[nc]; ok {
+			categories[i].Weight += weight
+			continue
 		}
+
+		index[nc] = len(categories)
+		categories = append(categories, category{Name: nc, Weight: weight})
 	}
 
-	return orderedColors
+	sort.SliceStable(categories, func(i, j int) bool {
+		return categories[i].Weight > categories[j].Weight
+	})
+
+	return categories
 }
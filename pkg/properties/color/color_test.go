@@ -2,8 +2,11 @@ package color
 
 import (
 	"context"
+	"math"
 	"os"
 	"testing"
+
+	"github.com/charlieegan3/storage-console/pkg/properties"
 )
 
 func TestColorProcessor(t *testing.T) {
@@ -20,7 +23,7 @@ func TestColorProcessor(t *testing.T) {
 		t.Fatalf("Could not process color: %s", err)
 	}
 
-	if exp, got := 6, len(props); exp != got {
+	if exp, got := 9, len(props); exp != got {
 		t.Fatalf("Expected %d properties, got %d", exp, got)
 	}
 
@@ -29,4 +32,60 @@ func TestColorProcessor(t *testing.T) {
 	if p0.PropertySource != "color" {
 		t.Fatalf("Expected property source to be color, got %s", p0.PropertySource)
 	}
+
+	total := 0.0
+	for _, prop := range props {
+		if prop.ValueType == "Float" {
+			total += *prop.ValueFloat
+		}
+	}
+
+	if math.Abs(total-1) > 1e-9 {
+		t.Fatalf("Expected category weights to sum to 1, got %f", total)
+	}
+}
+
+func TestFindNearestColor(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[properties.Color]string{
+		{R: 250, G: 250, B: 250}: "white",
+		{R: 5, G: 5, B: 5}:       "black",
+		{R: 30, G: 70, B: 210}:   "blue",
+		{R: 130, G: 80, B: 45}:   "brown",
+		{R: 250, G: 140, B: 20}:  "orange",
+		{R: 20, G: 35, B: 100}:   "navy",
+		{R: 45, G: 160, B: 60}:   "green",
+	}
+
+	for c, exp := range testCases {
+		if got := findNearestColor(c); got != exp {
+			t.Errorf("expected %v to be %s, got %s", c, exp, got)
+		}
+	}
+}
+
+func TestMapColors(t *testing.T) {
+	t.Parallel()
+
+	categories := mapColors([]colorData{
+		{Color: properties.Color{R: 30, G: 70, B: 210}, Cnt: 100},
+		{Color: properties.Color{R: 250, G: 250, B: 250}, Cnt: 200},
+		{Color: properties.Color{R: 35, G: 75, B: 205}, Cnt: 300},
+	})
+
+	expected := []category{
+		{Name: "blue", Weight: 400.0 / 600},
+		{Name: "white", Weight: 200.0 / 600},
+	}
+
+	if len(categories) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, categories)
+	}
+
+	for i := range expected {
+		if categories[i].Name != expected[i].Name || math.Abs(categories[i].Weight-expected[i].Weight) > 1e-9 {
+			t.Fatalf("expected %v, got %v", expected, categories)
+		}
+	}
 }
@@ -15,6 +15,14 @@ func (e *ExifProcessor) Name() string {
 	return "exif"
 }
 
+func (e *ExifProcessor) Version() int {
+	return 1
+}
+
+func (e *ExifProcessor) DependsOn() []string {
+	return []string{"exif"}
+}
+
 const source = "exif"
 
 func (e *ExifProcessor) Process(
@@ -180,6 +188,29 @@ func (e *ExifProcessor) Process(
 	return props, nil
 }
 
+// Coordinates returns the decimal GPS latitude and longitude from the output
+// of the exif metadata processor, false is returned when either is missing
+func Coordinates(content []byte) (float64, float64, bool, error) {
+	var em exifMetadata
+
+	err := json.Unmarshal(content, &em)
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("failed to unmarshal exif metadata: %w", err)
+	}
+
+	if len(em.GPSLatitude) < 3 || em.GPSLatitudeRef == "" || len(em.GPSLongitude) < 3 || em.GPSLongitudeRef == "" {
+		return 0, 0, false, nil
+	}
+
+	for _, c := range append(em.GPSLatitude, em.GPSLongitude...) {
+		if c.Denominator == 0 {
+			return 0, 0, false, nil
+		}
+	}
+
+	return convertDegrees(em.GPSLatitude, em.GPSLatitudeRef), convertDegrees(em.GPSLongitude, em.GPSLongitudeRef), true, nil
+}
+
 // convertDegrees converts GPS coordinates from fractional degrees to decimal format.
 func convertDegrees(coord []struct {
 	Numerator   int `json:"Numerator"`
@@ -35,3 +35,34 @@ func TestExifProcessor(t *testing.T) {
 		t.Fatalf("Expected PropertyType to be %s, got %s", exp, got)
 	}
 }
+
+func TestCoordinates(t *testing.T) {
+	t.Parallel()
+
+	bs, err := os.ReadFile("fixtures/exif.json")
+	if err != nil {
+		t.Fatalf("Could not read fixtures: %s", err)
+	}
+
+	lat, lon, ok, err := Coordinates(bs)
+	if err != nil {
+		t.Fatalf("Could not read coordinates: %s", err)
+	}
+
+	if !ok {
+		t.Fatalf("Expected coordinates to be found")
+	}
+
+	if lat < 9.96 || lat > 9.97 || lon < 76.24 || lon > 76.25 {
+		t.Fatalf("Unexpected coordinates %f, %f", lat, lon)
+	}
+
+	_, _, ok, err = Coordinates([]byte(`{"Make": "Sony"}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if ok {
+		t.Fatalf("Expected no coordinates")
+	}
+}
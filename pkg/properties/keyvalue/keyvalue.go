@@ -0,0 +1,103 @@
+package keyvalue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/charlieegan3/storage-console/pkg/properties"
+)
+
+// KeyValueProcessor loads each key of a JSON object written by a metadata
+// processor of the same name as a property. It's used for processors which
+// are configured rather than built in, where the output isn't known ahead of
+// time.
+type KeyValueProcessor struct {
+	ProcessorName string
+}
+
+func (k *KeyValueProcessor) Name() string {
+	return k.ProcessorName
+}
+
+func (k *KeyValueProcessor) Version() int {
+	return 1
+}
+
+func (k *KeyValueProcessor) DependsOn() []string {
+	return []string{k.ProcessorName}
+}
+
+func (k *KeyValueProcessor) Process(
+	ctx context.Context,
+	content []byte,
+) ([]properties.BlobProperties, error) {
+	var values map[string]any
+	err := json.Unmarshal(content, &values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+	}
+
+	var keys []string
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var props []properties.BlobProperties
+	for _, key := range keys {
+		prop, ok, err := Property(k.ProcessorName, key, values[key])
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			props = append(props, prop)
+		}
+	}
+
+	return props, nil
+}
+
+// Property converts a value decoded from JSON into a property. Booleans,
+// strings and numbers are stored as their own types, while lists and nested
+// objects are kept as JSON text. nulls are skipped.
+func Property(source, key string, value any) (properties.BlobProperties, bool, error) {
+	prop := properties.BlobProperties{
+		PropertySource: source,
+		PropertyType:   key,
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return prop, false, nil
+	case bool:
+		prop.ValueType = "Bool"
+		prop.ValueBool = &v
+	case string:
+		prop.ValueType = "Text"
+		prop.ValueText = &v
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) <= math.MaxInt32 {
+			i := int(v)
+			prop.ValueType = "Integer"
+			prop.ValueInteger = &i
+		} else {
+			prop.ValueType = "Float"
+			prop.ValueFloat = &v
+		}
+	default:
+		bs, err := json.Marshal(v)
+		if err != nil {
+			return prop, false, fmt.Errorf("failed to marshal value for %s: %w", key, err)
+		}
+
+		text := string(bs)
+		prop.ValueType = "Text"
+		prop.ValueText = &text
+	}
+
+	return prop, true, nil
+}
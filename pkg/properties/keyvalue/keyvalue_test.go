@@ -0,0 +1,54 @@
+package keyvalue
+
+import (
+	"context"
+	"testing"
+)
+
+func TestKeyValueProcessor(t *testing.T) {
+	t.Parallel()
+
+	processor := KeyValueProcessor{ProcessorName: "faces"}
+
+	props, err := processor.Process(context.Background(), []byte(`{
+  "count": 2,
+  "confidence": 0.93,
+  "reviewed": false,
+  "label": "family",
+  "names": ["alice", "bob"],
+  "missing": null
+}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := map[string]string{
+		"confidence": "0.93",
+		"count":      "2",
+		"label":      "family",
+		"names":      `["alice","bob"]`,
+		"reviewed":   "False",
+	}
+
+	if exp, got := len(expected), len(props); exp != got {
+		t.Fatalf("expected %d properties, got %d", exp, got)
+	}
+
+	for _, prop := range props {
+		if prop.PropertySource != "faces" {
+			t.Fatalf("unexpected source: %s", prop.PropertySource)
+		}
+
+		if exp, got := expected[prop.PropertyType], prop.String(); exp != got {
+			t.Fatalf("expected %s to be %q, got %q", prop.PropertyType, exp, got)
+		}
+	}
+
+	if props[1].PropertyType != "count" || props[1].ValueType != "Integer" {
+		t.Fatalf("expected count to be an integer, got %+v", props[1])
+	}
+
+	if deps := processor.DependsOn(); len(deps) != 1 || deps[0] != "faces" {
+		t.Fatalf("unexpected dependencies: %v", deps)
+	}
+}
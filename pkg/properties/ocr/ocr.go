@@ -0,0 +1,46 @@
+package ocr
+
+import (
+	"context"
+	"strings"
+
+	"github.com/charlieegan3/storage-console/pkg/properties"
+)
+
+const source = "ocr"
+
+// OCRProcessor loads recognised text as a property so that blobs can be
+// found by the text in them
+type OCRProcessor struct{}
+
+func (o *OCRProcessor) Name() string {
+	return "ocr"
+}
+
+func (o *OCRProcessor) Version() int {
+	return 1
+}
+
+func (o *OCRProcessor) DependsOn() []string {
+	return []string{"ocr"}
+}
+
+func (o *OCRProcessor) Process(
+	ctx context.Context,
+	content []byte,
+) ([]properties.BlobProperties, error) {
+	// line breaks from the page layout aren't useful for searching
+	text := strings.Join(strings.Fields(string(content)), " ")
+	if text == "" {
+		return nil, nil
+	}
+
+	return []properties.BlobProperties{
+		{
+			PropertySource: source,
+			PropertyType:   "Text",
+			ValueType:      "Text",
+			ValueText:      &text,
+		},
+	}, nil
+}
@@ -0,0 +1,34 @@
+package ocr
+
+import (
+	"context"
+	"testing"
+)
+
+func TestOCRProcessor(t *testing.T) {
+	t.Parallel()
+
+	processor := OCRProcessor{}
+
+	props, err := processor.Process(context.Background(), []byte("RECEIPT\n\nTOTAL   12.50\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(props) != 1 {
+		t.Fatalf("expected 1 property, got %d", len(props))
+	}
+
+	if exp, got := "RECEIPT TOTAL 12.50", props[0].String(); exp != got {
+		t.Fatalf("expected %q, got %q", exp, got)
+	}
+
+	props, err = processor.Process(context.Background(), []byte(" \n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(props) != 0 {
+		t.Fatalf("expected no properties for empty text, got %d", len(props))
+	}
+}
@@ -0,0 +1,110 @@
+package places
+
+import (
+	"context"
+
+	"github.com/charlieegan3/storage-console/pkg/geo"
+	"github.com/charlieegan3/storage-console/pkg/meta"
+	"github.com/charlieegan3/storage-console/pkg/properties"
+	"github.com/charlieegan3/storage-console/pkg/properties/exif"
+)
+
+const source = "places"
+
+// DefaultMaxDistance is used when the processor has no MaxDistance set
+const DefaultMaxDistance = 100.0
+
+// PlacesProcessor names the country, region and city where a photo was
+// taken from its GPS coordinates using an offline gazetteer
+type PlacesProcessor struct {
+	// Gazetteer is the bundled gazetteer when nil
+	Gazetteer *geo.Gazetteer
+	// MaxDistance in kilometres to the nearest place, photos further away
+	// than this, such as those taken at sea, are not named
+	MaxDistance float64
+}
+
+func (p *PlacesProcessor) Name() string {
+	return source
+}
+
+// Version changes with the gazetteer and max distance, so that places are
+// found again when either is changed
+func (p *PlacesProcessor) Version() int {
+	var checksum uint32
+	gazetteer, err := p.gazetteer()
+	if err == nil {
+		checksum = gazetteer.Checksum()
+	}
+
+	return meta.ConfigVersion(1, checksum, p.maxDistance())
+}
+
+func (p *PlacesProcessor) gazetteer() (*geo.Gazetteer, error) {
+	if p.Gazetteer != nil {
+		return p.Gazetteer, nil
+	}
+
+	return geo.Default()
+}
+
+func (p *PlacesProcessor) maxDistance() float64 {
+	if p.MaxDistance <= 0 {
+		return DefaultMaxDistance
+	}
+
+	return p.MaxDistance
+}
+
+func (p *PlacesProcessor) DependsOn() []string {
+	return []string{"exif"}
+}
+
+func (p *PlacesProcessor) Process(
+	ctx context.Context,
+	content []byte,
+) ([]properties.BlobProperties, error) {
+	lat, lon, ok, err := exif.Coordinates(content)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, nil
+	}
+
+	gazetteer, err := p.gazetteer()
+	if err != nil {
+		return nil, err
+	}
+
+	place, distance, ok := gazetteer.Nearest(lat, lon)
+	if !ok || distance > p.maxDistance() {
+		return nil, nil
+	}
+
+	var props []properties.BlobProperties
+	for _, v := range []struct {
+		propertyType string
+		value        string
+	}{
+		{"Country", place.Country},
+		{"CountryCode", place.CountryCode},
+		{"Region", place.Region},
+		{"City", place.City},
+	} {
+		if v.value == "" {
+			continue
+		}
+
+		value := v.value
+		props = append(props, properties.BlobProperties{
+			PropertySource: source,
+			PropertyType:   v.propertyType,
+			ValueType:      "Text",
+			ValueText:      &value,
+		})
+	}
+
+	return props, nil
+}
@@ -0,0 +1,106 @@
+package places
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/charlieegan3/storage-console/pkg/geo"
+)
+
+func TestPlacesProcessor(t *testing.T) {
+	t.Parallel()
+
+	bs, err := os.ReadFile("../exif/fixtures/exif.json")
+	if err != nil {
+		t.Fatalf("Could not read fixtures: %s", err)
+	}
+
+	processor := PlacesProcessor{}
+
+	props, err := processor.Process(context.Background(), bs)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := map[string]string{
+		"Country":     "India",
+		"CountryCode": "IN",
+		"Region":      "Kerala",
+		"City":        "Kochi",
+	}
+
+	if len(props) != len(expected) {
+		t.Fatalf("expected %d properties, got %d", len(expected), len(props))
+	}
+
+	for _, p := range props {
+		if exp, got := expected[p.PropertyType], p.String(); exp != got {
+			t.Errorf("expected %s to be %q, got %q", p.PropertyType, exp, got)
+		}
+	}
+}
+
+func TestPlacesProcessorMaxDistance(t *testing.T) {
+	t.Parallel()
+
+	bs, err := os.ReadFile("../exif/fixtures/exif.json")
+	if err != nil {
+		t.Fatalf("Could not read fixtures: %s", err)
+	}
+
+	// the only place is in rome, far from the photo
+	g, err := geo.Load(strings.NewReader("Rome\tLazio\tIT\t41.9028\t12.4964\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	processor := PlacesProcessor{Gazetteer: g, MaxDistance: 50}
+
+	props, err := processor.Process(context.Background(), bs)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(props) != 0 {
+		t.Fatalf("expected no properties, got %d", len(props))
+	}
+
+	props, err = processor.Process(context.Background(), []byte(`{"Make": "Sony"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(props) != 0 {
+		t.Fatalf("expected no properties without coordinates, got %d", len(props))
+	}
+}
+
+func TestPlacesProcessorVersion(t *testing.T) {
+	t.Parallel()
+
+	rome, err := geo.Load(strings.NewReader("Rome\tLazio\tIT\t41.9028\t12.4964\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	milan, err := geo.Load(strings.NewReader("Milan\tLombardy\tIT\t45.4642\t9.1900\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	base := (&PlacesProcessor{Gazetteer: rome}).Version()
+
+	if got := (&PlacesProcessor{Gazetteer: rome, MaxDistance: DefaultMaxDistance}).Version(); got != base {
+		t.Fatalf("expected the default max distance to give the same version")
+	}
+
+	if got := (&PlacesProcessor{Gazetteer: rome, MaxDistance: 10}).Version(); got == base {
+		t.Fatalf("expected a new max distance to change the version")
+	}
+
+	if got := (&PlacesProcessor{Gazetteer: milan}).Version(); got == base {
+		t.Fatalf("expected a new gazetteer to change the version")
+	}
+}
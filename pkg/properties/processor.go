@@ -7,24 +7,56 @@ import (
 	"time"
 )
 
-var PredefinedColors = map[string]Color{
-	"r":  {255, 0, 0},
-	"g":  {0, 255, 0},
-	"b":  {0, 0, 255},
-	"c":  {0, 255, 255},
-	"m":  {255, 0, 255},
-	"y":  {255, 255, 0},
-	"ro": {255, 127, 0},
-	"yo": {255, 191, 0},
-	"yg": {127, 255, 0},
-	"bg": {0, 255, 127},
-	"bv": {0, 127, 255},
-	"rv": {127, 0, 255},
-
-	"blk": {0, 0, 0},       // Black
-	"wht": {255, 255, 255}, // White
-	"lg":  {192, 192, 192}, // Light Grey
-	"dg":  {64, 64, 64},    // Dark Grey
+// Palette holds the named colors which prominent colors are categorised
+// into, in the order they're listed when browsing by color
+var Palette = []NamedColor{
+	{"red", Color{200, 30, 30}},
+	{"dark red", Color{120, 20, 20}},
+	{"pink", Color{240, 140, 170}},
+	{"magenta", Color{200, 40, 160}},
+	{"purple", Color{110, 50, 150}},
+	{"lavender", Color{180, 160, 220}},
+	{"navy", Color{20, 30, 90}},
+	{"blue", Color{40, 90, 200}},
+	{"sky blue", Color{120, 180, 230}},
+	{"teal", Color{0, 120, 120}},
+	{"turquoise", Color{60, 200, 190}},
+	{"dark green", Color{20, 70, 35}},
+	{"green", Color{50, 150, 60}},
+	{"olive", Color{110, 110, 40}},
+	{"lime", Color{160, 210, 60}},
+	{"yellow", Color{240, 210, 50}},
+	{"gold", Color{200, 160, 40}},
+	{"orange", Color{240, 130, 30}},
+	{"brown", Color{110, 70, 40}},
+	{"tan", Color{200, 170, 120}},
+	{"beige", Color{230, 215, 185}},
+	{"black", Color{0, 0, 0}},
+	{"charcoal", Color{50, 50, 55}},
+	{"grey", Color{128, 128, 128}},
+	{"silver", Color{192, 192, 192}},
+	{"white", Color{255, 255, 255}},
+}
+
+type NamedColor struct {
+	Name  string
+	Color Color
+}
+
+// PaletteColor returns the color with the given name from the palette
+func PaletteColor(name string) (Color, bool) {
+	for _, c := range Palette {
+		if c.Name == name {
+			return c.Color, true
+		}
+	}
+
+	return Color{}, false
+}
+
+// RGB formats the color for use in CSS rgb()
+func (c Color) RGB() string {
+	return fmt.Sprintf("%d,%d,%d", c.R, c.G, c.B)
 }
 
 type Color struct {
@@ -35,6 +67,13 @@ type Color struct {
 
 type Processor interface {
 	Name() string
+	// DependsOn returns the names of the metadata processors which must have
+	// succeeded for a blob before this processor can run. The output of the
+	// first is passed to Process.
+	DependsOn() []string
+	// Version is stored with the properties, it must change when the output
+	// changes so that existing blobs are processed again
+	Version() int
 	Process(ctx context.Context, content []byte) ([]BlobProperties, error)
 }
 
@@ -55,6 +94,8 @@ type BlobProperties struct {
 	ValueFloat       *float64   `db:"value_float"`
 	ValueTimestamp   *time.Time `db:"value_timestamp"`
 	ValueTimestamptz *time.Time `db:"value_timestamptz"`
+
+	Version int `db:"version"`
 }
 
 func (bp *BlobProperties) Color() string {
@@ -67,13 +108,34 @@ func (bp *BlobProperties) Color() string {
 	}
 
 	if strings.HasPrefix(bp.PropertyType, "ColorCategory") {
-		c := PredefinedColors[*bp.ValueText]
-		return fmt.Sprintf("%d,%d,%d", c.R, c.G, c.B)
+		c, ok := PaletteColor(*bp.ValueText)
+		if !ok {
+			return ""
+		}
+
+		return c.RGB()
 	}
 
 	return *bp.ValueText
 }
 
+// Stars returns an XMP rating as stars out of five, rejected photos have a
+// rating of -1
+func (bp *BlobProperties) Stars() string {
+	if bp.PropertySource != "xmp" || bp.PropertyType != "Rating" || bp.ValueInteger == nil {
+		return ""
+	}
+
+	rating := *bp.ValueInteger
+	if rating < 0 {
+		return "Rejected"
+	}
+
+	rating = min(rating, 5)
+
+	return strings.Repeat("★", rating) + strings.Repeat("☆", 5-rating)
+}
+
 func (bp *BlobProperties) String() string {
 	switch {
 	case bp.ValueType == "Bool" && bp.ValueBool != nil:
@@ -13,13 +13,31 @@ func TestBlobPropertiesColor(t *testing.T) {
 		Color          string
 	}{
 		"predef color": {
+			BlobProperties: BlobProperties{
+				PropertySource: "color",
+				PropertyType:   "ColorCategory1",
+				ValueType:      "Text",
+				ValueText:      &[]string{"orange"}[0],
+			},
+			Color: "240,130,30",
+		},
+		"unknown category": {
 			BlobProperties: BlobProperties{
 				PropertySource: "color",
 				PropertyType:   "ColorCategory1",
 				ValueType:      "Text",
 				ValueText:      &[]string{"ro"}[0],
 			},
-			Color: "255,127,0",
+			Color: "",
+		},
+		"category weight": {
+			BlobProperties: BlobProperties{
+				PropertySource: "color",
+				PropertyType:   "ColorCategory1Weight",
+				ValueType:      "Float",
+				ValueFloat:     &[]float64{0.5}[0],
+			},
+			Color: "",
 		},
 		"color": {
 			BlobProperties: BlobProperties{
@@ -43,6 +61,53 @@ func TestBlobPropertiesColor(t *testing.T) {
 	}
 }
 
+func TestBlobPropertiesStars(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		BlobProperties BlobProperties
+		Stars          string
+	}{
+		"rating": {
+			BlobProperties: BlobProperties{
+				PropertySource: "xmp",
+				PropertyType:   "Rating",
+				ValueType:      "Integer",
+				ValueInteger:   &[]int{3}[0],
+			},
+			Stars: "★★★☆☆",
+		},
+		"rejected": {
+			BlobProperties: BlobProperties{
+				PropertySource: "xmp",
+				PropertyType:   "Rating",
+				ValueType:      "Integer",
+				ValueInteger:   &[]int{-1}[0],
+			},
+			Stars: "Rejected",
+		},
+		"other integer": {
+			BlobProperties: BlobProperties{
+				PropertySource: "exif",
+				PropertyType:   "ISO",
+				ValueType:      "Integer",
+				ValueInteger:   &[]int{100}[0],
+			},
+			Stars: "",
+		},
+	}
+
+	for tcName, testData := range testCases {
+		t.Run(tcName, func(t *testing.T) {
+			t.Parallel()
+
+			if got, exp := testData.BlobProperties.Stars(), testData.Stars; got != exp {
+				t.Fatalf("expected: %q, got %q", exp, got)
+			}
+		})
+	}
+}
+
 func TestBlobPropertiesString(t *testing.T) {
 	t.Parallel()
 
@@ -1,32 +1,30 @@
 SELECT
-    bm.blob_id,
+    blobs.id,
     objects.key,
-    blobs.md5,
-    (bm.exif = 'success' AND NOT EXISTS (
-        SELECT 1
-        FROM blob_properties bp
-        WHERE bp.blob_id = bm.blob_id
-          AND bp.source = 'exif'
-          AND bp.property_type = 'Done'
-          AND bm.exif = 'success'
-    )) AS exif_missing,
-    (bm.color = 'success' AND NOT EXISTS (
-        SELECT 1
-        FROM blob_properties bp
-        WHERE bp.blob_id = bm.blob_id
-          AND bp.source = 'color'
-          AND bp.property_type = 'Done'
-          AND bm.color = 'success'
-    )) AS color_missing
+    blobs.md5
 FROM
-    blob_metadata bm
-JOIN
-    blobs ON bm.blob_id = blobs.id
+    blobs
 JOIN
     object_blobs ON blobs.id = object_blobs.blob_id
 JOIN
     objects ON object_blobs.object_id = objects.id
 WHERE
-    bm.exif = 'success' OR bm.color = 'success'
+    objects.bucket_id = $4
+    -- all the metadata processors this processor depends on have succeeded
+    AND (
+        SELECT count(*)
+        FROM blob_metadata bm
+        WHERE bm.blob_id = blobs.id
+          AND bm.processor = ANY($2::text[])
+          AND bm.result = 'success'
+    ) = cardinality($2::text[])
+    AND NOT EXISTS (
+        SELECT 1
+        FROM blob_properties bp
+        WHERE bp.blob_id = blobs.id
+          AND bp.source = $1
+          AND bp.property_type = 'Done'
+          AND bp.version = $3
+    )
 ORDER BY
-    bm.blob_id;
+    blobs.id;
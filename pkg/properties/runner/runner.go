@@ -7,16 +7,21 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
-	"path"
+	"log/slog"
+	"slices"
 	"strings"
 
+	"github.com/lib/pq"
 	"github.com/minio/minio-go/v7"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/charlieegan3/storage-console/pkg/database"
+	"github.com/charlieegan3/storage-console/pkg/layout"
+	"github.com/charlieegan3/storage-console/pkg/logging"
+	"github.com/charlieegan3/storage-console/pkg/metrics"
+	"github.com/charlieegan3/storage-console/pkg/processors"
 	"github.com/charlieegan3/storage-console/pkg/properties"
-	"github.com/charlieegan3/storage-console/pkg/properties/color"
-	"github.com/charlieegan3/storage-console/pkg/properties/exif"
+	"github.com/charlieegan3/storage-console/pkg/tracing"
 )
 
 //go:embed needs_props.sql
@@ -29,21 +34,37 @@ type Report struct {
 type Options struct {
 	SchemaName string
 	BucketName string
+	// BucketID is the database id of the bucket, from database.EnsureBuckets
+	BucketID int
 
 	Prefix string
+	// Layout is where metadata processor output is read from,
+	// layout.Default is used when it's nil
+	Layout *layout.Layout
 
 	EnabledProcessors []string
 
-	LoggerError *log.Logger
-	LoggerInfo  *log.Logger
+	// Registry holds the processors which can be enabled, the built-in
+	// processors are used when this is nil
+	Registry *processors.Registry
+
+	// Logger is used for progress and errors, nothing is logged when it's
+	// nil
+	Logger *slog.Logger
+	// Metrics counts processor results, it may be nil
+	Metrics *metrics.Metrics
+	// MetricsBucket labels the counts, it's the configured bucket's name
+	// since several can share an S3 bucket. BucketName is used when unset
+	MetricsBucket string
 }
 
 type blobProperties struct {
-	ID           int
-	Key          string
-	MD5          string
-	ExifMissing  bool
-	ColorMissing bool
+	ID  int
+	Key string
+	MD5 string
+
+	// ProcessorsNeeded are the enabled processors which have not yet run
+	ProcessorsNeeded []string
 }
 
 func Run(
@@ -52,17 +73,29 @@ func Run(
 	minioClient *minio.Client,
 	opts *Options,
 ) (*Report, error) {
-	processors := make(map[string]properties.Processor)
+	l := layout.OrDefault(opts.Layout)
+	logger := logging.OrDiscard(opts.Logger).With(logging.FieldBucket, opts.BucketName)
+
+	registry := opts.Registry
+	if registry == nil {
+		var err error
+		registry, err = processors.NewBuiltinRegistry(processors.BuiltinOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("could not build processor registry: %s", err)
+		}
+	}
+
+	enabledProcessors := make(map[string]properties.Processor)
 	for _, processorName := range opts.EnabledProcessors {
-		processor, err := processorForName(processorName)
+		processor, err := registry.Properties(processorName)
 		if err != nil {
 			return nil, fmt.Errorf("could not get processor: %s", err)
 		}
 
-		processors[processorName] = processor
+		enabledProcessors[processorName] = processor
 	}
 
-	txn, err := database.NewTxnWithSchema(db, opts.SchemaName)
+	txn, err := database.NewTxnWithSchemaContext(ctx, db, opts.SchemaName)
 	if err != nil {
 		return nil, fmt.Errorf("could not start transaction: %s", err)
 	}
@@ -70,70 +103,82 @@ func Run(
 	var rpt Report
 	rpt.Counts = make(map[string]int)
 
-	rows, err := txn.QueryContext(ctx, needsPropsSQL)
-	if err != nil {
-		_ = txn.Rollback()
-		return nil, fmt.Errorf("could not get blobs needing properties: %s", err)
-	}
+	var bps []*blobProperties
+	bpsByID := make(map[int]*blobProperties)
 
-	var bps []blobProperties
-	for rows.Next() {
-		var bp blobProperties
-		err = rows.Scan(&bp.ID, &bp.Key, &bp.MD5, &bp.ExifMissing, &bp.ColorMissing)
-		if errors.Is(err, sql.ErrNoRows) {
-			break
-		}
+	for _, processorName := range opts.EnabledProcessors {
+		processor := enabledProcessors[processorName]
+
+		rows, err := txn.QueryContext(
+			ctx,
+			needsPropsSQL,
+			processor.Name(),
+			pq.Array(processor.DependsOn()),
+			processor.Version(),
+			opts.BucketID,
+		)
 		if err != nil {
-			return nil, fmt.Errorf("could not scan path: %s", err)
+			_ = txn.Rollback()
+			return nil, fmt.Errorf("could not get blobs needing properties: %s", err)
 		}
 
-		if !strings.HasPrefix(bp.Key, opts.Prefix) {
-			continue
-		}
+		for rows.Next() {
+			var bp blobProperties
+			err = rows.Scan(&bp.ID, &bp.Key, &bp.MD5)
+			if errors.Is(err, sql.ErrNoRows) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("could not scan path: %s", err)
+			}
+
+			if !strings.HasPrefix(bp.Key, opts.Prefix) {
+				continue
+			}
+
+			if _, ok := bpsByID[bp.ID]; !ok {
+				bpsByID[bp.ID] = &bp
+				bps = append(bps, &bp)
+			}
 
-		bps = append(bps, bp)
+			// blobs are listed once per object that references them
+			if !slices.Contains(bpsByID[bp.ID].ProcessorsNeeded, processorName) {
+				bpsByID[bp.ID].ProcessorsNeeded = append(bpsByID[bp.ID].ProcessorsNeeded, processorName)
+			}
+		}
 	}
 
 	for _, bp := range bps {
 		var props []properties.BlobProperties
 
-		processorsNeeded := []string{}
-		if bp.ExifMissing {
-			processorsNeeded = append(processorsNeeded, "exif")
-		}
-		if bp.ColorMissing {
-			processorsNeeded = append(processorsNeeded, "color")
-		}
-
-		if len(processorsNeeded) == 0 {
-			continue
-		}
+		processorsNeeded := bp.ProcessorsNeeded
 
-		opts.LoggerInfo.Printf("processing properties for blob: %s (%s)", bp.Key, processorsNeeded)
+		logger.InfoContext(
+			ctx,
+			"processing properties",
+			logging.FieldKey, bp.Key,
+			logging.FieldETag, bp.MD5,
+			logging.FieldProcessor, processorsNeeded,
+		)
 
 		for _, processorName := range processorsNeeded {
-			ep, ok := processors[processorName]
-			if !ok {
-				return nil, fmt.Errorf("%s processor not found", processorName)
-			}
-
-			path := path.Join("meta", processorName, bp.MD5+".json")
-			obj, err := minioClient.GetObject(
-				ctx,
-				opts.BucketName,
-				path,
-				minio.GetObjectOptions{},
-			)
-			if err != nil {
-				return nil, fmt.Errorf("could not get object: %s", err)
-			}
+			ep := enabledProcessors[processorName]
 
-			bs, err := io.ReadAll(obj)
+			bs, err := readMetadata(ctx, minioClient, l.MetaBucketName(opts.BucketName), l, ep.DependsOn()[0], bp.MD5)
 			if err != nil {
-				return nil, fmt.Errorf("could not read object %s: %s", path, err)
+				return nil, fmt.Errorf("could not read metadata for %s: %s", processorName, err)
 			}
 
-			newProps, err := ep.Process(ctx, bs)
+			processCtx, span := tracing.Start(
+				ctx,
+				"process "+processorName,
+				attribute.String(logging.FieldProcessor, processorName),
+				attribute.String(logging.FieldKey, bp.Key),
+				attribute.String(logging.FieldETag, bp.MD5),
+			)
+			newProps, err := ep.Process(processCtx, bs)
+			tracing.End(span, err)
+			opts.Metrics.CountProcessed("properties", processorName, opts.metricsBucket(), err)
 			if err != nil {
 				return nil, fmt.Errorf("could not process object: %s", err)
 			}
@@ -149,6 +194,12 @@ func Run(
 
 			props = append(props, newProps...)
 
+			for i := range props {
+				if props[i].PropertySource == processorName {
+					props[i].Version = ep.Version()
+				}
+			}
+
 			deleteOldPropsSQL := `
 			delete from blob_properties
 where source = $1 and blob_id = $2;`
@@ -173,15 +224,38 @@ where source = $1 and blob_id = $2;`
 	return &rpt, nil
 }
 
-func processorForName(name string) (properties.Processor, error) {
-	switch name {
-	case "exif":
-		return &exif.ExifProcessor{}, nil
-	case "color":
-		return &color.ColorProcessor{}, nil
+// readMetadata loads the output of a metadata processor for a blob. Outputs
+// are stored as <meta prefix><processor>/<md5>.<ext> where the extension
+// depends on the processor.
+func readMetadata(
+	ctx context.Context,
+	minioClient *minio.Client,
+	bucketName string,
+	l *layout.Layout,
+	processorName, md5 string,
+) ([]byte, error) {
+	prefix := l.MetaKey(processorName, md5) + "."
+
+	for obj := range minioClient.ListObjects(ctx, bucketName, minio.ListObjectsOptions{Prefix: prefix}) {
+		if obj.Err != nil {
+			return nil, fmt.Errorf("could not list metadata: %w", obj.Err)
+		}
+
+		o, err := minioClient.GetObject(ctx, bucketName, obj.Key, minio.GetObjectOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("could not get object: %w", err)
+		}
+		defer o.Close()
+
+		bs, err := io.ReadAll(o)
+		if err != nil {
+			return nil, fmt.Errorf("could not read object %s: %w", obj.Key, err)
+		}
+
+		return bs, nil
 	}
 
-	return nil, fmt.Errorf("unknown processor: %s", name)
+	return nil, fmt.Errorf("no metadata found with prefix %s", prefix)
 }
 
 func insertBlobProperties(tx *sql.Tx, blobID int, properties []properties.BlobProperties) error {
@@ -194,7 +268,7 @@ func insertBlobProperties(tx *sql.Tx, blobID int, properties []properties.BlobPr
 			blob_id, source, property_type, value_type,
 			value_bool, value_numerator, value_denominator,
 			value_text, value_integer, value_float,
-			value_timestamp, value_timestamptz
+			value_timestamp, value_timestamptz, version
 		) VALUES
 	`
 
@@ -202,11 +276,11 @@ func insertBlobProperties(tx *sql.Tx, blobID int, properties []properties.BlobPr
 	placeholders := []string{}
 
 	for i, prop := range properties {
-		start := i * 12 // 12 columns per row
+		start := i * 13 // 13 columns per row
 		placeholders = append(placeholders, fmt.Sprintf(
-			"($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+			"($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
 			start+1, start+2, start+3, start+4, start+5, start+6, start+7,
-			start+8, start+9, start+10, start+11, start+12,
+			start+8, start+9, start+10, start+11, start+12, start+13,
 		))
 
 		values = append(values,
@@ -222,6 +296,7 @@ func insertBlobProperties(tx *sql.Tx, blobID int, properties []properties.BlobPr
 			prop.ValueFloat,
 			prop.ValueTimestamp,
 			prop.ValueTimestamptz,
+			max(prop.Version, 1),
 		)
 	}
 
@@ -234,3 +309,11 @@ func insertBlobProperties(tx *sql.Tx, blobID int, properties []properties.BlobPr
 
 	return nil
 }
+
+func (o *Options) metricsBucket() string {
+	if o.MetricsBucket != "" {
+		return o.MetricsBucket
+	}
+
+	return o.BucketName
+}
@@ -2,12 +2,13 @@ package runner_test
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"os"
 	"testing"
 
 	"github.com/minio/minio-go/v7"
 
+	"github.com/charlieegan3/storage-console/pkg/database"
 	"github.com/charlieegan3/storage-console/pkg/importer"
 	metaRunner "github.com/charlieegan3/storage-console/pkg/meta/runner"
 	"github.com/charlieegan3/storage-console/pkg/properties/runner"
@@ -44,6 +45,11 @@ func TestRun(t *testing.T) {
 		t.Fatalf("Could not init database: %s", err)
 	}
 
+	bucketIDs, err := database.EnsureBuckets(db, "storage_console", []string{"example"}, "example")
+	if err != nil {
+		t.Fatalf("Could not create buckets: %s", err)
+	}
+
 	// create the initial bucket state
 	err = minioClient.MakeBucket(ctx, "example", minio.MakeBucketOptions{})
 	if err != nil {
@@ -86,14 +92,14 @@ func TestRun(t *testing.T) {
 		}
 	}
 
-	logger := log.New(test.NewTLogWriter(t), "", 0)
+	logger := slog.New(slog.NewTextHandler(test.NewTLogWriter(t), nil))
 
 	// run the importer to set the initial state
 	importReport, err := importer.Run(ctx, db, minioClient, &importer.Options{
-		BucketName:  "example",
-		SchemaName:  "storage_console",
-		LoggerError: logger,
-		LoggerInfo:  logger,
+		BucketName: "example",
+		BucketID:   bucketIDs["example"],
+		SchemaName: "storage_console",
+		Logger:     logger,
 	})
 	if err != nil {
 		t.Fatalf("Could not run import: %s", err)
@@ -104,11 +110,11 @@ func TestRun(t *testing.T) {
 
 	_, err = metaRunner.Run(ctx, db, minioClient, &metaRunner.Options{
 		BucketName: "example",
+		BucketID:   bucketIDs["example"],
 		SchemaName: "storage_console",
 		// only need these two for properties
 		EnabledProcessors: []string{"exif", "color"},
-		LoggerError:       logger,
-		LoggerInfo:        logger,
+		Logger:            logger,
 	})
 	if err != nil {
 		t.Fatalf("Could not run meta runner: %s", err)
@@ -116,10 +122,10 @@ func TestRun(t *testing.T) {
 
 	rpt, err := runner.Run(ctx, db, minioClient, &runner.Options{
 		BucketName:        "example",
+		BucketID:          bucketIDs["example"],
 		SchemaName:        "storage_console",
 		EnabledProcessors: []string{"exif", "color"},
-		LoggerError:       logger,
-		LoggerInfo:        logger,
+		Logger:            logger,
 	})
 	if err != nil {
 		t.Fatalf("Could not run meta runner: %s", err)
@@ -0,0 +1,198 @@
+package tags
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charlieegan3/storage-console/pkg/meta/tags"
+	"github.com/charlieegan3/storage-console/pkg/properties"
+	"github.com/charlieegan3/storage-console/pkg/properties/keyvalue"
+)
+
+const source = "tags"
+
+// maxValueLength skips binary values such as maker notes and embedded
+// thumbnails which aren't useful as properties
+const maxValueLength = 1024
+
+// TagsProcessor loads every tag found by the tags metadata processor as a
+// property named <group>:<tag>, for example EXIF:WhiteBalance,
+// IPTC:Keywords or XMP:xmp:Rating. Tags with several values, such as
+// keywords, have a property per value.
+type TagsProcessor struct{}
+
+func (t *TagsProcessor) Name() string {
+	return source
+}
+
+func (t *TagsProcessor) Version() int {
+	return 1
+}
+
+func (t *TagsProcessor) DependsOn() []string {
+	return []string{"tags"}
+}
+
+func (t *TagsProcessor) Process(
+	ctx context.Context,
+	content []byte,
+) ([]properties.BlobProperties, error) {
+	var tt tags.Tags
+	err := json.Unmarshal(content, &tt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
+	}
+
+	var props []properties.BlobProperties
+
+	for _, name := range sortedKeys(tt.EXIF) {
+		if n, d, ok := fraction(tt.EXIF[name]); ok {
+			props = append(props, properties.BlobProperties{
+				PropertySource:   source,
+				PropertyType:     "EXIF:" + name,
+				ValueType:        "Fraction",
+				ValueNumerator:   &n,
+				ValueDenominator: &d,
+			})
+
+			continue
+		}
+
+		prop, ok, err := keyvalue.Property(source, "EXIF:"+name, exifValue(tt.EXIF[name]))
+		if err != nil {
+			return nil, err
+		}
+
+		if ok && (prop.ValueText == nil || len(*prop.ValueText) <= maxValueLength) {
+			props = append(props, prop)
+		}
+	}
+
+	props = append(props, textProperties("IPTC:", tt.IPTC)...)
+	props = append(props, textProperties("XMP:", tt.XMP)...)
+
+	return props, nil
+}
+
+// exifValue simplifies the values decoded by go-exif, which are lists even
+// when there's a single value
+func exifValue(value any) any {
+	switch v := value.(type) {
+	case string:
+		v = strings.TrimSpace(strings.TrimRight(v, "\x00"))
+		if v == "" {
+			return nil
+		}
+
+		return v
+	case []any:
+		if len(v) == 0 {
+			return nil
+		}
+
+		if len(v) == 1 {
+			return v[0]
+		}
+
+		var parts []string
+		for _, e := range v {
+			if r, ok := rational(e); ok {
+				parts = append(parts, r)
+				continue
+			}
+
+			n, ok := e.(float64)
+			if !ok {
+				return v
+			}
+
+			parts = append(parts, strconv.FormatFloat(n, 'f', -1, 64))
+		}
+
+		return strings.Join(parts, ", ")
+	}
+
+	return value
+}
+
+// fraction returns the parts of a single EXIF rational
+func fraction(value any) (int, int, bool) {
+	v, ok := value.([]any)
+	if !ok || len(v) != 1 {
+		return 0, 0, false
+	}
+
+	m, ok := v[0].(map[string]any)
+	if !ok {
+		return 0, 0, false
+	}
+
+	n, nok := m["Numerator"].(float64)
+	d, dok := m["Denominator"].(float64)
+	if !nok || !dok || d == 0 || math.Abs(n) > math.MaxInt32 || d > math.MaxInt32 {
+		return 0, 0, false
+	}
+
+	return int(n), int(d), true
+}
+
+// rational formats EXIF rationals as a fraction, or as a number when the
+// denominator is 1
+func rational(value any) (string, bool) {
+	m, ok := value.(map[string]any)
+	if !ok || len(m) != 2 {
+		return "", false
+	}
+
+	n, ok := m["Numerator"].(float64)
+	if !ok {
+		return "", false
+	}
+
+	d, ok := m["Denominator"].(float64)
+	if !ok || d == 0 {
+		return "", false
+	}
+
+	if d == 1 || math.Mod(n, d) == 0 {
+		return strconv.FormatFloat(n/d, 'f', -1, 64), true
+	}
+
+	return strconv.FormatFloat(n, 'f', -1, 64) + "/" + strconv.FormatFloat(d, 'f', -1, 64), true
+}
+
+func textProperties(prefix string, values map[string][]string) []properties.BlobProperties {
+	var props []properties.BlobProperties
+	for _, name := range sortedKeys(values) {
+		for _, value := range values[name] {
+			if value == "" || len(value) > maxValueLength {
+				continue
+			}
+
+			props = append(props, properties.BlobProperties{
+				PropertySource: source,
+				PropertyType:   prefix + name,
+				ValueType:      "Text",
+				ValueText:      &value,
+			})
+		}
+	}
+
+	return props
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
@@ -0,0 +1,65 @@
+package tags_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/charlieegan3/storage-console/pkg/properties/tags"
+)
+
+func TestTagsProcessor(t *testing.T) {
+	t.Parallel()
+
+	content := []byte(`{
+  "exif": {
+    "Make": "SONY",
+    "WhiteBalance": [0],
+    "ExposureTime": [{"Numerator": 1, "Denominator": 80}],
+    "GPSLatitude": [{"Numerator": 51, "Denominator": 1}, {"Numerator": 30, "Denominator": 1}, {"Numerator": 1234, "Denominator": 100}],
+    "ExifVersion": {"ExifVersion": "0231"},
+    "ImageDescription": "   \u0000"
+  },
+  "iptc": {
+    "Keywords": ["alps", "snow"]
+  },
+  "xmp": {
+    "xmp:Rating": ["4"]
+  }
+}`)
+
+	processor := tags.TagsProcessor{}
+
+	props, err := processor.Process(context.Background(), content)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []struct {
+		propertyType string
+		valueType    string
+		value        string
+	}{
+		{"EXIF:ExifVersion", "Text", `{"ExifVersion":"0231"}`},
+		{"EXIF:ExposureTime", "Fraction", "1/80"},
+		{"EXIF:GPSLatitude", "Text", "51, 30, 1234/100"},
+		{"EXIF:Make", "Text", "SONY"},
+		{"EXIF:WhiteBalance", "Integer", "0"},
+		{"IPTC:Keywords", "Text", "alps"},
+		{"IPTC:Keywords", "Text", "snow"},
+		{"XMP:xmp:Rating", "Text", "4"},
+	}
+
+	if len(props) != len(expected) {
+		for _, p := range props {
+			t.Logf("%s %s %s", p.PropertyType, p.ValueType, p.String())
+		}
+		t.Fatalf("expected %d properties, got %d", len(expected), len(props))
+	}
+
+	for i, e := range expected {
+		p := props[i]
+		if p.PropertySource != "tags" || p.PropertyType != e.propertyType || p.ValueType != e.valueType || p.String() != e.value {
+			t.Errorf("expected %s %s %q, got %s %s %q", e.propertyType, e.valueType, e.value, p.PropertyType, p.ValueType, p.String())
+		}
+	}
+}
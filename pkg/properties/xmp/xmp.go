@@ -0,0 +1,80 @@
+package xmp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/charlieegan3/storage-console/pkg/meta/xmp"
+	"github.com/charlieegan3/storage-console/pkg/properties"
+)
+
+const source = "xmp"
+
+// XMPProcessor loads the rating, color label, keywords and captions set in
+// Lightroom and other editors as properties
+type XMPProcessor struct{}
+
+func (x *XMPProcessor) Name() string {
+	return source
+}
+
+func (x *XMPProcessor) Version() int {
+	return 1
+}
+
+func (x *XMPProcessor) DependsOn() []string {
+	return []string{"xmp"}
+}
+
+func (x *XMPProcessor) Process(
+	ctx context.Context,
+	content []byte,
+) ([]properties.BlobProperties, error) {
+	var props xmp.Properties
+	err := json.Unmarshal(content, &props)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal xmp: %w", err)
+	}
+
+	var blobProperties []properties.BlobProperties
+
+	if values := props["xmp:Rating"]; len(values) > 0 {
+		// ratings are whole numbers, with -1 used for rejected photos
+		rating, err := strconv.ParseFloat(values[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse rating %q: %w", values[0], err)
+		}
+
+		r := int(rating)
+		blobProperties = append(blobProperties, properties.BlobProperties{
+			PropertySource: source,
+			PropertyType:   "Rating",
+			ValueType:      "Integer",
+			ValueInteger:   &r,
+		})
+	}
+
+	for _, p := range []struct {
+		name         string
+		propertyType string
+	}{
+		{"xmp:Label", "Label"},
+		{"dc:title", "Title"},
+		{"dc:description", "Caption"},
+		{"dc:subject", "Keyword"},
+	} {
+		for _, value := range props[p.name] {
+			v := value
+			blobProperties = append(blobProperties, properties.BlobProperties{
+				PropertySource: source,
+				PropertyType:   p.propertyType,
+				ValueType:      "Text",
+				ValueText:      &v,
+			})
+		}
+	}
+
+	return blobProperties, nil
+}
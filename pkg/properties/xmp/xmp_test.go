@@ -0,0 +1,53 @@
+package xmp
+
+import (
+	"context"
+	"testing"
+)
+
+func TestXMPProcessor(t *testing.T) {
+	t.Parallel()
+
+	processor := XMPProcessor{}
+
+	props, err := processor.Process(context.Background(), []byte(`{
+		"xmp:Rating": ["4"],
+		"xmp:Label": ["Red"],
+		"dc:subject": ["mountains", "snow"],
+		"dc:description": ["Morning above the valley"],
+		"crs:WhiteBalance": ["As Shot"]
+	}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []struct {
+		propertyType string
+		value        string
+	}{
+		{"Rating", "4"},
+		{"Label", "Red"},
+		{"Caption", "Morning above the valley"},
+		{"Keyword", "mountains"},
+		{"Keyword", "snow"},
+	}
+
+	if len(props) != len(expected) {
+		t.Fatalf("expected %d properties, got %d", len(expected), len(props))
+	}
+
+	for i, e := range expected {
+		if props[i].PropertyType != e.propertyType {
+			t.Errorf("expected property %d to be %s, got %s", i, e.propertyType, props[i].PropertyType)
+		}
+
+		if got := props[i].String(); got != e.value {
+			t.Errorf("expected %s to be %q, got %q", e.propertyType, e.value, got)
+		}
+	}
+
+	_, err = processor.Process(context.Background(), []byte(`{"xmp:Rating": ["lots"]}`))
+	if err == nil {
+		t.Fatalf("expected error for invalid rating")
+	}
+}
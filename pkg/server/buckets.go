@@ -0,0 +1,165 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/charlieegan3/storage-console/pkg/config"
+	"github.com/charlieegan3/storage-console/pkg/database"
+	"github.com/charlieegan3/storage-console/pkg/importer"
+	"github.com/charlieegan3/storage-console/pkg/logging"
+	metaRunner "github.com/charlieegan3/storage-console/pkg/meta/runner"
+	"github.com/charlieegan3/storage-console/pkg/metrics"
+	propRunner "github.com/charlieegan3/storage-console/pkg/properties/runner"
+	"github.com/charlieegan3/storage-console/pkg/server/handlers"
+	"github.com/charlieegan3/storage-console/pkg/tracing"
+)
+
+// OpenBuckets connects to each configured bucket, records it in the database
+// and builds its processors. The default bucket is returned first. m may be
+// nil when metrics aren't enabled.
+func OpenBuckets(cfg *config.Config, db *sql.DB, m *metrics.Metrics) ([]*handlers.Bucket, error) {
+	gazetteer, err := loadGazetteer(cfg.Processors.Places)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load gazetteer: %w", err)
+	}
+
+	var names []string
+	var defaultName string
+	for _, b := range cfg.Buckets {
+		names = append(names, b.Name)
+		if b.Default {
+			defaultName = b.Name
+		}
+	}
+
+	ids, err := database.EnsureBuckets(db, cfg.Database.SchemaName, names, defaultName)
+	if err != nil {
+		return nil, err
+	}
+
+	var buckets []*handlers.Bucket
+	for _, b := range cfg.Buckets {
+		wrap := []func(http.RoundTripper) http.RoundTripper{
+			func(rt http.RoundTripper) http.RoundTripper {
+				return m.RoundTripper(b.Name, rt)
+			},
+		}
+		if cfg.Server.Tracing.Enabled {
+			wrap = append(wrap, tracing.Transport)
+		}
+
+		minioClient, err := NewMinioClient(b.S3, wrap...)
+		if err != nil {
+			return nil, fmt.Errorf("bucket %s: %w", b.Name, err)
+		}
+
+		registry, err := buildRegistry(cfg, b.S3.BucketName, minioClient, gazetteer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build processor registry for bucket %s: %w", b.Name, err)
+		}
+
+		bucket := &handlers.Bucket{
+			ID:         ids[b.Name],
+			Name:       b.Name,
+			BucketName: b.S3.BucketName,
+			S3:         minioClient,
+			Processors: registry,
+			Layout:     b.Layout(),
+		}
+
+		if b.Default {
+			buckets = append([]*handlers.Bucket{bucket}, buckets...)
+		} else {
+			buckets = append(buckets, bucket)
+		}
+	}
+
+	return buckets, nil
+}
+
+// processBucket imports the objects in b under prefix and runs all the
+// processors over them
+func processBucket(ctx context.Context, opts *handlers.Options, b *handlers.Bucket, prefix string) error {
+	err := observeRun(ctx, opts, b, "importer", func(ctx context.Context) error {
+		_, err := importer.Run(ctx, opts.DB, b.S3, &importer.Options{
+			BucketName: b.BucketName,
+			BucketID:   b.ID,
+			SchemaName: opts.SchemaName,
+			Prefix:     prefix,
+			Layout:     b.Layout,
+			Logger:     opts.Logger,
+		})
+
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error running importer: %w", err)
+	}
+
+	// do initial metadata processing
+	err = observeRun(ctx, opts, b, "meta", func(ctx context.Context) error {
+		_, err := metaRunner.Run(ctx, opts.DB, b.S3, &metaRunner.Options{
+			BucketName:        b.BucketName,
+			BucketID:          b.ID,
+			SchemaName:        opts.SchemaName,
+			Prefix:            prefix,
+			Layout:            b.Layout,
+			Registry:          b.Processors,
+			EnabledProcessors: b.Processors.MetaNames(),
+			Logger:            opts.Logger,
+			Metrics:           opts.Metrics,
+			MetricsBucket:     b.Name,
+		})
+
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error running metadata runner: %w", err)
+	}
+
+	// upgrade metadata into rich properties
+	err = observeRun(ctx, opts, b, "properties", func(ctx context.Context) error {
+		_, err := propRunner.Run(ctx, opts.DB, b.S3, &propRunner.Options{
+			BucketName:        b.BucketName,
+			BucketID:          b.ID,
+			SchemaName:        opts.SchemaName,
+			Prefix:            prefix,
+			Layout:            b.Layout,
+			Registry:          b.Processors,
+			EnabledProcessors: b.Processors.PropertiesNames(),
+			Logger:            opts.Logger,
+			Metrics:           opts.Metrics,
+			MetricsBucket:     b.Name,
+		})
+
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error running properties runner: %w", err)
+	}
+
+	return nil
+}
+
+// observeRun times the importer or a runner and records a span for it
+func observeRun(ctx context.Context, opts *handlers.Options, b *handlers.Bucket, runner string, run func(context.Context) error) error {
+	ctx, span := tracing.Start(
+		ctx,
+		"run "+runner,
+		attribute.String(logging.FieldBucket, b.Name),
+	)
+
+	start := time.Now()
+	err := run(ctx)
+
+	opts.Metrics.ObserveRun(runner, b.Name, time.Since(start), err)
+	tracing.End(span, err)
+
+	return err
+}
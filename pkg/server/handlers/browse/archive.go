@@ -0,0 +1,171 @@
+package browse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/charlieegan3/storage-console/pkg/archive"
+	"github.com/charlieegan3/storage-console/pkg/logging"
+	"github.com/charlieegan3/storage-console/pkg/meta"
+	"github.com/charlieegan3/storage-console/pkg/server/handlers"
+)
+
+type archiveEntry struct {
+	Name     string
+	Size     string
+	Modified string
+	IsDir    bool
+}
+
+// loadArchiveListing returns the entries in an archive object. Listings are
+// cached in the meta path keyed by the object's ETag, so the archive is only
+// read the first time it's previewed.
+func loadArchiveListing(
+	ctx context.Context,
+	opts *handlers.Options,
+	mc *minio.Client,
+	objectPath string,
+	stat minio.ObjectInfo,
+	format archive.Format,
+) ([]archiveEntry, error) {
+	listingPath := opts.Bucket.MetaKey("archive", stat.ETag+".json")
+
+	var entries []archive.Entry
+
+	cached, err := mc.GetObject(ctx, opts.Bucket.MetaBucketName(), listingPath, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cached listing: %w", err)
+	}
+	defer cached.Close()
+
+	err = json.NewDecoder(cached).Decode(&entries)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code != "NoSuchKey" {
+			return nil, fmt.Errorf("failed to read cached listing: %w", err)
+		}
+
+		obj, err := mc.GetObject(ctx, opts.Bucket.BucketName, objectPath, minio.GetObjectOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get archive: %w", err)
+		}
+		defer obj.Close()
+
+		entries, err = archive.List(obj, stat.Size, format)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list archive: %w", err)
+		}
+
+		bs, err := json.Marshal(entries)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal listing: %w", err)
+		}
+
+		_, err = mc.PutObject(
+			ctx,
+			opts.Bucket.MetaBucketName(),
+			listingPath,
+			bytes.NewReader(bs),
+			int64(len(bs)),
+			minio.PutObjectOptions{
+				ContentType: meta.ContentTypeToString(meta.JSON),
+			},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to cache listing: %w", err)
+		}
+	}
+
+	var view []archiveEntry
+	for _, e := range entries {
+		ae := archiveEntry{
+			Name:     e.Name,
+			IsDir:    e.IsDir,
+			Modified: e.Modified.Format(time.RFC3339),
+		}
+
+		if !e.IsDir {
+			ae.Size = humanizeBytes(e.Size)
+		}
+
+		view = append(view, ae)
+	}
+
+	return view, nil
+}
+
+// renderArchiveEntry streams a single file from inside an archive object
+func renderArchiveEntry(
+	opts *handlers.Options,
+	mc *minio.Client,
+	objectPath string,
+	entryName string,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p := opts.Bucket.DataKey(objectPath)
+
+		stat, err := mc.StatObject(r.Context(), opts.Bucket.BucketName, p, minio.StatObjectOptions{})
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			opts.Logger.ErrorContext(r.Context(), "failed to stat archive", logging.Err(err))
+			return
+		}
+
+		format := archive.FormatFor(stat.ContentType, p)
+		if format == "" {
+			w.WriteHeader(http.StatusBadRequest)
+
+			_, err = w.Write([]byte("object is not a supported archive"))
+			if err != nil {
+				opts.Logger.ErrorContext(r.Context(), "failed to write response", logging.Err(err))
+			}
+
+			return
+		}
+
+		// entries in an archive never change without the archive's etag changing
+		etag := fmt.Sprintf("%s-%s", stat.ETag, entryName)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		obj, err := mc.GetObject(r.Context(), opts.Bucket.BucketName, p, minio.GetObjectOptions{})
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			opts.Logger.ErrorContext(r.Context(), "failed to get archive", logging.Err(err))
+			return
+		}
+		defer obj.Close()
+
+		rc, entry, err := archive.Open(obj, stat.Size, format, entryName)
+		if errors.Is(err, archive.ErrEntryNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			opts.Logger.ErrorContext(r.Context(), "failed to open archive entry", logging.Err(err))
+			return
+		}
+		defer rc.Close()
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", entry.Size))
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filepath.Base(entry.Name)))
+
+		_, err = io.Copy(w, rc)
+		if err != nil {
+			opts.Logger.ErrorContext(r.Context(), "failed to copy archive entry to response", logging.Err(err))
+		}
+	}
+}
@@ -12,21 +12,20 @@ import (
 	"path"
 	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/davidbyttow/govips/v2/vips"
 	"github.com/minio/minio-go/v7"
+	"go.opentelemetry.io/otel/attribute"
 
+	"github.com/charlieegan3/storage-console/pkg/archive"
 	"github.com/charlieegan3/storage-console/pkg/database"
+	"github.com/charlieegan3/storage-console/pkg/logging"
+	"github.com/charlieegan3/storage-console/pkg/meta/thumbnail"
 	"github.com/charlieegan3/storage-console/pkg/properties"
 	"github.com/charlieegan3/storage-console/pkg/server/handlers"
-	"github.com/charlieegan3/storage-console/pkg/utils"
-)
-
-const (
-	dataPath = "data/"
-	metaPath = "meta/"
+	"github.com/charlieegan3/storage-console/pkg/tracing"
 )
 
 type browseEntry struct {
@@ -38,6 +37,8 @@ type browseEntry struct {
 	Size        string
 	HasThumb    bool
 	MD5         string
+	ThumbSrc    string
+	ThumbSrcset string
 }
 
 type breadcrumbs struct {
@@ -56,8 +57,6 @@ func BuildHandler(opts *handlers.Options) (func(http.ResponseWriter, *http.Reque
 		return nil, fmt.Errorf("DB is required")
 	}
 
-	mc := opts.S3
-
 	tmplDir, err := template.ParseFS(
 		handlers.Templates,
 		"templates/browse.html",
@@ -85,20 +84,59 @@ func BuildHandler(opts *handlers.Options) (func(http.ResponseWriter, *http.Reque
 	)
 
 	return func(w http.ResponseWriter, r *http.Request) {
+		bucketName, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/b/"), "/")
+		if bucketName == "" && len(opts.Buckets) > 0 {
+			http.Redirect(w, r, opts.Buckets[0].URL()+"/", http.StatusFound)
+			return
+		}
+
+		b, ok := opts.FindBucket(bucketName)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		// the bucket root has no trailing / when linked from breadcrumbs
+		if r.URL.Path == b.URL() {
+			http.Redirect(w, r, b.URL()+"/", http.StatusFound)
+			return
+		}
+
+		opts := opts.ForBucket(b)
+		mc := b.S3
+
 		preview := r.URL.Query().Get("preview")
 		asset := r.URL.Query().Get("asset")
 		thumb := r.URL.Query().Get("thumb")
 		download := r.URL.Query().Get("download")
 		view := r.URL.Query().Get("view")
+		entry := r.URL.Query().Get("entry")
 
 		// then render the object
 		if asset != "" {
-			objectPath := strings.TrimPrefix(path.Join(r.URL.Path, asset), "/b/")
+			objectPath := strings.TrimPrefix(path.Join(r.URL.Path, asset), b.URL()+"/")
+
+			if entry != "" {
+				renderArchiveEntry(opts, mc, objectPath, entry)(w, r)
+				return
+			}
 
 			if thumb != "" {
-				objectPath := strings.TrimPrefix(path.Join(r.URL.Path, asset), "/b/")
+				size, err := strconv.Atoi(r.URL.Query().Get("size"))
+				if err != nil || !slices.Contains(opts.ThumbnailSizes, size) {
+					w.WriteHeader(http.StatusBadRequest)
+
+					_, err = w.Write([]byte("unknown thumbnail size"))
+					if err != nil {
+						opts.Logger.ErrorContext(r.Context(), "failed to write response", logging.Err(err))
+					}
+
+					return
+				}
+
+				thumbPath := thumbnail.Path(size, thumb, opts.ThumbnailFormat)
 
-				renderObject(opts, mc, objectPath, download != "", thumb)(w, r)
+				renderObject(opts, mc, objectPath, download != "", thumbPath)(w, r)
 				return
 			}
 
@@ -109,7 +147,7 @@ func BuildHandler(opts *handlers.Options) (func(http.ResponseWriter, *http.Reque
 
 		// then render the file
 		if preview != "" {
-			objectPath := path.Join("data", strings.TrimPrefix(r.URL.Path+preview, "/b/"))
+			objectPath := b.DataKey(strings.TrimPrefix(r.URL.Path+preview, b.URL()+"/"))
 
 			renderPreview(opts, mc, tmplFile, objectPath)(w, r)
 
@@ -132,8 +170,8 @@ func BuildHandler(opts *handlers.Options) (func(http.ResponseWriter, *http.Reque
 		w.WriteHeader(http.StatusBadRequest)
 
 		_, err = w.Write([]byte("unknown path type"))
-		if err != nil && opts.LoggerError != nil {
-			opts.LoggerError.Println(fmt.Errorf("failed to write response: %s", err))
+		if err != nil {
+			opts.Logger.ErrorContext(r.Context(), "failed to write response", logging.Err(err))
 		}
 	}, nil
 }
@@ -143,22 +181,25 @@ func renderObject(
 	mc *minio.Client,
 	objectPath string,
 	download bool,
-	thumbKey string,
+	thumbPath string,
 ) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var err error
 		var p string
 
-		if thumbKey != "" {
-			p = path.Join(metaPath, fmt.Sprintf("thumbnail/%s.jpg", thumbKey))
+		// thumbnails are processor output, stored in the meta bucket
+		bucketName := opts.Bucket.BucketName
+		if thumbPath != "" {
+			bucketName = opts.Bucket.MetaBucketName()
+			p = opts.Bucket.MetaKey(thumbPath)
 		} else {
-			p = path.Join(dataPath, objectPath)
+			p = opts.Bucket.DataKey(objectPath)
 		}
 
 		var obj io.Reader
 		obj, err = mc.GetObject(
 			r.Context(),
-			opts.BucketName,
+			bucketName,
 			p,
 			minio.StatObjectOptions{},
 		)
@@ -166,8 +207,8 @@ func renderObject(
 			w.WriteHeader(http.StatusInternalServerError)
 
 			_, err = w.Write([]byte(err.Error()))
-			if err != nil && opts.LoggerError != nil {
-				opts.LoggerError.Println(fmt.Errorf("failed to get object: %s", err))
+			if err != nil {
+				opts.Logger.ErrorContext(r.Context(), "failed to get object", logging.Err(err))
 			}
 
 			return
@@ -175,78 +216,69 @@ func renderObject(
 
 		stat, err := mc.StatObject(
 			r.Context(),
-			opts.BucketName,
+			bucketName,
 			p,
 			minio.StatObjectOptions{},
 		)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			_, err = w.Write([]byte(err.Error()))
-			if err != nil && opts.LoggerError != nil {
-				opts.LoggerError.Println(fmt.Errorf("failed to get object stat: %s", err))
+			if err != nil {
+				opts.Logger.ErrorContext(r.Context(), "failed to get object stat", logging.Err(err))
 			}
 
 			return
 		}
 
 		w.Header().Set("Content-Type", stat.ContentType)
-		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
-		w.Header().Set("Expires", time.Now().AddDate(10, 0, 0).Format(http.TimeFormat))
+		if thumbPath != "" {
+			// thumbnail URLs include the source's md5, so they always
+			// show the same image
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+			w.Header().Set("Expires", time.Now().AddDate(10, 0, 0).Format(http.TimeFormat))
+		} else {
+			// objects can be replaced, and large images switch to their
+			// preview once it's generated, so they're revalidated by ETag
+			w.Header().Set("Cache-Control", "no-cache")
+		}
 
 		etag := stat.ETag
 		contentLength := stat.Size
 
-		if r.Header.Get("If-None-Match") == etag {
-			w.WriteHeader(http.StatusNotModified)
-			return
-		}
-
-		if !download && stat.Size > 1024*1024 && stat.ContentType == "image/jpeg" {
-			originalImage, err := vips.NewImageFromReader(obj)
+		// large images are shown using their largest thumbnail, or the
+		// original until it has been generated
+		if thumbPath == "" && !download && stat.Size > previewMinBytes && stat.ContentType == "image/jpeg" && len(opts.ThumbnailSizes) > 0 {
+			previewPath, previewStat, ok, err := statPreview(r.Context(), opts, mc, stat.ETag, slices.Max(opts.ThumbnailSizes))
 			if err != nil {
 				w.WriteHeader(http.StatusInternalServerError)
-				opts.LoggerError.Println(fmt.Errorf("failed to create image from reader: %s", err))
+				opts.Logger.ErrorContext(r.Context(), "failed to stat preview", logging.Err(err))
 				return
 			}
 
-			longestSide := originalImage.Width()
-			if originalImage.Height() > originalImage.Width() {
-				longestSide = originalImage.Height()
-			}
+			if ok {
+				etag = previewETag(stat.ETag, previewStat.ETag)
+				if r.Header.Get("If-None-Match") == etag {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
 
-			if longestSide > 1200 {
-				err := originalImage.Resize(float64(1200)/float64(longestSide), vips.KernelNearest)
+				preview, err := mc.GetObject(r.Context(), opts.Bucket.MetaBucketName(), previewPath, minio.GetObjectOptions{})
 				if err != nil {
 					w.WriteHeader(http.StatusInternalServerError)
-					opts.LoggerError.Println(fmt.Errorf("failed to resize image: %s", err))
+					opts.Logger.ErrorContext(r.Context(), "failed to get preview", logging.Err(err))
 					return
 				}
-			}
+				defer preview.Close()
 
-			err = originalImage.AutoRotate()
-			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				opts.LoggerError.Println(fmt.Errorf("failed to auto rotate image: %s", err))
-				return
-			}
-
-			ep := vips.NewDefaultJPEGExportParams()
-			thumbBytes, _, err := originalImage.Export(ep)
-			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				opts.LoggerError.Println(fmt.Errorf("failed to export image: %s", err))
-				return
+				obj = preview
+				contentLength = previewStat.Size
+				w.Header().Set("Content-Type", previewStat.ContentType)
 			}
+		}
 
-			obj = bytes.NewReader(thumbBytes)
-
-			contentLength = int64(len(thumbBytes))
-			etag = utils.CRC32Hash(thumbBytes)
-
-			if r.Header.Get("If-None-Match") == etag {
-				w.WriteHeader(http.StatusNotModified)
-				return
-			}
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
 		}
 
 		w.Header().Set("Content-Length", fmt.Sprintf("%d", contentLength))
@@ -258,19 +290,13 @@ func renderObject(
 			w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"%s\"", filepath.Base(objectPath)))
 		}
 
-		if r.Header.Get("If-None-Match") == stat.ETag {
-			w.WriteHeader(http.StatusNotModified)
-
-			return
-		}
-
 		_, err = io.Copy(w, obj)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 
 			_, err = w.Write([]byte(err.Error()))
-			if err != nil && opts.LoggerError != nil {
-				opts.LoggerError.Println(fmt.Errorf("failed to copy object to response: %s", err))
+			if err != nil {
+				opts.Logger.ErrorContext(r.Context(), "failed to copy object to response", logging.Err(err))
 			}
 
 			return
@@ -285,9 +311,9 @@ func renderPreview(
 	objectPath string,
 ) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		_, err := mc.StatObject(
+		stat, err := mc.StatObject(
 			r.Context(),
-			opts.BucketName,
+			opts.Bucket.BucketName,
 			objectPath,
 			minio.StatObjectOptions{},
 		)
@@ -295,8 +321,8 @@ func renderPreview(
 			w.WriteHeader(http.StatusInternalServerError)
 
 			_, err = w.Write([]byte(err.Error()))
-			if err != nil && opts.LoggerError != nil {
-				opts.LoggerError.Println(fmt.Errorf("failed to stat object: %s", err))
+			if err != nil {
+				opts.Logger.ErrorContext(r.Context(), "failed to stat object", logging.Err(err))
 			}
 
 			return
@@ -304,50 +330,50 @@ func renderPreview(
 
 		buf := bytes.NewBuffer([]byte{})
 
-		viewPath := strings.TrimPrefix(objectPath, dataPath)
+		viewPath := opts.Bucket.ObjectKey(objectPath)
 
-		txn, err := database.NewTxnWithSchema(opts.DB, "storage_console")
+		txn, err := database.NewTxnWithSchemaContext(r.Context(), opts.DB, opts.SchemaName)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			_, err = w.Write([]byte(err.Error()))
-			if err != nil && opts.LoggerError != nil {
-				opts.LoggerError.Println(fmt.Errorf("failed to create transaction: %s", err))
+			if err != nil {
+				opts.Logger.ErrorContext(r.Context(), "failed to create transaction", logging.Err(err))
 			}
 			return
 		}
 
 		defer func() {
 			err := txn.Commit()
-			if err != nil && opts.LoggerError != nil {
-				opts.LoggerError.Println(fmt.Errorf("failed to commit transaction: %s", err))
+			if err != nil {
+				opts.Logger.ErrorContext(r.Context(), "failed to commit transaction", logging.Err(err))
 			}
 		}()
 
-		objectExistsSQL := `select deleted_at from objects where key = $1`
+		objectExistsSQL := `select deleted_at from objects where bucket_id = $1 and key = $2`
 		var deletedAt sql.NullTime
-		err = txn.QueryRowContext(r.Context(), objectExistsSQL, viewPath).Scan(&deletedAt)
+		err = txn.QueryRowContext(r.Context(), objectExistsSQL, opts.Bucket.ID, viewPath).Scan(&deletedAt)
 		if errors.Is(err, sql.ErrNoRows) {
-			http.Redirect(w, r, "/reload?prefix="+viewPath, http.StatusFound)
+			http.Redirect(w, r, handlers.ReloadURL(opts.Bucket.Name, viewPath), http.StatusFound)
 			return
 		}
 		if err != nil {
 			_, err = w.Write([]byte(err.Error()))
-			if err != nil && opts.LoggerError != nil {
-				opts.LoggerError.Println(fmt.Errorf("failed to check object exists: %s", err))
+			if err != nil {
+				opts.Logger.ErrorContext(r.Context(), "failed to check object exists", logging.Err(err))
 			}
 		}
 
 		if deletedAt.Valid {
-			objectUndeleteSQL := `update objects set deleted_at = NULL where key = $1`
-			_, err = txn.ExecContext(r.Context(), objectUndeleteSQL, viewPath)
+			objectUndeleteSQL := `update objects set deleted_at = NULL where bucket_id = $1 and key = $2`
+			_, err = txn.ExecContext(r.Context(), objectUndeleteSQL, opts.Bucket.ID, viewPath)
 			if err != nil {
 				_, err = w.Write([]byte(err.Error()))
-				if err != nil && opts.LoggerError != nil {
-					opts.LoggerError.Println(fmt.Errorf("failed to undelete object: %s", err))
+				if err != nil {
+					opts.Logger.ErrorContext(r.Context(), "failed to undelete object", logging.Err(err))
 				}
 			}
 
-			opts.LoggerInfo.Println(fmt.Sprintf("undeleted object: %s", viewPath))
+			opts.Logger.InfoContext(r.Context(), "undeleted object", logging.FieldKey, viewPath)
 		}
 
 		blobDetailsSQL := `
@@ -357,13 +383,16 @@ select
   last_modified,
   md5,
   content_types.name,
-  row_to_json(blob_metadata)::jsonb - 'id' - 'blob_id' as metadata_json
+  (
+    select jsonb_object_agg(processor, result)
+    from blob_metadata
+    where blob_metadata.blob_id = blobs.id
+  ) as metadata_json
 from objects
 left join object_blobs on objects.id = object_blobs.object_id
 left join blobs on blobs.id = object_blobs.blob_id
 left join content_types on blobs.content_type_id = content_types.id
-left join blob_metadata on blobs.id = blob_metadata.blob_id
-where key = $1`
+where objects.bucket_id = $1 and key = $2`
 		var size, id int64
 		var lastModified time.Time
 		var md5, contentType string
@@ -371,14 +400,15 @@ where key = $1`
 		err = txn.QueryRowContext(
 			r.Context(),
 			blobDetailsSQL,
+			opts.Bucket.ID,
 			viewPath,
 		).Scan(&id, &size, &lastModified, &md5, &contentType, &metaJSON)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 
 			_, err = w.Write([]byte(err.Error()))
-			if err != nil && opts.LoggerError != nil {
-				opts.LoggerError.Println(fmt.Errorf("failed to get blob details: %s", err))
+			if err != nil {
+				opts.Logger.ErrorContext(r.Context(), "failed to get blob details", logging.Err(err))
 			}
 
 			return
@@ -389,7 +419,7 @@ where key = $1`
 			err = json.Unmarshal(metaJSON, &metaData)
 			if err != nil {
 				w.WriteHeader(http.StatusInternalServerError)
-				opts.LoggerError.Println(fmt.Errorf("failed to unmarshal metadata: %s", err))
+				opts.Logger.ErrorContext(r.Context(), "failed to unmarshal metadata", logging.Err(err))
 				return
 			}
 		}
@@ -409,7 +439,7 @@ order by source, property_type;
 		)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
-			opts.LoggerError.Println(fmt.Errorf("failed to query properties: %s", err))
+			opts.Logger.ErrorContext(r.Context(), "failed to query properties", logging.Err(err))
 		}
 
 		for rows.Next() {
@@ -418,23 +448,97 @@ order by source, property_type;
 			err = rows.Scan(&prop.BlobID, &prop.PropertySource, &prop.PropertyType, &prop.ValueType, &prop.ValueBool, &prop.ValueNumerator, &prop.ValueDenominator, &prop.ValueText, &prop.ValueInteger, &prop.ValueFloat, &prop.ValueTimestamp, &prop.ValueTimestamptz)
 			if err != nil {
 				w.WriteHeader(http.StatusInternalServerError)
-				opts.LoggerError.Println(fmt.Errorf("failed to scan properties: %s", err))
+				opts.Logger.ErrorContext(r.Context(), "failed to scan properties", logging.Err(err))
 			}
 
 			props = append(props, prop)
 		}
 
+		processingErrors, err := handlers.ListProcessingErrors(r.Context(), txn, id)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			opts.Logger.ErrorContext(r.Context(), "failed to list processing errors", logging.Err(err))
+			return
+		}
+
 		previewableContentTypes := []string{
 			"image/png",
 			"image/jpeg",
 		}
 
+		var tp *textPreview
+		if kind := textPreviewKind(contentType, objectPath); kind != "" {
+			limit := textPreviewLimit(r.URL.Query().Get("limit"))
+
+			getOpts := minio.GetObjectOptions{}
+			truncated := stat.Size > limit
+			if truncated {
+				err = getOpts.SetRange(0, limit-1)
+				if err != nil {
+					w.WriteHeader(http.StatusInternalServerError)
+					opts.Logger.ErrorContext(r.Context(), "failed to set range", logging.Err(err))
+					return
+				}
+			}
+
+			obj, err := mc.GetObject(r.Context(), opts.Bucket.BucketName, objectPath, getOpts)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				opts.Logger.ErrorContext(r.Context(), "failed to get object", logging.Err(err))
+				return
+			}
+			defer obj.Close()
+
+			content, err := io.ReadAll(io.LimitReader(obj, limit))
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				opts.Logger.ErrorContext(r.Context(), "failed to read object", logging.Err(err))
+				return
+			}
+
+			tp, err = buildTextPreview(kind, objectPath, content, truncated, limit)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				opts.Logger.ErrorContext(r.Context(), "failed to build text preview", logging.Err(err))
+				return
+			}
+		}
+
+		var archiveEntries []archiveEntry
+		if format := archive.FormatFor(contentType, objectPath); format != "" {
+			archiveEntries, err = loadArchiveListing(r.Context(), opts, mc, objectPath, stat, format)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				opts.Logger.ErrorContext(r.Context(), "failed to load archive listing", logging.Err(err))
+				return
+			}
+		}
+
+		var ocrText string
+		if metaData["ocr"] == "success" {
+			ocrText, err = loadOCRText(r.Context(), opts, mc, md5)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				opts.Logger.ErrorContext(r.Context(), "failed to load ocr text", logging.Err(err))
+				return
+			}
+		}
+
 		dir := filepath.Dir(viewPath)
 
 		if dir == "." {
 			dir = "/"
 		}
 
+		// images with thumbnails are shown using the pre-generated renditions
+		var thumbSrc, thumbSrcset string
+		if metaData["thumbnail"] == "success" && len(opts.ThumbnailSizes) > 0 {
+			u := assetURL(path.Join(opts.Bucket.URL(), dir)+"/", filepath.Base(objectPath))
+			thumbSrc = thumbnailURL(u, md5, slices.Max(opts.ThumbnailSizes))
+			thumbSrcset = thumbnailSrcset(u, md5, opts.ThumbnailSizes)
+		}
+
+		_, span := tracing.Start(r.Context(), "render template", attribute.String("template", "preview"))
 		err = tmpl.ExecuteTemplate(buf, "base", struct {
 			Opts                   *handlers.Options
 			Breadcrumbs            breadcrumbs
@@ -447,6 +551,13 @@ order by source, property_type;
 			Size                   string
 			Metadata               map[string]string
 			Properties             []properties.BlobProperties
+			TextPreview            *textPreview
+			ArchiveEntries         []archiveEntry
+			ThumbSrc               string
+			ThumbSrcset            string
+			OCRText                string
+			ProcessingErrors       []handlers.ProcessingError
+			ReloadURL              string
 		}{
 			Opts:                   opts,
 			Breadcrumbs:            breadcrumbsFromPath(viewPath),
@@ -459,13 +570,21 @@ order by source, property_type;
 			Size:                   humanizeBytes(size),
 			Metadata:               metaData,
 			Properties:             props,
+			TextPreview:            tp,
+			ArchiveEntries:         archiveEntries,
+			ThumbSrc:               thumbSrc,
+			ThumbSrcset:            thumbSrcset,
+			OCRText:                ocrText,
+			ProcessingErrors:       processingErrors,
+			ReloadURL:              handlers.ReloadURL(opts.Bucket.Name, viewPath),
 		})
+		tracing.End(span, err)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 
 			_, err = w.Write([]byte(err.Error()))
-			if err != nil && opts.LoggerError != nil {
-				opts.LoggerError.Println(fmt.Errorf("failed to execute template: %s", err))
+			if err != nil {
+				opts.Logger.ErrorContext(r.Context(), "failed to execute template", logging.Err(err))
 			}
 
 			return
@@ -476,8 +595,8 @@ order by source, property_type;
 			w.WriteHeader(http.StatusInternalServerError)
 
 			_, err = w.Write([]byte(err.Error()))
-			if err != nil && opts.LoggerError != nil {
-				opts.LoggerError.Println(fmt.Errorf("failed to copy buffer to response: %s", err))
+			if err != nil {
+				opts.Logger.ErrorContext(r.Context(), "failed to copy buffer to response", logging.Err(err))
 			}
 
 			return
@@ -487,9 +606,9 @@ order by source, property_type;
 
 func renderDir(opts *handlers.Options, mc *minio.Client, tmpl *template.Template) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		viewPath := strings.TrimPrefix(r.URL.Path, "/b/")
+		viewPath := strings.TrimPrefix(r.URL.Path, opts.Bucket.URL()+"/")
 
-		p := path.Join(dataPath, viewPath)
+		p := opts.Bucket.DataKey(viewPath)
 		// a trailing / is required for path prefix listing,
 		// unless we are listing the root
 		if !strings.HasSuffix(p, "/") && p != "" {
@@ -504,7 +623,7 @@ func renderDir(opts *handlers.Options, mc *minio.Client, tmpl *template.Template
 
 		for obj := range mc.ListObjects(
 			r.Context(),
-			opts.BucketName,
+			opts.Bucket.BucketName,
 			minio.ListObjectsOptions{
 				Prefix:    p,
 				Recursive: false,
@@ -514,7 +633,7 @@ func renderDir(opts *handlers.Options, mc *minio.Client, tmpl *template.Template
 				continue
 			}
 
-			key := strings.TrimPrefix(obj.Key, dataPath)
+			key := opts.Bucket.ObjectKey(obj.Key)
 
 			orderedKeys = append(orderedKeys, key)
 			isDir := strings.HasSuffix(key, "/")
@@ -541,12 +660,12 @@ func renderDir(opts *handlers.Options, mc *minio.Client, tmpl *template.Template
 			}
 		}
 
-		txn, err := database.NewTxnWithSchema(opts.DB, "storage_console")
+		txn, err := database.NewTxnWithSchemaContext(r.Context(), opts.DB, opts.SchemaName)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			_, err = w.Write([]byte(err.Error()))
-			if err != nil && opts.LoggerError != nil {
-				opts.LoggerError.Println(fmt.Errorf("failed to create transaction: %s", err))
+			if err != nil {
+				opts.Logger.ErrorContext(r.Context(), "failed to create transaction", logging.Err(err))
 			}
 			return
 		}
@@ -556,7 +675,7 @@ func renderDir(opts *handlers.Options, mc *minio.Client, tmpl *template.Template
 		if len(keys) > 0 {
 			var placeholders string
 			for i := range keys {
-				placeholders += fmt.Sprintf("$%d", i+1)
+				placeholders += fmt.Sprintf("$%d", i+2)
 				if i < len(keys)-1 {
 					placeholders += ", "
 				}
@@ -568,21 +687,26 @@ SELECT
 	size,
 	md5,
 	content_types.name AS content_type,
-	COALESCE(blob_metadata.thumbnail = 'success', FALSE) as has_thumb
+	EXISTS (
+		SELECT 1
+		FROM blob_metadata
+		WHERE blob_metadata.blob_id = blobs.id
+		AND blob_metadata.processor = 'thumbnail'
+		AND blob_metadata.result = 'success'
+	) as has_thumb
 FROM objects
 LEFT JOIN object_blobs ON object_blobs.object_id = objects.id
 LEFT JOIN blobs ON object_blobs.blob_id = blobs.id
 LEFT JOIN content_types ON blobs.content_type_id = content_types.id
-LEFT JOIN blob_metadata ON blobs.id = blob_metadata.blob_id
-WHERE key IN (%s)`, placeholders)
+WHERE objects.bucket_id = $1 AND key IN (%s)`, placeholders)
 
-			rows, err := txn.Query(loadMetadataSQL, keys...)
+			rows, err := txn.QueryContext(r.Context(), loadMetadataSQL, append([]interface{}{opts.Bucket.ID}, keys...)...)
 			if err != nil {
 				w.WriteHeader(http.StatusInternalServerError)
 
 				_, err = w.Write([]byte(err.Error()))
-				if err != nil && opts.LoggerError != nil {
-					opts.LoggerError.Println(fmt.Errorf("failed to load metadata: %s", err))
+				if err != nil {
+					opts.Logger.ErrorContext(r.Context(), "failed to load metadata", logging.Err(err))
 				}
 
 				return
@@ -600,8 +724,8 @@ WHERE key IN (%s)`, placeholders)
 					w.WriteHeader(http.StatusInternalServerError)
 
 					_, err = w.Write([]byte(err.Error()))
-					if err != nil && opts.LoggerError != nil {
-						opts.LoggerError.Println(fmt.Errorf("failed to scan metadata: %s", err))
+					if err != nil {
+						opts.Logger.ErrorContext(r.Context(), "failed to scan metadata", logging.Err(err))
 					}
 
 					return
@@ -612,6 +736,12 @@ WHERE key IN (%s)`, placeholders)
 					e.ContentType = contentType
 					e.Size = humanizeBytes(size)
 					e.HasThumb = hasThumb
+
+					if hasThumb && len(opts.ThumbnailSizes) > 0 {
+						u := assetURL(r.URL.Path, e.Name)
+						e.ThumbSrc = thumbnailURL(u, md5, slices.Min(opts.ThumbnailSizes))
+						e.ThumbSrcset = thumbnailSrcset(u, md5, opts.ThumbnailSizes)
+					}
 				}
 			}
 		}
@@ -625,7 +755,7 @@ WHERE key IN (%s)`, placeholders)
 		if len(dirSizeArgs) > 0 {
 			var sb strings.Builder
 			for i := range dirSizeArgs {
-				sb.WriteString(fmt.Sprintf("WHEN key ILIKE $%d || '%%' THEN $%d\n", i+1, i+1))
+				sb.WriteString(fmt.Sprintf("WHEN key ILIKE $%d || '%%' THEN $%d\n", i+2, i+2))
 			}
 
 			dirSizeSQL := fmt.Sprintf(`
@@ -638,15 +768,16 @@ select
 left join object_blobs ON object_blobs.object_id = objects.id
 left join blobs ON object_blobs.blob_id = blobs.id
 left join content_types ON blobs.content_type_id = content_types.id
+where objects.bucket_id = $1
 group by dir`, sb.String())
 
-			dirSizeRows, err := txn.Query(dirSizeSQL, dirSizeArgs...)
+			dirSizeRows, err := txn.QueryContext(r.Context(), dirSizeSQL, append([]interface{}{opts.Bucket.ID}, dirSizeArgs...)...)
 			if err != nil {
 				w.WriteHeader(http.StatusInternalServerError)
 
 				_, err = w.Write([]byte(err.Error()))
-				if err != nil && opts.LoggerError != nil {
-					opts.LoggerError.Println(err)
+				if err != nil {
+					opts.Logger.ErrorContext(r.Context(), "failed to write response", logging.Err(err))
 				}
 
 				return
@@ -660,8 +791,8 @@ group by dir`, sb.String())
 					w.WriteHeader(http.StatusInternalServerError)
 
 					_, err = w.Write([]byte(err.Error()))
-					if err != nil && opts.LoggerError != nil {
-						opts.LoggerError.Println(fmt.Errorf("failed to scan dir size: %s", err))
+					if err != nil {
+						opts.Logger.ErrorContext(r.Context(), "failed to scan dir size", logging.Err(err))
 					}
 
 					return
@@ -675,6 +806,7 @@ group by dir`, sb.String())
 
 		buf := bytes.NewBuffer([]byte{})
 
+		_, span := tracing.Start(r.Context(), "render template", attribute.String("template", "dir"))
 		err = tmpl.ExecuteTemplate(buf, "base", struct {
 			Opts        *handlers.Options
 			Path        string
@@ -686,12 +818,13 @@ group by dir`, sb.String())
 			Entries:     entryList,
 			Breadcrumbs: breadcrumbsFromPath(viewPath),
 		})
+		tracing.End(span, err)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 
 			_, err = w.Write([]byte(err.Error()))
-			if err != nil && opts.LoggerError != nil {
-				opts.LoggerError.Println(fmt.Errorf("failed to execute template: %s", err))
+			if err != nil {
+				opts.Logger.ErrorContext(r.Context(), "failed to execute template", logging.Err(err))
 			}
 
 			return
@@ -702,8 +835,8 @@ group by dir`, sb.String())
 			w.WriteHeader(http.StatusInternalServerError)
 
 			_, err = w.Write([]byte(err.Error()))
-			if err != nil && opts.LoggerError != nil {
-				opts.LoggerError.Println(fmt.Errorf("failed to copy buffer to response: %s", err))
+			if err != nil {
+				opts.Logger.ErrorContext(r.Context(), "failed to copy buffer to response", logging.Err(err))
 			}
 
 			return
@@ -0,0 +1,192 @@
+package browse
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/charlieegan3/storage-console/pkg/database"
+	"github.com/charlieegan3/storage-console/pkg/logging"
+	"github.com/charlieegan3/storage-console/pkg/properties"
+	"github.com/charlieegan3/storage-console/pkg/server/handlers"
+)
+
+// paletteEntry is a palette color and the number of objects in which it's
+// one of the main colors
+type paletteEntry struct {
+	Name  string
+	RGB   string
+	Count int
+	Link  string
+}
+
+func colorURL(name string) string {
+	return "/colors?" + url.Values{"name": {name}}.Encode()
+}
+
+// BuildColorsHandler lists the palette colors, or the objects in which the
+// selected color is most prominent
+func BuildColorsHandler(opts *handlers.Options) (func(http.ResponseWriter, *http.Request), error) {
+	if opts.DB == nil {
+		return nil, fmt.Errorf("DB is required")
+	}
+
+	tmpl, err := template.ParseFS(
+		handlers.Templates,
+		"templates/colors.html",
+		"templates/results.html",
+		"templates/base.html",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse colors templates: %s", err)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimSpace(r.URL.Query().Get("name"))
+
+		var selected *paletteEntry
+		if name != "" {
+			c, ok := properties.PaletteColor(name)
+			if !ok {
+				http.Error(w, "unknown color", http.StatusNotFound)
+				return
+			}
+
+			selected = &paletteEntry{Name: name, RGB: c.RGB(), Link: colorURL(name)}
+		}
+
+		txn, err := database.NewTxnWithSchemaContext(r.Context(), opts.DB, opts.SchemaName)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			opts.Logger.ErrorContext(r.Context(), "failed to create transaction", logging.Err(err))
+			return
+		}
+		defer txn.Rollback()
+
+		countsSQL := `
+select category.value_text, count(distinct objects.id)
+from blob_properties category
+join object_blobs on object_blobs.blob_id = category.blob_id
+join objects on objects.id = object_blobs.object_id
+where
+  objects.deleted_at is null
+  and category.source = 'color'
+  and category.property_type like 'ColorCategory_'
+group by category.value_text`
+
+		rows, err := txn.QueryContext(r.Context(), countsSQL)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			opts.Logger.ErrorContext(r.Context(), "failed to count colors", logging.Err(err))
+			return
+		}
+
+		counts := make(map[string]int)
+		for rows.Next() {
+			var name string
+			var count int
+			err = rows.Scan(&name, &count)
+			if err != nil {
+				rows.Close()
+				w.WriteHeader(http.StatusInternalServerError)
+				opts.Logger.ErrorContext(r.Context(), "failed to scan color count", logging.Err(err))
+				return
+			}
+
+			counts[name] = count
+		}
+		rows.Close()
+
+		var palette []paletteEntry
+		for _, c := range properties.Palette {
+			palette = append(palette, paletteEntry{
+				Name:  c.Name,
+				RGB:   c.Color.RGB(),
+				Count: counts[c.Name],
+				Link:  colorURL(c.Name),
+			})
+		}
+
+		var results []searchResult
+		if selected != nil {
+			selected.Count = counts[selected.Name]
+
+			// objects are ordered by the share of the image in the color
+			resultsSQL := `
+select
+  coalesce(buckets.name, ''),
+  objects.key,
+  blobs.md5,
+  blobs.size,
+  coalesce(content_types.name, ''),
+  exists (
+    select 1
+    from blob_metadata
+    where blob_metadata.blob_id = blobs.id
+    and blob_metadata.processor = 'thumbnail'
+    and blob_metadata.result = 'success'
+  ) as has_thumb
+from blob_properties category
+join blob_properties weight
+  on weight.blob_id = category.blob_id
+  and weight.source = 'color'
+  and weight.property_type = category.property_type || 'Weight'
+join blobs on blobs.id = category.blob_id
+join object_blobs on object_blobs.blob_id = blobs.id
+join objects on objects.id = object_blobs.object_id
+left join buckets on buckets.id = objects.bucket_id
+left join content_types on content_types.id = blobs.content_type_id
+where
+  objects.deleted_at is null
+  and category.source = 'color'
+  and category.property_type like 'ColorCategory_'
+  and category.value_text = $1
+order by weight.value_float desc, objects.key
+limit $2`
+
+			rows, err := txn.QueryContext(r.Context(), resultsSQL, selected.Name, searchResultsLimit)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				opts.Logger.ErrorContext(r.Context(), "failed to list objects by color", logging.Err(err))
+				return
+			}
+
+			results, err = scanSearchResults(opts, rows)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				opts.Logger.ErrorContext(r.Context(), "failed to read objects by color", logging.Err(err))
+				return
+			}
+		}
+
+		buf := bytes.NewBuffer([]byte{})
+
+		err = tmpl.ExecuteTemplate(buf, "base", struct {
+			Opts     *handlers.Options
+			Palette  []paletteEntry
+			Selected *paletteEntry
+			Results  []searchResult
+			Limit    int
+		}{
+			Opts:     opts,
+			Palette:  palette,
+			Selected: selected,
+			Results:  results,
+			Limit:    searchResultsLimit,
+		})
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			opts.Logger.ErrorContext(r.Context(), "failed to execute template", logging.Err(err))
+			return
+		}
+
+		_, err = io.Copy(w, buf)
+		if err != nil {
+			opts.Logger.ErrorContext(r.Context(), "failed to copy buffer to response", logging.Err(err))
+		}
+	}, nil
+}
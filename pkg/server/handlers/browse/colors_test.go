@@ -0,0 +1,21 @@
+package browse
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestColorURL(t *testing.T) {
+	u, err := url.Parse(colorURL("sky blue"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if exp, got := "/colors", u.Path; exp != got {
+		t.Fatalf("expected path %s, got %s", exp, got)
+	}
+
+	if exp, got := "sky blue", u.Query().Get("name"); exp != got {
+		t.Fatalf("expected name %q, got %q", exp, got)
+	}
+}
@@ -0,0 +1,31 @@
+package browse
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/charlieegan3/storage-console/pkg/meta"
+	"github.com/charlieegan3/storage-console/pkg/server/handlers"
+)
+
+// loadOCRText returns the text recognised in a blob by the ocr processor,
+// long documents are cut off at the text preview limit
+func loadOCRText(ctx context.Context, opts *handlers.Options, mc *minio.Client, md5 string) (string, error) {
+	p := opts.Bucket.MetaKey("ocr", md5+"."+meta.ContentTypeToFileExt(meta.TEXT))
+
+	obj, err := mc.GetObject(ctx, opts.Bucket.MetaBucketName(), p, minio.GetObjectOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get ocr text: %w", err)
+	}
+	defer obj.Close()
+
+	bs, err := io.ReadAll(io.LimitReader(obj, textPreviewDefaultLimit))
+	if err != nil {
+		return "", fmt.Errorf("failed to read ocr text: %w", err)
+	}
+
+	return string(bs), nil
+}
@@ -0,0 +1,202 @@
+package browse
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/charlieegan3/storage-console/pkg/database"
+	"github.com/charlieegan3/storage-console/pkg/logging"
+	"github.com/charlieegan3/storage-console/pkg/server/handlers"
+)
+
+// place is a country, or a city within one, and the number of objects
+// found there
+type place struct {
+	Name   string
+	Code   string
+	Region string
+	Count  int
+	Link   string
+}
+
+// placeSearchURL links to the objects with the given places property,
+// within the country and region when they're set
+func placeSearchURL(propertyType, value, countryCode, region string) string {
+	q := url.Values{
+		"source": {"places"},
+		"type":   {propertyType},
+		"value":  {value},
+	}
+
+	if countryCode != "" {
+		q.Set("country", countryCode)
+	}
+
+	if region != "" {
+		q.Set("region", region)
+	}
+
+	return "/search?" + q.Encode()
+}
+
+// BuildPlacesHandler lists the countries where photos were taken, or the
+// cities within a country when one is selected
+func BuildPlacesHandler(opts *handlers.Options) (func(http.ResponseWriter, *http.Request), error) {
+	if opts.DB == nil {
+		return nil, fmt.Errorf("DB is required")
+	}
+
+	tmpl, err := template.ParseFS(
+		handlers.Templates,
+		"templates/places.html",
+		"templates/base.html",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse places templates: %s", err)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		countryCode := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("country")))
+
+		txn, err := database.NewTxnWithSchemaContext(r.Context(), opts.DB, opts.SchemaName)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			opts.Logger.ErrorContext(r.Context(), "failed to create transaction", logging.Err(err))
+			return
+		}
+		defer txn.Rollback()
+
+		var country *place
+		var places []place
+		if countryCode == "" {
+			places, err = listCountries(r, txn)
+		} else {
+			country, places, err = listCities(r, txn, countryCode)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			opts.Logger.ErrorContext(r.Context(), "failed to list places", logging.Err(err))
+			return
+		}
+
+		buf := bytes.NewBuffer([]byte{})
+
+		err = tmpl.ExecuteTemplate(buf, "base", struct {
+			Opts    *handlers.Options
+			Country *place
+			Places  []place
+		}{
+			Opts:    opts,
+			Country: country,
+			Places:  places,
+		})
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			opts.Logger.ErrorContext(r.Context(), "failed to execute template", logging.Err(err))
+			return
+		}
+
+		_, err = io.Copy(w, buf)
+		if err != nil {
+			opts.Logger.ErrorContext(r.Context(), "failed to copy buffer to response", logging.Err(err))
+		}
+	}, nil
+}
+
+func listCountries(r *http.Request, txn *sql.Tx) ([]place, error) {
+	countriesSQL := `
+select country.value_text, code.value_text, count(distinct objects.id)
+from blob_properties country
+join blob_properties code
+  on code.blob_id = country.blob_id
+  and code.source = 'places'
+  and code.property_type = 'CountryCode'
+join object_blobs on object_blobs.blob_id = country.blob_id
+join objects on objects.id = object_blobs.object_id
+where
+  objects.deleted_at is null
+  and country.source = 'places'
+  and country.property_type = 'Country'
+group by country.value_text, code.value_text
+order by count(distinct objects.id) desc, country.value_text`
+
+	rows, err := txn.QueryContext(r.Context(), countriesSQL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query countries: %s", err)
+	}
+	defer rows.Close()
+
+	var countries []place
+	for rows.Next() {
+		var p place
+		err = rows.Scan(&p.Name, &p.Code, &p.Count)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan country: %s", err)
+		}
+
+		p.Link = "/places?" + url.Values{"country": {p.Code}}.Encode()
+		countries = append(countries, p)
+	}
+
+	return countries, rows.Err()
+}
+
+func listCities(r *http.Request, txn *sql.Tx, countryCode string) (*place, []place, error) {
+	citiesSQL := `
+select
+  country.value_text,
+  coalesce(region.value_text, ''),
+  city.value_text,
+  count(distinct objects.id)
+from blob_properties city
+join blob_properties code
+  on code.blob_id = city.blob_id
+  and code.source = 'places'
+  and code.property_type = 'CountryCode'
+join blob_properties country
+  on country.blob_id = city.blob_id
+  and country.source = 'places'
+  and country.property_type = 'Country'
+left join blob_properties region
+  on region.blob_id = city.blob_id
+  and region.source = 'places'
+  and region.property_type = 'Region'
+join object_blobs on object_blobs.blob_id = city.blob_id
+join objects on objects.id = object_blobs.object_id
+where
+  objects.deleted_at is null
+  and city.source = 'places'
+  and city.property_type = 'City'
+  and code.value_text = $1
+group by country.value_text, region.value_text, city.value_text
+order by region.value_text, city.value_text`
+
+	rows, err := txn.QueryContext(r.Context(), citiesSQL, countryCode)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query cities: %s", err)
+	}
+	defer rows.Close()
+
+	country := &place{Code: countryCode, Name: countryCode, Link: placeSearchURL("CountryCode", countryCode, "", "")}
+
+	var cities []place
+	for rows.Next() {
+		var p place
+		err = rows.Scan(&country.Name, &p.Region, &p.Name, &p.Count)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to scan city: %s", err)
+		}
+
+		p.Link = placeSearchURL("City", p.Name, countryCode, p.Region)
+		country.Count += p.Count
+		cities = append(cities, p)
+	}
+
+	return country, cities, rows.Err()
+}
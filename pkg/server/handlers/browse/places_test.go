@@ -0,0 +1,25 @@
+package browse
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestPlaceSearchURL(t *testing.T) {
+	u, err := url.Parse(placeSearchURL("City", "Portland", "US", "Oregon"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := searchFilter{
+		Source:  "places",
+		Type:    "City",
+		Value:   "Portland",
+		Country: "US",
+		Region:  "Oregon",
+	}
+
+	if got := searchFilterFromQuery(u.Query()); got != expected {
+		t.Fatalf("expected %+v, got %+v", expected, got)
+	}
+}
@@ -0,0 +1,44 @@
+package browse
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/charlieegan3/storage-console/pkg/meta/thumbnail"
+	"github.com/charlieegan3/storage-console/pkg/server/handlers"
+)
+
+// previewMinBytes is the size over which images are shown using their
+// largest thumbnail rendition
+const previewMinBytes = 1024 * 1024
+
+// previewETag returns the ETag of the rendition shown in place of an object,
+// it changes when the rendition is regenerated, such as for a new quality
+func previewETag(sourceETag, renditionETag string) string {
+	return fmt.Sprintf("%s-preview-%s", sourceETag, renditionETag)
+}
+
+// statPreview finds the rendition of the given size generated by the
+// thumbnail processor for an object, ok is false when the object hasn't
+// been processed yet
+func statPreview(
+	ctx context.Context,
+	opts *handlers.Options,
+	mc *minio.Client,
+	sourceETag string,
+	size int,
+) (previewPath string, stat minio.ObjectInfo, ok bool, err error) {
+	previewPath = opts.Bucket.MetaKey(thumbnail.Path(size, sourceETag, opts.ThumbnailFormat))
+
+	stat, err = mc.StatObject(ctx, opts.Bucket.MetaBucketName(), previewPath, minio.StatObjectOptions{})
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return previewPath, stat, false, nil
+	}
+	if err != nil {
+		return previewPath, stat, false, fmt.Errorf("failed to stat preview: %w", err)
+	}
+
+	return previewPath, stat, true, nil
+}
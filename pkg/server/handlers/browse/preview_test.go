@@ -0,0 +1,213 @@
+package browse
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"github.com/charlieegan3/storage-console/pkg/logging"
+	"github.com/charlieegan3/storage-console/pkg/meta"
+	"github.com/charlieegan3/storage-console/pkg/server/handlers"
+)
+
+type fakeObject struct {
+	content     []byte
+	contentType string
+	etag        string
+}
+
+// newFakeS3 serves HEAD and GET requests for the objects, keyed by
+// <bucket>/<key>, in the way minio-go expects for path style requests
+func newFakeS3(t *testing.T, objects map[string]fakeObject) *minio.Client {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		obj, ok := objects[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			if r.Method != http.MethodHead {
+				fmt.Fprintf(w, "<Error><Code>NoSuchKey</Code><Key>%s</Key></Error>", r.URL.Path)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", obj.contentType)
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(obj.content)))
+		w.Header().Set("ETag", `"`+obj.etag+`"`)
+		w.Header().Set("Last-Modified", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Format(http.TimeFormat))
+
+		if r.Method != http.MethodHead {
+			_, _ = w.Write(obj.content)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	mc, err := minio.New(u.Host, &minio.Options{
+		Creds:        credentials.NewStaticV4("access", "secret", ""),
+		Region:       "us-east-1",
+		BucketLookup: minio.BucketLookupPath,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	return mc
+}
+
+func TestRenderObjectPreview(t *testing.T) {
+	t.Parallel()
+
+	original := fakeObject{
+		content:     bytes.Repeat([]byte{0xff}, previewMinBytes+1),
+		contentType: "image/jpeg",
+		etag:        "abc",
+	}
+	rendition := fakeObject{
+		content:     []byte("webp"),
+		contentType: "image/webp",
+		etag:        "def",
+	}
+
+	testCases := map[string]struct {
+		objects         map[string]fakeObject
+		thumbPath       string
+		ifNoneMatch     string
+		expectedCode    int
+		expectedETag    string
+		expectedType    string
+		expectedCache   string
+		expectedContent []byte
+	}{
+		"largest rendition": {
+			objects: map[string]fakeObject{
+				"photos/data/a.jpg":                   original,
+				"photos/meta/thumbnail/1200/abc.webp": rendition,
+				"photos/meta/thumbnail/300/abc.webp":  {content: []byte("small"), contentType: "image/webp"},
+			},
+			expectedCode:    http.StatusOK,
+			expectedETag:    "abc-preview-def",
+			expectedType:    "image/webp",
+			expectedCache:   "no-cache",
+			expectedContent: rendition.content,
+		},
+		"not modified": {
+			objects: map[string]fakeObject{
+				"photos/data/a.jpg":                   original,
+				"photos/meta/thumbnail/1200/abc.webp": rendition,
+			},
+			ifNoneMatch:  "abc-preview-def",
+			expectedCode: http.StatusNotModified,
+		},
+		"regenerated rendition": {
+			objects: map[string]fakeObject{
+				"photos/data/a.jpg":                   original,
+				"photos/meta/thumbnail/1200/abc.webp": rendition,
+			},
+			ifNoneMatch:     "abc-preview-old",
+			expectedCode:    http.StatusOK,
+			expectedETag:    "abc-preview-def",
+			expectedType:    "image/webp",
+			expectedCache:   "no-cache",
+			expectedContent: rendition.content,
+		},
+		"original until the rendition is generated": {
+			objects: map[string]fakeObject{
+				"photos/data/a.jpg": original,
+			},
+			expectedCode:    http.StatusOK,
+			expectedETag:    "abc",
+			expectedType:    "image/jpeg",
+			expectedCache:   "no-cache",
+			expectedContent: original.content,
+		},
+		"original not modified until the rendition is generated": {
+			objects: map[string]fakeObject{
+				"photos/data/a.jpg": original,
+			},
+			ifNoneMatch:  "abc",
+			expectedCode: http.StatusNotModified,
+		},
+		"cached original once the rendition is generated": {
+			objects: map[string]fakeObject{
+				"photos/data/a.jpg":                   original,
+				"photos/meta/thumbnail/1200/abc.webp": rendition,
+			},
+			ifNoneMatch:     "abc",
+			expectedCode:    http.StatusOK,
+			expectedETag:    "abc-preview-def",
+			expectedType:    "image/webp",
+			expectedCache:   "no-cache",
+			expectedContent: rendition.content,
+		},
+		"thumbnail": {
+			objects: map[string]fakeObject{
+				"photos/meta/thumbnail/1200/abc.webp": rendition,
+			},
+			thumbPath:       "thumbnail/1200/abc.webp",
+			expectedCode:    http.StatusOK,
+			expectedETag:    "def",
+			expectedType:    "image/webp",
+			expectedCache:   "public, max-age=31536000, immutable",
+			expectedContent: rendition.content,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			opts := &handlers.Options{
+				Logger:          logging.Discard(),
+				ThumbnailSizes:  []int{300, 1200},
+				ThumbnailFormat: meta.WEBP,
+				Bucket:          &handlers.Bucket{Name: "photos", BucketName: "photos"},
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/b/photos/a.jpg?asset=a.jpg", nil)
+			if tc.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tc.ifNoneMatch)
+			}
+
+			rec := httptest.NewRecorder()
+			renderObject(opts, newFakeS3(t, tc.objects), "a.jpg", false, tc.thumbPath)(rec, req)
+
+			if rec.Code != tc.expectedCode {
+				t.Fatalf("expected status code %d, got %d: %s", tc.expectedCode, rec.Code, rec.Body.String())
+			}
+
+			if tc.expectedCode != http.StatusOK {
+				return
+			}
+
+			if got := rec.Header().Get("ETag"); got != tc.expectedETag {
+				t.Fatalf("expected etag %q, got %q", tc.expectedETag, got)
+			}
+
+			if got := rec.Header().Get("Cache-Control"); got != tc.expectedCache {
+				t.Fatalf("expected cache control %q, got %q", tc.expectedCache, got)
+			}
+
+			if got := rec.Header().Get("Content-Type"); got != tc.expectedType {
+				t.Fatalf("expected content type %q, got %q", tc.expectedType, got)
+			}
+
+			if !bytes.Equal(rec.Body.Bytes(), tc.expectedContent) {
+				t.Fatalf("unexpected content of %d bytes", rec.Body.Len())
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package browse
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/charlieegan3/storage-console/pkg/database"
+	"github.com/charlieegan3/storage-console/pkg/logging"
+	"github.com/charlieegan3/storage-console/pkg/server/handlers"
+)
+
+const searchResultsLimit = 200
+
+// searchResultsSQL selects the columns read by scanSearchResults for blobs
+// with matching properties
+const searchResultsSQL = `
+select distinct
+  coalesce(buckets.name, ''),
+  objects.key,
+  blobs.md5,
+  blobs.size,
+  coalesce(content_types.name, ''),
+  exists (
+    select 1
+    from blob_metadata
+    where blob_metadata.blob_id = blobs.id
+    and blob_metadata.processor = 'thumbnail'
+    and blob_metadata.result = 'success'
+  ) as has_thumb
+from blob_properties
+join blobs on blobs.id = blob_properties.blob_id
+join object_blobs on object_blobs.blob_id = blobs.id
+join objects on objects.id = object_blobs.object_id
+left join buckets on buckets.id = objects.bucket_id
+left join content_types on content_types.id = blobs.content_type_id`
+
+const propertySearchSQL = searchResultsSQL + `
+where
+  objects.deleted_at is null
+  and blob_properties.property_type = $1
+  and ($2::text = '' or blob_properties.source = $2)
+  and (
+    $3::text = ''
+    or lower(blob_properties.value_text) = lower($3)
+    or blob_properties.value_integer::text = $3
+    or blob_properties.value_float::text = $3
+    or blob_properties.value_bool::text = lower($3)
+    or (blob_properties.value_denominator = 1 and blob_properties.value_numerator::text = $3)
+    or blob_properties.value_numerator || '/' || blob_properties.value_denominator = $3
+  )
+  and objects.key like $4::text || '%'
+  and ($6::text = '' or exists (
+    select 1
+    from blob_properties country
+    where country.blob_id = blobs.id
+    and country.source = 'places'
+    and country.property_type = 'CountryCode'
+    and country.value_text = $6
+  ))
+  and ($7::text = '' or exists (
+    select 1
+    from blob_properties region
+    where region.blob_id = blobs.id
+    and region.source = 'places'
+    and region.property_type = 'Region'
+    and region.value_text = $7
+  ))
+order by objects.key
+limit $5`
+
+// ocrSearchSQL matches the words in recognised text, the expression and
+// source match the blob_properties_ocr_text_search index
+const ocrSearchSQL = searchResultsSQL + `
+where
+  objects.deleted_at is null
+  and blob_properties.source = 'ocr'
+  and to_tsvector('simple', blob_properties.value_text) @@ plainto_tsquery('simple', $1)
+  and objects.key like $2::text || '%'
+order by objects.key
+limit $3`
+
+// searchFilter selects objects by the value of one of their properties, or
+// by words in the text recognised in them. Country and Region limit the
+// results to photos taken there, so that places with the same name aren't
+// mixed up.
+type searchFilter struct {
+	Source  string
+	Type    string
+	Value   string
+	Text    string
+	Prefix  string
+	Country string
+	Region  string
+}
+
+type searchResult struct {
+	browseEntry
+	Bucket string
+	Link   string
+}
+
+func searchFilterFromQuery(q url.Values) searchFilter {
+	return searchFilter{
+		Source: strings.TrimSpace(q.Get("source")),
+		Type:   strings.TrimSpace(q.Get("type")),
+		Value:  strings.TrimSpace(q.Get("value")),
+		Text:   strings.TrimSpace(q.Get("text")),
+		Prefix: strings.TrimLeft(q.Get("prefix"), "/"),
+
+		Country: strings.ToUpper(strings.TrimSpace(q.Get("country"))),
+		Region:  strings.TrimSpace(q.Get("region")),
+	}
+}
+
+// BuildSearchHandler lists the objects with a matching property, for example
+// all photos with a given EXIF:WhiteBalance or keyword, or with the given
+// words in their OCR text
+func BuildSearchHandler(opts *handlers.Options) (func(http.ResponseWriter, *http.Request), error) {
+	if opts.DB == nil {
+		return nil, fmt.Errorf("DB is required")
+	}
+
+	tmpl, err := template.ParseFS(
+		handlers.Templates,
+		"templates/search.html",
+		"templates/results.html",
+		"templates/base.html",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse search templates: %s", err)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter := searchFilterFromQuery(r.URL.Query())
+
+		txn, err := database.NewTxnWithSchemaContext(r.Context(), opts.DB, opts.SchemaName)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			opts.Logger.ErrorContext(r.Context(), "failed to create transaction", logging.Err(err))
+			return
+		}
+		defer txn.Rollback()
+
+		var query string
+		var args []any
+		switch {
+		case filter.Text != "":
+			query = ocrSearchSQL
+			args = []any{filter.Text, filter.Prefix, searchResultsLimit}
+		case filter.Type != "":
+			query = propertySearchSQL
+			args = []any{
+				filter.Type,
+				filter.Source,
+				filter.Value,
+				filter.Prefix,
+				searchResultsLimit,
+				filter.Country,
+				filter.Region,
+			}
+		}
+
+		var results []searchResult
+		if query != "" {
+			rows, err := txn.QueryContext(r.Context(), query, args...)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				opts.Logger.ErrorContext(r.Context(), "failed to search properties", logging.Err(err))
+				return
+			}
+
+			results, err = scanSearchResults(opts, rows)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				opts.Logger.ErrorContext(r.Context(), "failed to read search results", logging.Err(err))
+				return
+			}
+		}
+
+		buf := bytes.NewBuffer([]byte{})
+
+		err = tmpl.ExecuteTemplate(buf, "base", struct {
+			Opts    *handlers.Options
+			Filter  searchFilter
+			Results []searchResult
+			Limit   int
+		}{
+			Opts:    opts,
+			Filter:  filter,
+			Results: results,
+			Limit:   searchResultsLimit,
+		})
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			opts.Logger.ErrorContext(r.Context(), "failed to execute template", logging.Err(err))
+			return
+		}
+
+		_, err = io.Copy(w, buf)
+		if err != nil {
+			opts.Logger.ErrorContext(r.Context(), "failed to copy buffer to response", logging.Err(err))
+		}
+	}, nil
+}
+
+// scanSearchResults reads rows of bucket, key, md5, size, content type and
+// whether the blob has a thumbnail
+func scanSearchResults(opts *handlers.Options, rows *sql.Rows) ([]searchResult, error) {
+	defer rows.Close()
+
+	var results []searchResult
+	for rows.Next() {
+		var res searchResult
+		var size int64
+
+		err := rows.Scan(&res.Bucket, &res.Key, &res.MD5, &size, &res.ContentType, &res.HasThumb)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %s", err)
+		}
+
+		res.Name = path.Base(res.Key)
+		res.ShortName = shortName(res.Name)
+		res.Size = humanizeBytes(size)
+		res.Link = handlers.PreviewURL(res.Bucket, res.Key)
+
+		if res.HasThumb && len(opts.ThumbnailSizes) > 0 {
+			dir := path.Dir(res.Key)
+			if dir == "." {
+				dir = ""
+			}
+
+			u := assetURL(path.Join("/b", res.Bucket, dir)+"/", res.Name)
+			res.ThumbSrc = thumbnailURL(u, res.MD5, slices.Min(opts.ThumbnailSizes))
+			res.ThumbSrcset = thumbnailSrcset(u, res.MD5, opts.ThumbnailSizes)
+		}
+
+		results = append(results, res)
+	}
+
+	return results, rows.Err()
+}
@@ -0,0 +1,150 @@
+package browse
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/charlieegan3/storage-console/pkg/database"
+	"github.com/charlieegan3/storage-console/pkg/server/handlers"
+	"github.com/charlieegan3/storage-console/pkg/test"
+)
+
+func TestSearchFilterFromQuery(t *testing.T) {
+	q, err := url.ParseQuery("type=+XMP:dc:subject+&value=snow&prefix=/photos/2024&source=tags")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := searchFilter{
+		Source: "tags",
+		Type:   "XMP:dc:subject",
+		Value:  "snow",
+		Prefix: "photos/2024",
+	}
+
+	if got := searchFilterFromQuery(q); got != expected {
+		t.Fatalf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestSearchOCRText(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	db, postgresCleanup, err := test.InitPostgres(ctx, t)
+	defer func() {
+		if postgresCleanup == nil {
+			return
+		}
+		if err := postgresCleanup(); err != nil {
+			t.Fatalf("Could not cleanup postgres: %s", err)
+		}
+	}()
+	if err != nil {
+		t.Fatalf("Could not init database: %s", err)
+	}
+
+	bucketIDs, err := database.EnsureBuckets(db, "storage_console", []string{"docs"}, "docs")
+	if err != nil {
+		t.Fatalf("Could not create buckets: %s", err)
+	}
+
+	txn, err := database.NewTxnWithSchema(db, "storage_console")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer txn.Rollback()
+
+	for i, blob := range []struct {
+		key    string
+		source string
+		text   string
+	}{
+		{key: "receipts/shop.jpg", source: "ocr", text: "RECEIPT Corner Shop TOTAL 12.50"},
+		{key: "receipts/cafe.jpg", source: "ocr", text: "Cafe receipt, coffee 3.20"},
+		// text from other sources isn't searched
+		{key: "photos/sign.jpg", source: "tags", text: "total eclipse"},
+	} {
+		_, err := txn.ExecContext(ctx, `
+with blob as (
+  insert into blobs (bucket_id, md5, size, last_modified, content_type_id)
+  values ($1, $2, 1, now(), find_or_create_content_type('image/jpeg'))
+  returning id
+), object as (
+  insert into objects (bucket_id, key) values ($1, $3) returning id
+), object_blob as (
+  insert into object_blobs (object_id, blob_id) select object.id, blob.id from object, blob
+)
+insert into blob_properties (blob_id, source, property_type, value_type, value_text)
+select blob.id, $4, 'Text', 'Text', $5 from blob`,
+			bucketIDs["docs"], fmt.Sprintf("%032d", i), blob.key, blob.source, blob.text,
+		)
+		if err != nil {
+			t.Fatalf("Could not insert %s: %s", blob.key, err)
+		}
+	}
+
+	err = txn.Commit()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	handler, err := BuildSearchHandler(&handlers.Options{
+		DB:         db,
+		SchemaName: "storage_console",
+		Logger:     slog.New(slog.NewTextHandler(test.NewTLogWriter(t), nil)),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	testCases := map[string]struct {
+		query    string
+		expected []string
+		excluded []string
+	}{
+		"word in the text": {
+			query:    "text=total",
+			expected: []string{"shop.jpg"},
+			excluded: []string{"cafe.jpg", "sign.jpg"},
+		},
+		"all words": {
+			query:    "text=cafe+RECEIPT",
+			expected: []string{"cafe.jpg"},
+			excluded: []string{"shop.jpg"},
+		},
+		"prefix": {
+			query:    "text=receipt&prefix=photos",
+			excluded: []string{"shop.jpg", "cafe.jpg"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler(rec, httptest.NewRequest(http.MethodGet, "/search?"+tc.query, nil))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("unexpected status code: %d", rec.Code)
+			}
+
+			for _, name := range tc.expected {
+				if !strings.Contains(rec.Body.String(), name) {
+					t.Errorf("expected %s in results", name)
+				}
+			}
+
+			for _, name := range tc.excluded {
+				if strings.Contains(rec.Body.String(), name) {
+					t.Errorf("expected %s not to be in results", name)
+				}
+			}
+		})
+	}
+}
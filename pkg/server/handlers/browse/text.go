@@ -0,0 +1,230 @@
+package browse
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alecthomas/chroma/v2"
+	chromaHTML "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+const (
+	textPreviewDefaultLimit = 64 * 1024
+	textPreviewMaxLimit     = 4 * 1024 * 1024
+)
+
+const (
+	textKindCode     = "code"
+	textKindMarkdown = "markdown"
+	textKindCSV      = "csv"
+)
+
+// textContentTypes are non text/* content types which are still safe to
+// render as text
+var textContentTypes = []string{
+	"application/json",
+	"application/xml",
+	"application/yaml",
+	"application/x-yaml",
+	"application/toml",
+	"application/javascript",
+	"application/x-sh",
+	"application/sql",
+}
+
+type textPreview struct {
+	Kind string
+
+	// HTML is set for code and markdown previews
+	HTML template.HTML
+	// Rows is set for csv previews, the first row is used as the header
+	Rows [][]string
+
+	Truncated bool
+	NextLimit int64
+}
+
+// textPreviewKind returns the kind of text preview that should be used for
+// an object, or "" if the object should not be previewed as text.
+func textPreviewKind(contentType, name string) string {
+	ext := strings.ToLower(path.Ext(name))
+
+	switch {
+	case contentType == "text/csv" || ext == ".csv":
+		return textKindCSV
+	case contentType == "text/markdown" || ext == ".md" || ext == ".markdown":
+		return textKindMarkdown
+	}
+
+	for _, prefix := range []string{"image/", "video/", "audio/", "font/"} {
+		if strings.HasPrefix(contentType, prefix) {
+			return ""
+		}
+	}
+
+	if strings.HasPrefix(contentType, "text/") {
+		return textKindCode
+	}
+
+	for _, ct := range textContentTypes {
+		if contentType == ct {
+			return textKindCode
+		}
+	}
+
+	// object stores often use application/octet-stream for source files, so
+	// fall back to the file name
+	if lexers.Match(name) != nil {
+		return textKindCode
+	}
+
+	return ""
+}
+
+// textPreviewLimit parses the requested number of bytes to preview,
+// clamping it to a sensible range.
+func textPreviewLimit(raw string) int64 {
+	var limit int64
+	if _, err := fmt.Sscanf(raw, "%d", &limit); err != nil || limit <= 0 {
+		return textPreviewDefaultLimit
+	}
+
+	if limit > textPreviewMaxLimit {
+		return textPreviewMaxLimit
+	}
+
+	return limit
+}
+
+// buildTextPreview renders the content of an object (which may be the
+// first part of it when truncated) for display on the preview page.
+func buildTextPreview(kind, name string, content []byte, truncated bool, limit int64) (*textPreview, error) {
+	if truncated {
+		content = trimPartialLine(content)
+	}
+
+	tp := &textPreview{
+		Kind:      kind,
+		Truncated: truncated,
+	}
+
+	if truncated && limit < textPreviewMaxLimit {
+		tp.NextLimit = limit * 4
+		if tp.NextLimit > textPreviewMaxLimit {
+			tp.NextLimit = textPreviewMaxLimit
+		}
+	}
+
+	var err error
+	switch kind {
+	case textKindCSV:
+		tp.Rows, err = csvRows(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse csv: %w", err)
+		}
+	case textKindMarkdown:
+		tp.HTML, err = markdownHTML(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render markdown: %w", err)
+		}
+	case textKindCode:
+		tp.HTML, err = highlightedHTML(name, content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to highlight code: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown text preview kind: %s", kind)
+	}
+
+	return tp, nil
+}
+
+// trimPartialLine drops the last incomplete line, and any partial utf8
+// sequence, from content which has been cut off at an arbitrary byte.
+func trimPartialLine(content []byte) []byte {
+	if i := bytes.LastIndexByte(content, '\n'); i >= 0 {
+		return content[:i+1]
+	}
+
+	for len(content) > 0 && !utf8.Valid(content) {
+		content = content[:len(content)-1]
+	}
+
+	return content
+}
+
+func csvRows(content []byte) ([][]string, error) {
+	r := csv.NewReader(bytes.NewReader(content))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	var rows [][]string
+	for {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func markdownHTML(content []byte) (template.HTML, error) {
+	md := goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+	buf := bytes.NewBuffer([]byte{})
+	if err := md.Convert(content, buf); err != nil {
+		return "", err
+	}
+
+	// markdown can contain raw html, so the output must be sanitised before
+	// being placed in the page
+	return template.HTML(bluemonday.UGCPolicy().SanitizeBytes(buf.Bytes())), nil
+}
+
+func highlightedHTML(name string, content []byte) (template.HTML, error) {
+	lexer := lexers.Match(name)
+	if lexer == nil {
+		lexer = lexers.Analyse(string(content))
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+
+	iterator, err := lexer.Tokenise(nil, string(content))
+	if err != nil {
+		return "", err
+	}
+
+	formatter := chromaHTML.New(
+		chromaHTML.WithLineNumbers(true),
+		chromaHTML.TabWidth(4),
+		chromaHTML.WrapLongLines(true),
+	)
+
+	buf := bytes.NewBuffer([]byte{})
+	err = formatter.Format(buf, styles.Get("github"), iterator)
+	if err != nil {
+		return "", err
+	}
+
+	return template.HTML(buf.String()), nil
+}
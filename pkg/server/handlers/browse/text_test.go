@@ -0,0 +1,165 @@
+package browse
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTextPreviewKind(t *testing.T) {
+	tests := map[string]struct {
+		contentType string
+		name        string
+		expected    string
+	}{
+		"go source with generic content type": {
+			contentType: "application/octet-stream",
+			name:        "data/main.go",
+			expected:    textKindCode,
+		},
+		"yaml": {
+			contentType: "application/x-yaml",
+			name:        "data/config.yaml",
+			expected:    textKindCode,
+		},
+		"plain text": {
+			contentType: "text/plain",
+			name:        "data/notes",
+			expected:    textKindCode,
+		},
+		"markdown": {
+			contentType: "text/markdown",
+			name:        "data/README",
+			expected:    textKindMarkdown,
+		},
+		"markdown by extension": {
+			contentType: "application/octet-stream",
+			name:        "data/README.md",
+			expected:    textKindMarkdown,
+		},
+		"csv": {
+			contentType: "text/csv",
+			name:        "data/report.csv",
+			expected:    textKindCSV,
+		},
+		"jpeg": {
+			contentType: "image/jpeg",
+			name:        "data/photo.jpg",
+			expected:    "",
+		},
+		"svg image": {
+			contentType: "image/svg+xml",
+			name:        "data/icon.svg",
+			expected:    "",
+		},
+		"unknown binary": {
+			contentType: "application/octet-stream",
+			name:        "data/archive.bin",
+			expected:    "",
+		},
+	}
+
+	for testCase, testData := range tests {
+		t.Run(testCase, func(t *testing.T) {
+			actual := textPreviewKind(testData.contentType, testData.name)
+			if actual != testData.expected {
+				t.Fatalf("expected %q, got %q", testData.expected, actual)
+			}
+		})
+	}
+}
+
+func TestTextPreviewLimit(t *testing.T) {
+	tests := map[string]struct {
+		raw      string
+		expected int64
+	}{
+		"empty":    {raw: "", expected: textPreviewDefaultLimit},
+		"invalid":  {raw: "foo", expected: textPreviewDefaultLimit},
+		"negative": {raw: "-1", expected: textPreviewDefaultLimit},
+		"valid":    {raw: "1024", expected: 1024},
+		"too large": {
+			raw:      "999999999999",
+			expected: textPreviewMaxLimit,
+		},
+	}
+
+	for testCase, testData := range tests {
+		t.Run(testCase, func(t *testing.T) {
+			actual := textPreviewLimit(testData.raw)
+			if actual != testData.expected {
+				t.Fatalf("expected %d, got %d", testData.expected, actual)
+			}
+		})
+	}
+}
+
+func TestBuildTextPreview(t *testing.T) {
+	t.Run("code is highlighted", func(t *testing.T) {
+		tp, err := buildTextPreview(textKindCode, "main.go", []byte("package main\n"), false, 1024)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if !strings.Contains(string(tp.HTML), "<pre") {
+			t.Fatalf("expected highlighted html, got %s", tp.HTML)
+		}
+
+		if !strings.Contains(string(tp.HTML), "package") {
+			t.Fatalf("expected source in html, got %s", tp.HTML)
+		}
+	})
+
+	t.Run("markdown is sanitised", func(t *testing.T) {
+		content := "# Title\n\n<script>alert(1)</script>\n\n[link](javascript:alert(1))\n"
+
+		tp, err := buildTextPreview(textKindMarkdown, "README.md", []byte(content), false, 1024)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		html := string(tp.HTML)
+		if !strings.Contains(html, "<h1") {
+			t.Fatalf("expected heading in html, got %s", html)
+		}
+
+		if strings.Contains(html, "<script") || strings.Contains(html, "javascript:") {
+			t.Fatalf("expected html to be sanitised, got %s", html)
+		}
+	})
+
+	t.Run("truncated csv drops the partial row", func(t *testing.T) {
+		content := "name,size\na,1\nb,2\nc,"
+
+		tp, err := buildTextPreview(textKindCSV, "data.csv", []byte(content), true, 1024)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if exp, got := 3, len(tp.Rows); exp != got {
+			t.Fatalf("expected %d rows, got %d", exp, got)
+		}
+
+		if exp, got := "b", tp.Rows[2][0]; exp != got {
+			t.Fatalf("expected %q, got %q", exp, got)
+		}
+
+		if !tp.Truncated {
+			t.Fatalf("expected preview to be truncated")
+		}
+
+		if exp, got := int64(4096), tp.NextLimit; exp != got {
+			t.Fatalf("expected next limit %d, got %d", exp, got)
+		}
+	})
+
+	t.Run("no more to load past the max limit", func(t *testing.T) {
+		tp, err := buildTextPreview(textKindCode, "a.txt", []byte("a\nb"), true, textPreviewMaxLimit)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if tp.NextLimit != 0 {
+			t.Fatalf("expected no next limit, got %d", tp.NextLimit)
+		}
+	})
+}
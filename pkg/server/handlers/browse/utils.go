@@ -3,6 +3,7 @@ package browse
 import (
 	"fmt"
 	"math"
+	"net/url"
 	"strings"
 )
 
@@ -51,7 +52,7 @@ func breadcrumbsFromPath(path string) breadcrumbs {
 	parts := strings.Split(path, "/")
 
 	for i, part := range parts {
-		if part == "" || part == "data" {
+		if part == "" {
 			continue
 		}
 
@@ -66,3 +67,27 @@ func breadcrumbsFromPath(path string) breadcrumbs {
 
 	return b
 }
+
+// assetURL returns the URL used to load an object from a directory page
+func assetURL(dirPath, name string) string {
+	return dirPath + "?" + url.Values{"asset": {name}}.Encode()
+}
+
+// thumbnailURL returns the URL of a single pre-generated thumbnail rendition
+func thumbnailURL(assetURL, md5 string, size int) string {
+	return fmt.Sprintf("%s&%s", assetURL, url.Values{
+		"thumb": {md5},
+		"size":  {fmt.Sprint(size)},
+	}.Encode())
+}
+
+// thumbnailSrcset lists each thumbnail rendition with its width so that the
+// browser can choose the smallest one that fits
+func thumbnailSrcset(assetURL, md5 string, sizes []int) string {
+	var candidates []string
+	for _, size := range sizes {
+		candidates = append(candidates, fmt.Sprintf("%s %dw", thumbnailURL(assetURL, md5, size), size))
+	}
+
+	return strings.Join(candidates, ", ")
+}
@@ -98,6 +98,29 @@ func TestBreadcrumbsFromPath(t *testing.T) {
 				},
 			},
 		},
+		"data directory": {
+			inputPath: "/data/a",
+			expected: breadcrumbs{
+				Display: true,
+				Items: []breadcrumb{
+					{
+						Name:      "root",
+						Path:      "/",
+						Navigable: true,
+					},
+					{
+						Name:      "data",
+						Path:      "/data",
+						Navigable: true,
+					},
+					{
+						Name:      "a",
+						Path:      "/data/a",
+						Navigable: false,
+					},
+				},
+			},
+		},
 		"three levels with file": {
 			inputPath: "/a/b/c/file.jpg",
 			expected: breadcrumbs{
@@ -159,3 +182,34 @@ func TestBreadcrumbsFromPath(t *testing.T) {
 		})
 	}
 }
+
+func TestThumbnailSrcset(t *testing.T) {
+	tests := map[string]struct {
+		dirPath  string
+		name     string
+		sizes    []int
+		expected string
+	}{
+		"single size": {
+			dirPath:  "/b/photos/",
+			name:     "a.jpg",
+			sizes:    []int{300},
+			expected: "/b/photos/?asset=a.jpg&size=300&thumb=abc 300w",
+		},
+		"multiple sizes": {
+			dirPath:  "/b/",
+			name:     "a b.heic",
+			sizes:    []int{150, 1200},
+			expected: "/b/?asset=a+b.heic&size=150&thumb=abc 150w, /b/?asset=a+b.heic&size=1200&thumb=abc 1200w",
+		},
+	}
+
+	for testCase, testData := range tests {
+		t.Run(testCase, func(t *testing.T) {
+			got := thumbnailSrcset(assetURL(testData.dirPath, testData.name), "abc", testData.sizes)
+			if got != testData.expected {
+				t.Fatalf("expected %q, got %q", testData.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/charlieegan3/storage-console/pkg/database"
+	"github.com/charlieegan3/storage-console/pkg/logging"
+)
+
+// ProcessingError is a failed processor run which is waiting to be retried
+type ProcessingError struct {
+	Bucket        string
+	Key           string
+	Processor     string
+	Error         string
+	Attempts      int
+	LastAttemptAt time.Time
+	// NextRetryAt is nil once the runner has stopped retrying
+	NextRetryAt *time.Time
+}
+
+// PreviewURL links to the preview page of the errored object
+func (pe ProcessingError) PreviewURL() string {
+	return PreviewURL(pe.Bucket, pe.Key)
+}
+
+// RetryURL reprocesses the errored object immediately
+func (pe ProcessingError) RetryURL() string {
+	return ReloadURL(pe.Bucket, pe.Key)
+}
+
+const processingErrorsLimit = 500
+
+func BuildErrorsHandler(opts *Options) (func(http.ResponseWriter, *http.Request), error) {
+	tmpl, err := template.ParseFS(
+		Templates,
+		"templates/errors.html",
+		"templates/base.html",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse templates: %s", err)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		txn, err := database.NewTxnWithSchemaContext(r.Context(), opts.DB, opts.SchemaName)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			opts.Logger.ErrorContext(r.Context(), "failed to start transaction", logging.Err(err))
+			return
+		}
+		defer txn.Rollback()
+
+		// retrying makes all errors due now, the runners are then started by
+		// the reload handler
+		if r.Method == http.MethodPost {
+			_, err = txn.ExecContext(
+				r.Context(),
+				`UPDATE processing_errors SET next_retry_at = now();`,
+			)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				opts.Logger.ErrorContext(r.Context(), "failed to reset processing errors", logging.Err(err))
+				return
+			}
+
+			err = txn.Commit()
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				opts.Logger.ErrorContext(r.Context(), "failed to commit transaction", logging.Err(err))
+				return
+			}
+
+			http.Redirect(w, r, "/reload", http.StatusSeeOther)
+			return
+		}
+
+		processingErrors, err := ListProcessingErrors(r.Context(), txn, 0)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			opts.Logger.ErrorContext(r.Context(), "failed to list processing errors", logging.Err(err))
+			return
+		}
+
+		buf := bytes.NewBuffer([]byte{})
+
+		err = tmpl.ExecuteTemplate(buf, "base", struct {
+			Opts   *Options
+			Errors []ProcessingError
+			Limit  int
+		}{
+			Opts:   opts,
+			Errors: processingErrors,
+			Limit:  processingErrorsLimit,
+		})
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, err = w.Write([]byte(err.Error()))
+			if err != nil {
+				opts.Logger.ErrorContext(r.Context(), "failed to write response", logging.Err(err))
+			}
+			return
+		}
+
+		_, err = io.Copy(w, buf)
+		if err != nil {
+			opts.Logger.ErrorContext(r.Context(), "failed to write response", logging.Err(err))
+		}
+	}, nil
+}
+
+// ListProcessingErrors returns the most recent processing errors, if blobID
+// is set then only errors for that blob are returned
+func ListProcessingErrors(ctx context.Context, txn *sql.Tx, blobID int64) ([]ProcessingError, error) {
+	listErrorsSQL := `
+select
+  coalesce(buckets.name, ''),
+  coalesce((
+    select objects.key
+    from object_blobs
+    join objects on objects.id = object_blobs.object_id
+    where object_blobs.blob_id = processing_errors.blob_id
+    and objects.deleted_at is null
+    order by objects.key
+    limit 1
+  ), '') as key,
+  processor,
+  error,
+  attempts,
+  last_attempt_at,
+  next_retry_at
+from processing_errors
+join blobs on blobs.id = processing_errors.blob_id
+left join buckets on buckets.id = blobs.bucket_id
+where $1 = 0 or blob_id = $1
+order by last_attempt_at desc, processor
+limit $2;
+`
+	rows, err := txn.QueryContext(ctx, listErrorsSQL, blobID, processingErrorsLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query processing errors: %s", err)
+	}
+	defer rows.Close()
+
+	var processingErrors []ProcessingError
+	for rows.Next() {
+		var pe ProcessingError
+		err = rows.Scan(&pe.Bucket, &pe.Key, &pe.Processor, &pe.Error, &pe.Attempts, &pe.LastAttemptAt, &pe.NextRetryAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan processing error: %s", err)
+		}
+
+		processingErrors = append(processingErrors, pe)
+	}
+
+	return processingErrors, rows.Err()
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"testing"
+)
+
+func TestProcessingErrorURLs(t *testing.T) {
+	tests := map[string]struct {
+		key             string
+		expectedPreview string
+		expectedRetry   string
+	}{
+		"root object": {
+			key:             "a.jpg",
+			expectedPreview: "/b/photos/?preview=a.jpg",
+			expectedRetry:   "/reload?bucket=photos&prefix=a.jpg",
+		},
+		"nested object": {
+			key:             "photos/2024/a b.jpg",
+			expectedPreview: "/b/photos/photos/2024/?preview=a+b.jpg",
+			expectedRetry:   "/reload?bucket=photos&prefix=photos%2F2024%2Fa+b.jpg",
+		},
+	}
+
+	for testCase, testData := range tests {
+		t.Run(testCase, func(t *testing.T) {
+			pe := ProcessingError{Bucket: "photos", Key: testData.key}
+
+			if got := pe.PreviewURL(); got != testData.expectedPreview {
+				t.Fatalf("expected %q, got %q", testData.expectedPreview, got)
+			}
+
+			if got := pe.RetryURL(); got != testData.expectedRetry {
+				t.Fatalf("expected %q, got %q", testData.expectedRetry, got)
+			}
+		})
+	}
+}
+
+func TestBuildErrorsHandler(t *testing.T) {
+	_, err := BuildErrorsHandler(&Options{})
+	if err != nil {
+		t.Fatalf("failed to build errors handler: %s", err)
+	}
+}
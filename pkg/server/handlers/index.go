@@ -6,6 +6,8 @@ import (
 	"html/template"
 	"io"
 	"net/http"
+
+	"github.com/charlieegan3/storage-console/pkg/logging"
 )
 
 func BuildIndexHandler(opts *Options) (func(http.ResponseWriter, *http.Request), error) {
@@ -29,8 +31,8 @@ func BuildIndexHandler(opts *Options) (func(http.ResponseWriter, *http.Request),
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			_, err = w.Write([]byte(err.Error()))
-			if err != nil && opts.LoggerError != nil {
-				opts.LoggerError.Println(err)
+			if err != nil {
+				opts.Logger.ErrorContext(r.Context(), "failed to write response", logging.Err(err))
 			}
 			return
 		}
@@ -39,8 +41,8 @@ func BuildIndexHandler(opts *Options) (func(http.ResponseWriter, *http.Request),
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			_, err = w.Write([]byte(err.Error()))
-			if err != nil && opts.LoggerError != nil {
-				opts.LoggerError.Println(err)
+			if err != nil {
+				opts.Logger.ErrorContext(r.Context(), "failed to write response", logging.Err(err))
 			}
 			return
 		}
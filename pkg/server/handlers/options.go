@@ -2,9 +2,14 @@ package handlers
 
 import (
 	"database/sql"
-	"log"
+	"log/slog"
 
 	"github.com/minio/minio-go/v7"
+
+	"github.com/charlieegan3/storage-console/pkg/layout"
+	"github.com/charlieegan3/storage-console/pkg/meta"
+	"github.com/charlieegan3/storage-console/pkg/metrics"
+	"github.com/charlieegan3/storage-console/pkg/processors"
 )
 
 type Options struct {
@@ -12,10 +17,80 @@ type Options struct {
 	EtagScript string
 	EtagStyles string
 
-	LoggerError *log.Logger
-	LoggerInfo  *log.Logger
+	// Logger must be set, logging.Discard can be used for no logs
+	Logger *slog.Logger
+	// Metrics is nil when metrics aren't enabled
+	Metrics *metrics.Metrics
 
-	DB         *sql.DB
-	S3         *minio.Client
+	DB *sql.DB
+	// SchemaName is the postgres schema holding the console's tables
+	SchemaName string
+
+	// Buckets are the configured buckets, the first is the default
+	Buckets []*Bucket
+	// Bucket is the bucket being browsed, it's set for each request by the
+	// browse handler
+	Bucket *Bucket
+
+	ThumbnailSizes  []int
+	ThumbnailFormat meta.ContentType
+}
+
+// Bucket is a configured bucket, browsed under /b/<Name>/
+type Bucket struct {
+	// ID scopes the bucket's objects and blobs in the database
+	ID         int
+	Name       string
 	BucketName string
+	S3         *minio.Client
+
+	// Processors are bound to the bucket for processors which read from it
+	Processors *processors.Registry
+	// Layout is where objects and processor output are stored, the default
+	// layout is used when it's nil
+	Layout *layout.Layout
+}
+
+// URL returns the root of the bucket's browse pages, without a trailing /
+func (b *Bucket) URL() string {
+	return "/b/" + b.Name
+}
+
+// DataKey returns the storage key for an object key
+func (b *Bucket) DataKey(key string) string {
+	return layout.OrDefault(b.Layout).DataKey(key)
+}
+
+// ObjectKey returns the object key for a storage key in the bucket
+func (b *Bucket) ObjectKey(dataKey string) string {
+	return layout.OrDefault(b.Layout).ObjectKey(dataKey)
+}
+
+// MetaKey returns the storage key for processor output, in MetaBucketName
+func (b *Bucket) MetaKey(elem ...string) string {
+	return layout.OrDefault(b.Layout).MetaKey(elem...)
+}
+
+// MetaBucketName is the bucket holding processor output
+func (b *Bucket) MetaBucketName() string {
+	return layout.OrDefault(b.Layout).MetaBucketName(b.BucketName)
+}
+
+// FindBucket returns the configured bucket with the given name
+func (o *Options) FindBucket(name string) (*Bucket, bool) {
+	for _, b := range o.Buckets {
+		if b.Name == name {
+			return b, true
+		}
+	}
+
+	return nil, false
+}
+
+// ForBucket returns a copy of the options for handling a request to b
+func (o *Options) ForBucket(b *Bucket) *Options {
+	bo := *o
+	bo.Bucket = b
+
+	return &bo
 }
@@ -14,6 +14,7 @@ import (
 	"github.com/tdewolff/minify/v2/css"
 	"github.com/tdewolff/minify/v2/js"
 
+	"github.com/charlieegan3/storage-console/pkg/logging"
 	"github.com/charlieegan3/storage-console/pkg/utils"
 )
 
@@ -40,8 +41,8 @@ func BuildFaviconHandler(opts *Options) (handler func(http.ResponseWriter, *http
 		}
 
 		_, err = w.Write(bs)
-		if err != nil && opts.LoggerError != nil {
-			opts.LoggerError.Println(err)
+		if err != nil {
+			opts.Logger.ErrorContext(req.Context(), "failed to write response", logging.Err(err))
 		}
 	}
 }
@@ -106,8 +107,8 @@ func BuildFontHandler(opts *Options) (handler func(http.ResponseWriter, *http.Re
 		}
 
 		_, err = w.Write(ff.Bytes)
-		if err != nil && opts.LoggerError != nil {
-			opts.LoggerError.Println(err)
+		if err != nil {
+			opts.Logger.ErrorContext(req.Context(), "failed to write response", logging.Err(err))
 		}
 	}
 }
@@ -140,8 +141,20 @@ func BuildContentTypeIconHandler(opts *Options) (handler func(http.ResponseWrite
 			key = "png"
 		case "image/gif":
 			key = "gif"
-		case "image/heic":
+		case "image/heic", "image/heif":
 			key = "heic"
+		case "image/webp":
+			key = "webp"
+		case "image/tiff":
+			key = "tiff"
+		case "image/x-canon-cr2", "image/x-canon-cr3":
+			key = "cr2"
+		case "image/x-nikon-nef":
+			key = "nef"
+		case "image/x-adobe-dng":
+			key = "dng"
+		case "image/x-sony-arw", "image/x-fuji-raf", "image/x-panasonic-rw2", "image/x-olympus-orf":
+			key = "raw"
 		case "video/mp4":
 			key = "mp4"
 		case "application/pdf":
@@ -165,8 +178,8 @@ func BuildContentTypeIconHandler(opts *Options) (handler func(http.ResponseWrite
 			w.WriteHeader(http.StatusInternalServerError)
 
 			_, err = w.Write([]byte("failed to open icon"))
-			if err != nil && opts.LoggerError != nil {
-				opts.LoggerError.Println(err)
+			if err != nil {
+				opts.Logger.ErrorContext(r.Context(), "failed to write response", logging.Err(err))
 			}
 
 			return
@@ -178,8 +191,8 @@ func BuildContentTypeIconHandler(opts *Options) (handler func(http.ResponseWrite
 			w.WriteHeader(http.StatusInternalServerError)
 
 			_, err = w.Write([]byte("failed to read icon"))
-			if err != nil && opts.LoggerError != nil {
-				opts.LoggerError.Println(err)
+			if err != nil {
+				opts.Logger.ErrorContext(r.Context(), "failed to write response", logging.Err(err))
 			}
 
 			return
@@ -203,8 +216,8 @@ func BuildContentTypeIconHandler(opts *Options) (handler func(http.ResponseWrite
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			_, err = w.Write([]byte("failed to copy icon data"))
-			if err != nil && opts.LoggerError != nil {
-				opts.LoggerError.Println(err)
+			if err != nil {
+				opts.Logger.ErrorContext(r.Context(), "failed to write response", logging.Err(err))
 			}
 			return
 		}
@@ -257,8 +270,8 @@ func BuildCSSHandler(opts *Options) (string, func(http.ResponseWriter, *http.Req
 		}
 
 		_, err := w.Write(out.Bytes())
-		if err != nil && opts.LoggerError != nil {
-			opts.LoggerError.Println(err)
+		if err != nil {
+			opts.Logger.ErrorContext(r.Context(), "failed to write response", logging.Err(err))
 		}
 	}, nil
 }
@@ -312,8 +325,8 @@ func BuildJSHandler(opts *Options) (string, func(http.ResponseWriter, *http.Requ
 		}
 
 		_, err := w.Write(out.Bytes())
-		if err != nil && opts.LoggerError != nil {
-			opts.LoggerError.Println(err)
+		if err != nil {
+			opts.Logger.ErrorContext(r.Context(), "failed to write response", logging.Err(err))
 		}
 	}, nil
 }
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"net/url"
+	"path"
+)
+
+// PreviewURL returns the preview page URL for an object key in a bucket
+func PreviewURL(bucket, key string) string {
+	dir := path.Dir(key)
+	if dir == "." {
+		dir = ""
+	}
+
+	return path.Join("/b", bucket, dir) + "/?" + url.Values{"preview": {path.Base(key)}}.Encode()
+}
+
+// ReloadURL returns the URL which imports and processes an object again
+func ReloadURL(bucket, key string) string {
+	return "/reload?" + url.Values{"bucket": {bucket}, "prefix": {key}}.Encode()
+}
@@ -0,0 +1,225 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+
+	"github.com/charlieegan3/storage-console/pkg/database/migration"
+	"github.com/charlieegan3/storage-console/pkg/logging"
+	"github.com/charlieegan3/storage-console/pkg/meta/thumbnail"
+	"github.com/charlieegan3/storage-console/pkg/server/handlers"
+)
+
+// readyTimeout bounds the checks made for each /readyz request, so that
+// probes get an answer from an unreachable database or bucket
+const readyTimeout = 5 * time.Second
+
+// readyCheck is one of the dependencies reported by /readyz
+type readyCheck struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+type checkStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type healthStatus struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkStatus `json:"checks,omitempty"`
+}
+
+// importState is the progress of the import run by Server.Start
+type importState struct {
+	mu      sync.Mutex
+	running bool
+	err     error
+}
+
+func (i *importState) start() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.running = true
+	i.err = nil
+}
+
+// finish records the end of the import, err joins the buckets' failures
+func (i *importState) finish(err error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.running = false
+	i.err = err
+}
+
+// check returns an error while the import is running or when it failed
+func (i *importState) check() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.running {
+		return fmt.Errorf("import is running")
+	}
+
+	if i.err != nil {
+		return fmt.Errorf("import failed: %w", i.err)
+	}
+
+	return nil
+}
+
+// readyChecks returns the checks made before the server is reported as
+// ready to serve requests
+func (s *Server) readyChecks() []readyCheck {
+	checks := []readyCheck{
+		{
+			name: "database",
+			run: func(ctx context.Context) error {
+				return s.db.PingContext(ctx)
+			},
+		},
+		{
+			name: "migrations",
+			run: func(ctx context.Context) error {
+				latest, err := migration.Latest()
+				if err != nil {
+					return err
+				}
+
+				version, dirty, err := migration.AppliedVersion(ctx, s.db, &postgres.Config{
+					MigrationsTable: s.cfg.Database.MigrationsTable,
+					SchemaName:      s.cfg.Database.MigrationsSchema,
+				})
+				if err != nil {
+					return err
+				}
+
+				if dirty {
+					return fmt.Errorf("migration %d failed part way through", version)
+				}
+
+				if version != latest {
+					return fmt.Errorf("database is at migration %d, expected %d", version, latest)
+				}
+
+				return nil
+			},
+		},
+	}
+
+	for _, b := range s.buckets {
+		checks = append(checks, readyCheck{
+			name: "bucket:" + b.Name,
+			run: func(ctx context.Context) error {
+				bucketNames := []string{b.BucketName}
+				if b.MetaBucketName() != b.BucketName {
+					bucketNames = append(bucketNames, b.MetaBucketName())
+				}
+
+				for _, name := range bucketNames {
+					exists, err := b.S3.BucketExists(ctx, name)
+					if err != nil {
+						return fmt.Errorf("could not check if bucket %s exists: %w", name, err)
+					}
+
+					if !exists {
+						return fmt.Errorf("bucket %s does not exist", name)
+					}
+				}
+
+				return nil
+			},
+		})
+	}
+
+	checks = append(checks, readyCheck{
+		name: "vips",
+		run: func(context.Context) error {
+			return thumbnail.Ready()
+		},
+	})
+
+	if s.cfg.Server.RunImporter && s.cfg.Server.ReadyAfterImport {
+		checks = append(checks, readyCheck{
+			name: "import",
+			run: func(context.Context) error {
+				return s.imports.check()
+			},
+		})
+	}
+
+	return checks
+}
+
+// healthHandler reports that the server is running, without checking
+// anything it depends on
+func healthHandler(opts *handlers.Options) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, r, opts, http.StatusOK, healthStatus{Status: "ok"})
+	})
+}
+
+// readyHandler runs the checks concurrently, responding with 503 and the
+// failures when any of them fail
+func readyHandler(opts *handlers.Options, checks []readyCheck) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+		defer cancel()
+
+		resp := healthStatus{
+			Status: "ready",
+			Checks: make(map[string]checkStatus, len(checks)),
+		}
+		code := http.StatusOK
+
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for _, c := range checks {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				status := checkStatus{Status: "ok"}
+				err := c.run(ctx)
+				if err != nil {
+					opts.Logger.DebugContext(ctx, "readiness check failed", "check", c.name, logging.Err(err))
+					status = checkStatus{Status: "failed", Error: err.Error()}
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				resp.Checks[c.name] = status
+				if err != nil {
+					resp.Status = "not ready"
+					code = http.StatusServiceUnavailable
+				}
+			}()
+		}
+		wg.Wait()
+
+		writeHealth(w, r, opts, code, resp)
+	})
+}
+
+func writeHealth(w http.ResponseWriter, r *http.Request, opts *handlers.Options, code int, status healthStatus) {
+	bs, err := json.Marshal(status)
+	if err != nil {
+		opts.Logger.ErrorContext(r.Context(), "failed to marshal health status", logging.Err(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_, _ = w.Write(append(bs, '\n'))
+}
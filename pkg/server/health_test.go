@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/charlieegan3/storage-console/pkg/logging"
+	"github.com/charlieegan3/storage-console/pkg/server/handlers"
+)
+
+func TestReadyHandler(t *testing.T) {
+	t.Parallel()
+
+	opts := &handlers.Options{Logger: logging.Discard()}
+
+	ok := func(context.Context) error { return nil }
+
+	testCases := map[string]struct {
+		checks         []readyCheck
+		expectedCode   int
+		expectedStatus healthStatus
+	}{
+		"ready": {
+			checks: []readyCheck{
+				{name: "database", run: ok},
+				{name: "vips", run: ok},
+			},
+			expectedCode: http.StatusOK,
+			expectedStatus: healthStatus{
+				Status: "ready",
+				Checks: map[string]checkStatus{
+					"database": {Status: "ok"},
+					"vips":     {Status: "ok"},
+				},
+			},
+		},
+		"not ready": {
+			checks: []readyCheck{
+				{name: "database", run: ok},
+				{name: "import", run: func(context.Context) error {
+					return errors.New("import is running")
+				}},
+			},
+			expectedCode: http.StatusServiceUnavailable,
+			expectedStatus: healthStatus{
+				Status: "not ready",
+				Checks: map[string]checkStatus{
+					"database": {Status: "ok"},
+					"import":   {Status: "failed", Error: "import is running"},
+				},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			readyHandler(opts, tc.checks).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if rec.Code != tc.expectedCode {
+				t.Fatalf("expected status code %d, got %d", tc.expectedCode, rec.Code)
+			}
+
+			var status healthStatus
+			err := json.Unmarshal(rec.Body.Bytes(), &status)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if status.Status != tc.expectedStatus.Status || len(status.Checks) != len(tc.expectedStatus.Checks) {
+				t.Fatalf("unexpected status: %+v", status)
+			}
+
+			for name, expected := range tc.expectedStatus.Checks {
+				if status.Checks[name] != expected {
+					t.Fatalf("unexpected %s check: %+v", name, status.Checks[name])
+				}
+			}
+		})
+	}
+}
+
+func TestHealthHandler(t *testing.T) {
+	t.Parallel()
+
+	rec := httptest.NewRecorder()
+	healthHandler(&handlers.Options{Logger: logging.Discard()}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status code: %d", rec.Code)
+	}
+
+	if rec.Body.String() != "{\"status\":\"ok\"}\n" {
+		t.Fatalf("unexpected body: %s", rec.Body.String())
+	}
+}
+
+func TestImportState(t *testing.T) {
+	t.Parallel()
+
+	var imports importState
+
+	if err := imports.check(); err != nil {
+		t.Fatalf("unexpected error before an import: %s", err)
+	}
+
+	imports.start()
+	if err := imports.check(); err == nil || err.Error() != "import is running" {
+		t.Fatalf("unexpected error while importing: %v", err)
+	}
+
+	imports.finish(errors.Join(errors.New("bucket photos: boom"), errors.New("bucket docs: boom")))
+	err := imports.check()
+	if err == nil || err.Error() != "import failed: bucket photos: boom\nbucket docs: boom" {
+		t.Fatalf("unexpected error after a failed import: %v", err)
+	}
+
+	imports.start()
+	imports.finish(nil)
+	if err := imports.check(); err != nil {
+		t.Fatalf("unexpected error after an import: %s", err)
+	}
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/charlieegan3/storage-console/pkg/database"
+	metaRunner "github.com/charlieegan3/storage-console/pkg/meta/runner"
+	"github.com/charlieegan3/storage-console/pkg/metrics"
+	"github.com/charlieegan3/storage-console/pkg/server/handlers"
+)
+
+// registerStoreMetrics adds gauges for the objects, blobs and processing
+// backlog of each bucket, which are counted in the database when the
+// metrics are scraped
+func registerStoreMetrics(m *metrics.Metrics, db *sql.DB, schema string, buckets []*handlers.Bucket) {
+	bucketNames := make(map[string]string)
+	for _, b := range buckets {
+		bucketNames[strconv.Itoa(b.ID)] = b.Name
+	}
+
+	gauges := []struct {
+		name   string
+		help   string
+		labels []string
+		sql    string
+	}{
+		{
+			name:   metrics.Namespace + "objects",
+			help:   "Objects by bucket, excluding deleted objects.",
+			labels: []string{"bucket"},
+			sql: `
+select bucket_id, count(*)
+from objects
+where deleted_at is null
+group by bucket_id`,
+		},
+		{
+			name:   metrics.Namespace + "blobs",
+			help:   "Blobs by bucket.",
+			labels: []string{"bucket"},
+			sql: `
+select bucket_id, count(*)
+from blobs
+group by bucket_id`,
+		},
+		{
+			name:   metrics.Namespace + "blob_bytes",
+			help:   "Total size of the blobs by bucket.",
+			labels: []string{"bucket"},
+			sql: `
+select bucket_id, coalesce(sum(size), 0)
+from blobs
+group by bucket_id`,
+		},
+	}
+
+	for _, g := range gauges {
+		m.NewGaugeFunc(g.name, g.help, g.labels, func(ctx context.Context) ([]metrics.Sample, error) {
+			samples, err := querySamples(ctx, db, schema, g.sql, len(g.labels))
+			if err != nil {
+				return nil, err
+			}
+
+			// the first label is the bucket's id, rows for buckets which
+			// are no longer configured are left out
+			var named []metrics.Sample
+			for _, s := range samples {
+				name, ok := bucketNames[s.Labels[0]]
+				if !ok {
+					continue
+				}
+
+				s.Labels[0] = name
+				named = append(named, s)
+			}
+
+			return named, nil
+		})
+	}
+
+	m.NewGaugeFunc(
+		metrics.Namespace+"metadata_backlog",
+		"Blobs waiting for a metadata processor, including failures due for a retry, by bucket and processor.",
+		[]string{"bucket", "processor"},
+		func(ctx context.Context) ([]metrics.Sample, error) {
+			return backlogSamples(ctx, db, schema, buckets)
+		},
+	)
+}
+
+// backlogSamples counts the blobs each bucket's metadata processors would
+// process on their next run
+func backlogSamples(ctx context.Context, db *sql.DB, schema string, buckets []*handlers.Bucket) ([]metrics.Sample, error) {
+	txn, err := database.NewTxnWithSchemaContext(ctx, db, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer txn.Rollback()
+
+	var samples []metrics.Sample
+	for _, b := range buckets {
+		for _, name := range b.Processors.MetaNames() {
+			processor, err := b.Processors.Meta(name)
+			if err != nil {
+				return nil, err
+			}
+
+			count, err := metaRunner.Backlog(ctx, txn, b.ID, processor)
+			if err != nil {
+				return nil, fmt.Errorf("bucket %s, processor %s: %w", b.Name, name, err)
+			}
+
+			samples = append(samples, metrics.Sample{
+				Labels: []string{b.Name, name},
+				Value:  float64(count),
+			})
+		}
+	}
+
+	return samples, nil
+}
+
+// querySamples reads rows of label values followed by a value
+func querySamples(ctx context.Context, db *sql.DB, schema, query string, labels int) ([]metrics.Sample, error) {
+	txn, err := database.NewTxnWithSchemaContext(ctx, db, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer txn.Rollback()
+
+	rows, err := txn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("could not query samples: %w", err)
+	}
+	defer rows.Close()
+
+	var samples []metrics.Sample
+	for rows.Next() {
+		s := metrics.Sample{Labels: make([]string, labels)}
+
+		dest := make([]any, 0, labels+1)
+		for i := range s.Labels {
+			dest = append(dest, &s.Labels[i])
+		}
+		dest = append(dest, &s.Value)
+
+		err = rows.Scan(dest...)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan sample: %w", err)
+		}
+
+		samples = append(samples, s)
+	}
+
+	return samples, rows.Err()
+}
@@ -3,6 +3,7 @@ package middlewares
 import (
 	"net/http"
 
+	"github.com/charlieegan3/storage-console/pkg/logging"
 	"github.com/charlieegan3/storage-console/pkg/server/handlers"
 )
 
@@ -14,8 +15,8 @@ func BuildAuth(h http.Handler, opts *handlers.Options) http.Handler {
 		}
 
 		_, err := w.Write([]byte("TODO"))
-		if err != nil && opts.LoggerError != nil {
-			opts.LoggerError.Println(err)
+		if err != nil {
+			opts.Logger.ErrorContext(r.Context(), "failed to write response", logging.Err(err))
 		}
 
 		w.WriteHeader(http.StatusUnauthorized)
@@ -0,0 +1,30 @@
+package middlewares
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/charlieegan3/storage-console/pkg/server/handlers"
+)
+
+// BuildMetrics records each request by the mux pattern it matched rather
+// than its path, so that object keys don't each get their own series
+func BuildMetrics(mux *http.ServeMux, opts *handlers.Options) http.Handler {
+	if opts.Metrics == nil {
+		return mux
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, route := mux.Handler(r)
+		if route == "" {
+			route = "unmatched"
+		}
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+
+		mux.ServeHTTP(rec, r)
+
+		opts.Metrics.ObserveHTTP(route, r.Method, rec.status, time.Since(start))
+	})
+}
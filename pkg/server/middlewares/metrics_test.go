@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/charlieegan3/storage-console/pkg/metrics"
+	"github.com/charlieegan3/storage-console/pkg/server/handlers"
+)
+
+func TestBuildMetrics(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/b/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	m := metrics.New()
+	h := BuildMetrics(mux, &handlers.Options{Metrics: m})
+
+	for _, path := range []string{"/b/photos/a.jpg", "/b/photos/b.jpg", "/missing"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	m.Handler(nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	for _, line := range []string{
+		`storage_console_http_requests_total{code="404",method="GET",route="/b/"} 2`,
+		`storage_console_http_requests_total{code="404",method="GET",route="unmatched"} 1`,
+	} {
+		if !strings.Contains(rec.Body.String(), line+"\n") {
+			t.Errorf("expected %q in:\n%s", line, rec.Body.String())
+		}
+	}
+
+	if h := BuildMetrics(mux, &handlers.Options{}); h != mux {
+		t.Fatalf("expected the mux to be returned when metrics are disabled")
+	}
+}
@@ -0,0 +1,70 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/charlieegan3/storage-console/pkg/logging"
+	"github.com/charlieegan3/storage-console/pkg/server/handlers"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// incoming IDs are only reused when they're safe to write to logs
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// BuildRequestID tags each request with an ID, taken from the X-Request-ID
+// header when set, which is returned in the response and added to the lines
+// logged with the request's context
+func BuildRequestID(h http.Handler, opts *handlers.Options) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(requestIDHeader, id)
+		r = r.WithContext(logging.WithRequestID(r.Context(), id))
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+
+		h.ServeHTTP(rec, r)
+
+		opts.Logger.DebugContext(
+			r.Context(),
+			"request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"duration", time.Since(start),
+		)
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+
+	// crypto/rand doesn't fail on supported platforms
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// statusRecorder keeps the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,78 @@
+package middlewares
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/charlieegan3/storage-console/pkg/logging"
+	"github.com/charlieegan3/storage-console/pkg/server/handlers"
+)
+
+func TestBuildRequestID(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		header   string
+		expected string
+	}{
+		"incoming id is reused": {
+			header:   "abc-123",
+			expected: "abc-123",
+		},
+		"missing id is generated": {
+			header: "",
+		},
+		"unsafe id is replaced": {
+			header: "abc\n123",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			logger, err := logging.New(&buf, slog.LevelDebug, "text")
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			var handlerID string
+			h := BuildRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handlerID = logging.RequestID(r.Context())
+				w.WriteHeader(http.StatusTeapot)
+			}), &handlers.Options{Logger: logger})
+
+			req := httptest.NewRequest(http.MethodGet, "/b/photos/", nil)
+			if tc.header != "" {
+				req.Header.Set("X-Request-ID", tc.header)
+			}
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			id := rec.Header().Get("X-Request-ID")
+			if id == "" || id != handlerID {
+				t.Fatalf("expected response id %q to match handler id %q", id, handlerID)
+			}
+
+			if tc.expected != "" && id != tc.expected {
+				t.Fatalf("expected id %q, got %q", tc.expected, id)
+			}
+
+			if tc.expected == "" && len(id) != 32 {
+				t.Fatalf("expected generated id, got %q", id)
+			}
+
+			for _, s := range []string{"request_id=" + id, "status=418", "path=/b/photos/"} {
+				if !strings.Contains(buf.String(), s) {
+					t.Fatalf("expected %q in log line:\n%s", s, buf.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"github.com/charlieegan3/storage-console/pkg/config"
+)
+
+// NewMinioClient connects to the S3 endpoint using the bucket's connection
+// settings. Each wrap func is applied to the client's transport, to
+// instrument its requests.
+func NewMinioClient(s3 config.S3, wrap ...func(http.RoundTripper) http.RoundTripper) (*minio.Client, error) {
+	opts, err := minioOptions(s3)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, w := range wrap {
+		opts.Transport = w(opts.Transport)
+	}
+
+	minioClient, err := minio.New(s3.Endpoint, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to minio: %w", err)
+	}
+
+	return minioClient, nil
+}
+
+func minioOptions(s3 config.S3) (*minio.Options, error) {
+	transport, err := minio.DefaultTransport(s3.Secure)
+	if err != nil {
+		return nil, fmt.Errorf("error creating transport: %w", err)
+	}
+
+	if s3.CAFile != "" {
+		pem, err := os.ReadFile(s3.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading ca file: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in ca file %s", s3.CAFile)
+		}
+
+		transport.TLSClientConfig.RootCAs = pool
+	}
+
+	creds := credentials.NewStaticV4(s3.AccessKey, s3.SecretKey, s3.SessionToken)
+	if s3.AccessKey == "" {
+		creds = credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.EnvMinio{},
+			&credentials.FileAWSCredentials{},
+			&credentials.IAM{Client: &http.Client{Transport: http.DefaultTransport}},
+		})
+	}
+
+	lookup := minio.BucketLookupAuto
+	switch s3.BucketLookup {
+	case "path":
+		lookup = minio.BucketLookupPath
+	case "dns":
+		lookup = minio.BucketLookupDNS
+	}
+
+	return &minio.Options{
+		Creds:        creds,
+		Secure:       s3.Secure,
+		Region:       s3.Region,
+		Transport:    transport,
+		BucketLookup: lookup,
+	}, nil
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/charlieegan3/storage-console/pkg/config"
+)
+
+func TestMinioOptions(t *testing.T) {
+	t.Parallel()
+
+	opts, err := minioOptions(config.S3{
+		AccessKey:    "key",
+		SecretKey:    "secret",
+		SessionToken: "token",
+		Secure:       true,
+		Region:       "eu-west-2",
+		BucketLookup: "path",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !opts.Secure || opts.Region != "eu-west-2" || opts.BucketLookup != minio.BucketLookupPath {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+
+	value, err := opts.Creds.Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if value.AccessKeyID != "key" || value.SessionToken != "token" {
+		t.Fatalf("unexpected credentials: %+v", value)
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	err = os.WriteFile(caFile, []byte("not a certificate"), 0o600)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	_, err = minioOptions(config.S3{Secure: true, CAFile: caFile})
+	if err == nil {
+		t.Fatal("expected error for ca file without certificates")
+	}
+}
@@ -3,13 +3,10 @@ package server
 import (
 	"fmt"
 	"net/http"
-	"path"
 	"strings"
 
 	"github.com/charlieegan3/storage-console/pkg/database"
-	"github.com/charlieegan3/storage-console/pkg/importer"
-	metaRunner "github.com/charlieegan3/storage-console/pkg/meta/runner"
-	propRunner "github.com/charlieegan3/storage-console/pkg/properties/runner"
+	"github.com/charlieegan3/storage-console/pkg/logging"
 	"github.com/charlieegan3/storage-console/pkg/server/handlers"
 	"github.com/charlieegan3/storage-console/pkg/server/handlers/browse"
 	"github.com/charlieegan3/storage-console/pkg/server/middlewares"
@@ -18,6 +15,16 @@ import (
 func newMux(opts *handlers.Options) (*http.ServeMux, error) {
 	mux := http.NewServeMux()
 
+	if len(opts.Buckets) == 0 {
+		return nil, fmt.Errorf("at least one bucket is required")
+	}
+
+	for _, b := range opts.Buckets {
+		if b.Processors == nil {
+			return nil, fmt.Errorf("processor registry is required for bucket %s", b.Name)
+		}
+	}
+
 	stylesEtag, stylesHandler, err := handlers.BuildCSSHandler(opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build styles handler: %s", err)
@@ -36,36 +43,80 @@ func newMux(opts *handlers.Options) (*http.ServeMux, error) {
 		return nil, fmt.Errorf("failed to build index handler: %s", err)
 	}
 
+	errorsHandler, err := handlers.BuildErrorsHandler(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build errors handler: %s", err)
+	}
+
 	browseHandler, err := browse.BuildHandler(opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build browse handler: %s", err)
 	}
 
+	searchHandler, err := browse.BuildSearchHandler(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build search handler: %s", err)
+	}
+
+	placesHandler, err := browse.BuildPlacesHandler(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build places handler: %s", err)
+	}
+
+	colorsHandler, err := browse.BuildColorsHandler(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build colors handler: %s", err)
+	}
+
 	mux.Handle(
 		"/reload",
 		middlewares.BuildAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			opts.LoggerInfo.Printf("reloading")
 
-			var prefix string
+			var prefix, bucketName string
 			if r.Method == http.MethodPost {
 				err := r.ParseForm()
 				if err == nil {
 					prefix = r.FormValue("prefix")
+					bucketName = r.FormValue("bucket")
 				}
 			}
 			if queryPrefix := r.URL.Query().Get("prefix"); queryPrefix != "" {
 				prefix = queryPrefix
 			}
+			if queryBucket := r.URL.Query().Get("bucket"); queryBucket != "" {
+				bucketName = queryBucket
+			}
+
+			// without a bucket, a prefix is in the default bucket and a
+			// full reload covers all of them
+			buckets := opts.Buckets
+			if bucketName != "" {
+				b, ok := opts.FindBucket(bucketName)
+				if !ok {
+					http.Error(w, "unknown bucket", http.StatusNotFound)
+					return
+				}
+
+				buckets = []*handlers.Bucket{b}
+			} else if prefix != "" {
+				buckets = opts.Buckets[:1]
+			}
+
 			if prefix != "" {
-				// prefixes will be joined with the dataPath, if the dir is
-				// "", then the path might have a / at the start
+				// prefixes will be joined with the data prefix, if the dir
+				// is "", then the path might have a / at the start
 				prefix = strings.TrimLeft(prefix, "/")
 
-				opts.LoggerInfo.Printf("prefix: %q", prefix)
+				opts.Logger.InfoContext(
+					r.Context(),
+					"reloading object",
+					logging.FieldBucket, buckets[0].Name,
+					logging.FieldKey, prefix,
+				)
 
-				txn, err := database.NewTxnWithSchema(opts.DB, "storage_console")
+				txn, err := database.NewTxnWithSchemaContext(r.Context(), opts.DB, opts.SchemaName)
 				if err != nil {
-					opts.LoggerError.Printf("error creating transaction: %v", err)
+					opts.Logger.ErrorContext(r.Context(), "failed to create transaction", logging.Err(err))
 
 					return
 				}
@@ -76,15 +127,15 @@ with blob_ids as (
     from blobs
     join object_blobs on object_blobs.blob_id = blobs.id
     join objects on object_blobs.object_id = objects.id
-    where objects.key = $1
+    where objects.key = $1 and objects.bucket_id = $2
 )
 delete from blob_metadata
 where blob_id in (select id from blob_ids);
 `
 
-				_, err = txn.Exec(deleteMetadataStateSQL, prefix)
+				_, err = txn.ExecContext(r.Context(), deleteMetadataStateSQL, prefix, buckets[0].ID)
 				if err != nil {
-					opts.LoggerError.Printf("error cleaning state: %v", err)
+					opts.Logger.ErrorContext(r.Context(), "failed to clean state", logging.Err(err))
 
 					return
 				}
@@ -95,73 +146,58 @@ with blob_ids as (
     from blobs
     join object_blobs on object_blobs.blob_id = blobs.id
     join objects on object_blobs.object_id = objects.id
-    where objects.key = $1
+    where objects.key = $1 and objects.bucket_id = $2
 )
 delete from blob_properties
 where blob_id in (select id from blob_ids);
 `
 
-				_, err = txn.Exec(deletePropertiesStateSQL, prefix)
+				_, err = txn.ExecContext(r.Context(), deletePropertiesStateSQL, prefix, buckets[0].ID)
 				if err != nil {
-					opts.LoggerError.Printf("error cleaning state: %v", err)
+					opts.Logger.ErrorContext(r.Context(), "failed to clean state", logging.Err(err))
 
 					return
 				}
 
-				err = txn.Commit()
+				// reloading is an explicit retry, so previous failures are cleared
+				deleteErrorsStateSQL := `
+with blob_ids as (
+    select blobs.id
+    from blobs
+    join object_blobs on object_blobs.blob_id = blobs.id
+    join objects on object_blobs.object_id = objects.id
+    where objects.key = $1 and objects.bucket_id = $2
+)
+delete from processing_errors
+where blob_id in (select id from blob_ids);
+`
+
+				_, err = txn.ExecContext(r.Context(), deleteErrorsStateSQL, prefix, buckets[0].ID)
 				if err != nil {
-					opts.LoggerError.Printf("error committing transaction: %v", err)
+					opts.Logger.ErrorContext(r.Context(), "failed to clean state", logging.Err(err))
+
 					return
 				}
-			}
-
-			_, err := importer.Run(r.Context(), opts.DB, opts.S3, &importer.Options{
-				BucketName:  opts.BucketName,
-				SchemaName:  "storage_console",
-				Prefix:      prefix,
-				LoggerInfo:  opts.LoggerInfo,
-				LoggerError: opts.LoggerError,
-			})
-			if err != nil {
-				opts.LoggerError.Printf("error running importer: %v", err)
-				return
-			}
 
-			// do initial metadata processing
-			_, err = metaRunner.Run(r.Context(), opts.DB, opts.S3, &metaRunner.Options{
-				BucketName:        opts.BucketName,
-				SchemaName:        "storage_console",
-				Prefix:            prefix,
-				EnabledProcessors: []string{"thumbnail", "exif", "color"},
-				LoggerInfo:        opts.LoggerInfo,
-				LoggerError:       opts.LoggerError,
-			})
-			if err != nil {
-				opts.LoggerError.Printf("error running metadata runner: %v", err)
-				return
+				err = txn.Commit()
+				if err != nil {
+					opts.Logger.ErrorContext(r.Context(), "failed to commit transaction", logging.Err(err))
+					return
+				}
 			}
 
-			// upgrade metadata into rich properties
-			_, err = propRunner.Run(r.Context(), opts.DB, opts.S3, &propRunner.Options{
-				BucketName:        opts.BucketName,
-				SchemaName:        "storage_console",
-				Prefix:            prefix,
-				EnabledProcessors: []string{"exif", "color"},
-				LoggerInfo:        opts.LoggerInfo,
-				LoggerError:       opts.LoggerError,
-			})
-			if err != nil {
-				opts.LoggerError.Printf("error running properties runner: %v", err)
-
-				return
+			for _, b := range buckets {
+				err := processBucket(r.Context(), opts, b, prefix)
+				if err != nil {
+					opts.Logger.ErrorContext(r.Context(), "failed to reload bucket", logging.FieldBucket, b.Name, logging.Err(err))
+					return
+				}
 			}
 
-			opts.LoggerInfo.Printf("reloaded")
+			opts.Logger.InfoContext(r.Context(), "reloaded")
 
 			if prefix != "" {
-				file := path.Base(prefix)
-				dir := path.Dir(prefix)
-				http.Redirect(w, r, "/b/"+dir+"/?preview="+file, http.StatusSeeOther)
+				http.Redirect(w, r, handlers.PreviewURL(buckets[0].Name, prefix), http.StatusSeeOther)
 				return
 			}
 
@@ -169,6 +205,26 @@ where blob_id in (select id from blob_ids);
 		}), opts),
 	)
 
+	mux.Handle(
+		"/errors",
+		middlewares.BuildAuth(http.HandlerFunc(errorsHandler), opts),
+	)
+
+	mux.Handle(
+		"/search",
+		middlewares.BuildAuth(http.HandlerFunc(searchHandler), opts),
+	)
+
+	mux.Handle(
+		"/places",
+		middlewares.BuildAuth(http.HandlerFunc(placesHandler), opts),
+	)
+
+	mux.Handle(
+		"/colors",
+		middlewares.BuildAuth(http.HandlerFunc(colorsHandler), opts),
+	)
+
 	mux.Handle(
 		"/b/",
 		middlewares.BuildAuth(http.HandlerFunc(browseHandler), opts),
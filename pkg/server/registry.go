@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/charlieegan3/storage-console/pkg/config"
+	"github.com/charlieegan3/storage-console/pkg/geo"
+	"github.com/charlieegan3/storage-console/pkg/meta"
+	"github.com/charlieegan3/storage-console/pkg/meta/command"
+	"github.com/charlieegan3/storage-console/pkg/meta/ocr"
+	"github.com/charlieegan3/storage-console/pkg/meta/webhook"
+	"github.com/charlieegan3/storage-console/pkg/meta/xmp"
+	"github.com/charlieegan3/storage-console/pkg/processors"
+	"github.com/charlieegan3/storage-console/pkg/properties/places"
+)
+
+// webhookPresignExpiry is how long remote processors have to download a blob
+const webhookPresignExpiry = 15 * time.Minute
+
+// buildRegistry returns the built-in processors along with those configured
+// as external commands and webhooks. Processors which read from or link to
+// the bucket use bucketName.
+func buildRegistry(
+	cfg *config.Config,
+	bucketName string,
+	minioClient *minio.Client,
+	gazetteer *geo.Gazetteer,
+) (*processors.Registry, error) {
+	thumbnailFormat, err := meta.ImageFormatFromString(cfg.Thumbnails.Format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse thumbnail format: %w", err)
+	}
+
+	var commands []command.CommandProcessor
+	for _, c := range cfg.Processors.Commands {
+		commands = append(commands, command.CommandProcessor{
+			ProcessorName:         c.Name,
+			Command:               c.Command,
+			Input:                 c.Input,
+			Timeout:               c.Timeout,
+			ProcessorVersion:      c.Version,
+			ProcessorContentTypes: c.ContentTypes,
+		})
+	}
+
+	presign := func(ctx context.Context, key string) (string, error) {
+		u, err := minioClient.PresignedGetObject(ctx, bucketName, key, webhookPresignExpiry, nil)
+		if err != nil {
+			return "", err
+		}
+
+		return u.String(), nil
+	}
+
+	var webhooks []*webhook.WebhookProcessor
+	for _, w := range cfg.Processors.Webhooks {
+		webhooks = append(webhooks, &webhook.WebhookProcessor{
+			ProcessorName:         w.Name,
+			URL:                   w.URL,
+			Send:                  w.Send,
+			Headers:               w.Headers,
+			Timeout:               w.Timeout,
+			Retries:               w.Retries,
+			Concurrency:           w.Concurrency,
+			ProcessorVersion:      w.Version,
+			ProcessorContentTypes: w.ContentTypes,
+			Presign:               presign,
+		})
+	}
+
+	var sidecar xmp.SidecarLoader = func(ctx context.Context, key string) ([]byte, error) {
+		obj, err := minioClient.GetObject(ctx, bucketName, key, minio.GetObjectOptions{})
+		if err != nil {
+			return nil, err
+		}
+		defer obj.Close()
+
+		bs, err := io.ReadAll(obj)
+		if err != nil {
+			if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+				return nil, nil
+			}
+
+			return nil, err
+		}
+
+		return bs, nil
+	}
+
+	return processors.NewBuiltinRegistry(processors.BuiltinOptions{
+		ThumbnailSizes:   cfg.Thumbnails.Sizes,
+		ThumbnailFormat:  thumbnailFormat,
+		ThumbnailQuality: cfg.Thumbnails.Quality,
+		OCR: ocr.OCRProcessor{
+			Command:   cfg.Processors.OCR.Command,
+			Languages: cfg.Processors.OCR.Languages,
+			MaxPages:  cfg.Processors.OCR.MaxPages,
+		},
+		Places: places.PlacesProcessor{
+			Gazetteer:   gazetteer,
+			MaxDistance: cfg.Processors.Places.MaxDistance,
+		},
+		XMPSidecar: sidecar,
+		AllTags:    cfg.Processors.Tags.Enabled,
+		Commands:   commands,
+		Webhooks:   webhooks,
+	})
+}
+
+// loadGazetteer reads the configured GeoNames files, nil is returned when
+// none are set so that the bundled gazetteer is used
+func loadGazetteer(cfg config.Places) (*geo.Gazetteer, error) {
+	if cfg.Cities == "" {
+		return nil, nil
+	}
+
+	cities, err := os.Open(cfg.Cities)
+	if err != nil {
+		return nil, err
+	}
+	defer cities.Close()
+
+	var admin1Codes io.Reader
+	if cfg.Admin1Codes != "" {
+		f, err := os.Open(cfg.Admin1Codes)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		admin1Codes = f
+	}
+
+	return geo.LoadGeoNames(cities, admin1Codes)
+}
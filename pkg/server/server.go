@@ -3,62 +3,120 @@ package server
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
 
-	"github.com/minio/minio-go/v7"
-
 	"github.com/charlieegan3/storage-console/pkg/config"
-	"github.com/charlieegan3/storage-console/pkg/importer"
-	metaRunner "github.com/charlieegan3/storage-console/pkg/meta/runner"
-	propRunner "github.com/charlieegan3/storage-console/pkg/properties/runner"
+	"github.com/charlieegan3/storage-console/pkg/logging"
+	"github.com/charlieegan3/storage-console/pkg/meta"
+	"github.com/charlieegan3/storage-console/pkg/metrics"
 	"github.com/charlieegan3/storage-console/pkg/server/handlers"
+	"github.com/charlieegan3/storage-console/pkg/server/middlewares"
+	"github.com/charlieegan3/storage-console/pkg/tracing"
 )
 
-func NewServer(db *sql.DB, minioClient *minio.Client, cfg *config.Config) (Server, error) {
+// NewServer returns a server for the buckets, m is nil unless metrics are
+// enabled
+func NewServer(db *sql.DB, buckets []*handlers.Bucket, cfg *config.Config, m *metrics.Metrics) (Server, error) {
+	if len(buckets) == 0 {
+		return Server{}, fmt.Errorf("at least one bucket is required")
+	}
+
+	if m != nil {
+		registerStoreMetrics(m, db, cfg.Database.SchemaName, buckets)
+	}
+
 	return Server{
-		cfg:         cfg,
-		db:          db,
-		minioClient: minioClient,
+		cfg:     cfg,
+		db:      db,
+		buckets: buckets,
+		metrics: m,
+
+		imports: &importState{},
 	}, nil
 }
 
 type Server struct {
 	cfg *config.Config
 
-	db          *sql.DB
-	minioClient *minio.Client
+	db      *sql.DB
+	buckets []*handlers.Bucket
+	metrics *metrics.Metrics
 
 	httpServer *http.Server
+	// metricsServer is set when metrics are served on their own listener
+	metricsServer *http.Server
+
+	// imports tracks the import run by Start
+	imports *importState
 }
 
 func (s *Server) Start(ctx context.Context) error {
 	var err error
 
+	thumbnailFormat, err := meta.ImageFormatFromString(s.cfg.Thumbnails.Format)
+	if err != nil {
+		return fmt.Errorf("failed to parse thumbnail format: %w", err)
+	}
+
+	opts := &handlers.Options{
+		DevMode:    s.cfg.Server.DevMode,
+		DB:         s.db,
+		SchemaName: s.cfg.Database.SchemaName,
+		Buckets:    s.buckets,
+		Logger:     s.cfg.Server.Logger,
+		Metrics:    s.metrics,
+
+		ThumbnailSizes:  s.cfg.Thumbnails.Sizes,
+		ThumbnailFormat: thumbnailFormat,
+	}
+
 	mux := http.NewServeMux()
 	if s.cfg.Server.RegisterMux {
-		mux, err = newMux(
-			&handlers.Options{
-				DevMode:     s.cfg.Server.DevMode,
-				DB:          s.db,
-				S3:          s.minioClient,
-				BucketName:  s.cfg.S3.BucketName,
-				LoggerInfo:  s.cfg.Server.LoggerInfo,
-				LoggerError: s.cfg.Server.LoggerError,
-			},
-		)
+		mux, err = newMux(opts)
 		if err != nil {
 			return fmt.Errorf("failed to create mux: %w", err)
 		}
 	}
 
+	// probes are answered without authentication, and when the mux isn't
+	// registered
+	mux.Handle("/healthz", healthHandler(opts))
+	mux.Handle("/readyz", readyHandler(opts, s.readyChecks()))
+
+	if s.metrics != nil {
+		metricsCfg := s.cfg.Server.Metrics
+		if metricsCfg.Port == 0 {
+			// served with the console, so behind the same authentication
+			mux.Handle(metricsCfg.Path, middlewares.BuildAuth(s.metrics.Handler(opts.Logger), opts))
+		} else {
+			metricsMux := http.NewServeMux()
+			metricsMux.Handle(metricsCfg.Path, s.metrics.Handler(opts.Logger))
+
+			s.metricsServer = &http.Server{
+				Addr:    fmt.Sprintf("%s:%d", metricsCfg.Address, metricsCfg.Port),
+				Handler: metricsMux,
+			}
+		}
+	}
+
+	handler := middlewares.BuildMetrics(mux, opts)
+	if s.cfg.Server.Tracing.Enabled {
+		handler = tracing.Handler(handler, mux)
+	}
+
 	s.httpServer = &http.Server{
 		Addr: fmt.Sprintf(
 			"%s:%d",
 			s.cfg.Server.Address,
 			s.cfg.Server.Port,
 		),
-		Handler: mux,
+		Handler: middlewares.BuildRequestID(handler, opts),
+	}
+
+	if s.cfg.Server.RunImporter {
+		s.imports.start()
 	}
 
 	go func() {
@@ -66,73 +124,61 @@ func (s *Server) Start(ctx context.Context) error {
 			return
 		}
 
-		_, err := importer.Run(ctx, s.db, s.minioClient, &importer.Options{
-			BucketName:  s.cfg.S3.BucketName,
-			SchemaName:  "storage_console",
-			LoggerInfo:  s.cfg.Server.LoggerInfo,
-			LoggerError: s.cfg.Server.LoggerError,
-		})
-		if err != nil {
-			s.cfg.Server.LoggerError.Printf("error running importer: %v", err)
-			return
-		}
-
-		// do initial metadata processing
-		_, err = metaRunner.Run(ctx, s.db, s.minioClient, &metaRunner.Options{
-			BucketName:        s.cfg.S3.BucketName,
-			SchemaName:        "storage_console",
-			EnabledProcessors: []string{"thumbnail", "exif", "color"},
-			LoggerInfo:        s.cfg.Server.LoggerInfo,
-			LoggerError:       s.cfg.Server.LoggerError,
-		})
-		if err != nil {
-			s.cfg.Server.LoggerError.Printf("error running metadata runner: %v", err)
-			return
+		// a failing bucket doesn't hold up the others, the failures are
+		// reported by the import ready check
+		var errs []error
+		for _, b := range s.buckets {
+			err := processBucket(ctx, opts, b, "")
+			if err != nil {
+				s.cfg.Server.Logger.Error("failed to process bucket", logging.FieldBucket, b.Name, logging.Err(err))
+				errs = append(errs, fmt.Errorf("bucket %s: %w", b.Name, err))
+				continue
+			}
+
+			s.cfg.Server.Logger.Info("processed bucket", logging.FieldBucket, b.Name)
 		}
 
-		// upgrade metadata into rich properties
-		_, err = propRunner.Run(ctx, s.db, s.minioClient, &propRunner.Options{
-			BucketName:        s.cfg.S3.BucketName,
-			SchemaName:        "storage_console",
-			EnabledProcessors: []string{"exif", "color"},
-			LoggerInfo:        s.cfg.Server.LoggerInfo,
-			LoggerError:       s.cfg.Server.LoggerError,
-		})
-		if err != nil {
-			s.cfg.Server.LoggerError.Printf("error running properties runner: %v", err)
-			return
-		}
-
-		s.cfg.Server.LoggerInfo.Println("imported")
+		s.imports.finish(errors.Join(errs...))
 	}()
 
-	go func() {
-		<-ctx.Done()
-		err = s.httpServer.Shutdown(ctx)
-		if err != nil {
-			s.cfg.Server.LoggerError.Println("failed to shutdown", err)
+	for _, srv := range []*http.Server{s.httpServer, s.metricsServer} {
+		if srv == nil {
+			continue
 		}
-	}()
 
-	go func() {
-		err = s.httpServer.ListenAndServe()
-		if err != nil {
-			s.cfg.Server.LoggerError.Println("failed to listen and serve", err)
-		}
-	}()
+		go func() {
+			<-ctx.Done()
+			err := srv.Shutdown(ctx)
+			if err != nil {
+				s.cfg.Server.Logger.Error("failed to shutdown", "address", srv.Addr, logging.Err(err))
+			}
+		}()
+
+		go func() {
+			err := srv.ListenAndServe()
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				s.cfg.Server.Logger.Error("failed to listen and serve", "address", srv.Addr, logging.Err(err))
+			}
+		}()
+	}
 
 	return nil
 }
 
 func (s *Server) Stop(ctx context.Context) error {
-	if s.httpServer != nil {
-		err := s.httpServer.Shutdown(ctx)
+	for _, srv := range []*http.Server{s.httpServer, s.metricsServer} {
+		if srv == nil {
+			continue
+		}
+
+		err := srv.Shutdown(ctx)
 		if err != nil {
 			return err
 		}
 	}
 
 	s.httpServer = nil
+	s.metricsServer = nil
 
 	return nil
 }
@@ -5,15 +5,19 @@ import (
 	"database/sql"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 
 	"github.com/charlieegan3/storage-console/pkg/config"
+	"github.com/charlieegan3/storage-console/pkg/metrics"
+	"github.com/charlieegan3/storage-console/pkg/server/handlers"
 	"github.com/charlieegan3/storage-console/pkg/test"
 	"github.com/charlieegan3/storage-console/pkg/utils"
 )
@@ -27,7 +31,7 @@ func TestNewServer(t *testing.T) {
 		t.Fatalf("unexpected error: %s", err)
 	}
 
-	logger := log.New(test.NewTLogWriter(t), "", 0)
+	logger := slog.New(slog.NewTextHandler(test.NewTLogWriter(t), nil))
 
 	serverConfig := &config.Config{
 		Server: config.Server{
@@ -36,8 +40,7 @@ func TestNewServer(t *testing.T) {
 			Address:     "localhost",
 			RegisterMux: false,
 			RunImporter: false,
-			LoggerError: logger,
-			LoggerInfo:  logger,
+			Logger:      logger,
 		},
 		S3: config.S3{
 			Endpoint:   "localhost:9000",
@@ -58,10 +61,14 @@ func TestNewServer(t *testing.T) {
 		Secure: false,
 	})
 	if err != nil {
-		log.Fatalf("error connecting to minio: %v", err)
+		t.Fatalf("error connecting to minio: %v", err)
 	}
 
-	server, err := NewServer(db, minioClient, serverConfig)
+	buckets := []*handlers.Bucket{
+		{Name: "local", BucketName: serverConfig.S3.BucketName, S3: minioClient},
+	}
+
+	server, err := NewServer(db, buckets, serverConfig, nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %s", err)
 	}
@@ -114,4 +121,85 @@ func TestNewServer(t *testing.T) {
 		t.Logf("body: %s", bodyBs)
 		t.Fatalf("unexpected status code: %d", resp.StatusCode)
 	}
+
+	// health checks are served without the mux
+	resp, err = client.Get(fmt.Sprintf("http://localhost:%d/healthz", port))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected healthz status code: %d", resp.StatusCode)
+	}
+}
+
+func TestServerMetricsAuth(t *testing.T) {
+	ctx := context.Background()
+
+	testCases := map[string]struct {
+		metricsPort int
+	}{
+		"console listener": {},
+		"metrics listener": {metricsPort: 9090},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			server := Server{
+				cfg: &config.Config{
+					Server: config.Server{
+						Address: "localhost",
+						Logger:  slog.New(slog.NewTextHandler(test.NewTLogWriter(t), nil)),
+						Metrics: config.Metrics{
+							Enabled: true,
+							Path:    "/metrics",
+							Port:    tc.metricsPort,
+						},
+					},
+				},
+				metrics: metrics.New(),
+				imports: &importState{},
+			}
+
+			startCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			// the handlers are used directly, the listeners are closed
+			// before they're used
+			err := server.Start(startCtx)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			consoleHandler := server.httpServer.Handler
+			metricsServer := server.metricsServer
+
+			err = server.Stop(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			scrape := func(h http.Handler) string {
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+				return rec.Body.String()
+			}
+
+			if strings.Contains(scrape(consoleHandler), "go_goroutines") {
+				t.Fatalf("expected metrics to require authentication on the console listener")
+			}
+
+			if tc.metricsPort == 0 {
+				if metricsServer != nil {
+					t.Fatalf("expected no metrics listener")
+				}
+				return
+			}
+
+			if !strings.Contains(scrape(metricsServer.Handler), "go_goroutines") {
+				t.Fatalf("expected metrics to be served on the metrics listener")
+			}
+		})
+	}
 }
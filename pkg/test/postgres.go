@@ -69,8 +69,9 @@ func InitPostgres(ctx context.Context, t *testing.T) (db *sql.DB, postgresCleanu
 		}
 	}
 
-	err = migration.Cycle(db, &postgres.Config{
+	err = migration.Cycle(db, "storage_console", &postgres.Config{
 		MigrationsTable: "schema_migrations_storage_console",
+		SchemaName:      "public",
 	})
 	if err != nil {
 		return nil, nil, fmt.Errorf("could not run migrations up and down: %s", err)
@@ -0,0 +1,135 @@
+// Package tracing records OpenTelemetry spans for requests, object storage
+// and database calls and processor runs. Spans are made with the global
+// tracer provider, which drops them until Setup is called.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/charlieegan3/storage-console/pkg/config"
+	"github.com/charlieegan3/storage-console/pkg/database"
+)
+
+const scope = "github.com/charlieegan3/storage-console"
+
+// Setup exports spans to the configured collector and sets the global
+// tracer provider. The returned func flushes and stops the exporter.
+func Setup(ctx context.Context, cfg config.Tracing) (func(context.Context) error, error) {
+	var opts []otlptracehttp.Option
+	if cfg.Endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	}
+
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	tp := NewProvider(cfg.ServiceName, cfg.SampleRatio, sdktrace.WithBatcher(exporter))
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return tp.Shutdown, nil
+}
+
+// NewProvider returns a provider for the service, sampling sampleRatio of
+// the traces started here and following the sampling of incoming traces.
+// opts sets the exporter, tests use an in-memory one with sdktrace.WithSyncer.
+func NewProvider(serviceName string, sampleRatio float64, opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(append(
+		[]sdktrace.TracerProviderOption{
+			sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+			sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+		},
+		opts...,
+	)...)
+}
+
+// Start starts a span with the console's tracer
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(scope).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err, if any, on the span and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// DBHook records a span for each database call, for database.Open. Calls
+// outside of a trace, such as the importer's per-object queries, are left
+// out so that they don't each start their own.
+func DBHook() database.Hook {
+	return func(ctx context.Context, op, query string) func(error) {
+		if !trace.SpanContextFromContext(ctx).IsValid() {
+			return func(error) {}
+		}
+
+		attrs := []attribute.KeyValue{
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(op),
+		}
+		if query != "" {
+			attrs = append(attrs, semconv.DBQueryText(query))
+		}
+
+		_, span := otel.Tracer(scope).Start(
+			ctx,
+			"db "+op,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attrs...),
+		)
+
+		return func(err error) {
+			End(span, err)
+		}
+	}
+}
+
+// Transport records a span for each object storage request made with rt
+func Transport(rt http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(
+		rt,
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return "s3 " + r.Method
+		}),
+	)
+}
+
+// Handler records a span for each request served by h, named by the mux
+// pattern which matched it
+func Handler(h http.Handler, mux *http.ServeMux) http.Handler {
+	return otelhttp.NewHandler(
+		h,
+		"http",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			_, route := mux.Handler(r)
+			if route == "" {
+				route = "unmatched"
+			}
+
+			return r.Method + " " + route
+		}),
+	)
+}
@@ -0,0 +1,143 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// recordSpans sets the global provider to one recording spans in memory,
+// so tests using it can't run in parallel
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := NewProvider("storage-console-test", 1, sdktrace.WithSyncer(exporter))
+
+	previous, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		otel.SetTextMapPropagator(previousPropagator)
+		_ = tp.Shutdown(context.Background())
+	})
+
+	return exporter
+}
+
+func TestDBHook(t *testing.T) {
+	exporter := recordSpans(t)
+
+	hook := DBHook()
+
+	// calls outside of a trace aren't recorded
+	hook(context.Background(), "query", "select 1")(nil)
+	if spans := exporter.GetSpans(); len(spans) != 0 {
+		t.Fatalf("expected no spans, got %d", len(spans))
+	}
+
+	ctx, parent := Start(context.Background(), "preview")
+	hook(ctx, "query", "select * from blobs")(errors.New("timeout"))
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	db := spans[0]
+	if db.Name != "db query" {
+		t.Fatalf("unexpected span name: %s", db.Name)
+	}
+
+	if db.Parent.SpanID() != spans[1].SpanContext.SpanID() {
+		t.Fatalf("expected db span to be a child of the preview span")
+	}
+
+	if db.Status.Code != codes.Error {
+		t.Fatalf("expected error status, got %v", db.Status)
+	}
+
+	var statement string
+	for _, attr := range db.Attributes {
+		if attr.Key == semconv.DBQueryTextKey {
+			statement = attr.Value.AsString()
+		}
+	}
+
+	if statement != "select * from blobs" {
+		t.Fatalf("unexpected statement: %q", statement)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	exporter := recordSpans(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the trace is propagated to the server
+		if r.Header.Get("Traceparent") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	ctx, parent := Start(context.Background(), "run meta")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, srv.URL+"/photos/data/a.jpg", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	resp, err := (&http.Client{Transport: Transport(http.DefaultTransport)}).Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	resp.Body.Close()
+	parent.End()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected trace headers to be sent, got status %d", resp.StatusCode)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 || spans[0].Name != "s3 HEAD" {
+		t.Fatalf("expected an s3 HEAD span, got %v", spans.Snapshots())
+	}
+}
+
+func TestHandler(t *testing.T) {
+	exporter := recordSpans(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/b/", func(w http.ResponseWriter, r *http.Request) {
+		_, span := Start(r.Context(), "render template")
+		End(span, nil)
+	})
+
+	h := Handler(mux, mux)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/b/photos/a.jpg?preview=true", nil))
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	if spans[0].Name != "render template" || spans[1].Name != "GET /b/" {
+		t.Fatalf("unexpected spans: %s, %s", spans[0].Name, spans[1].Name)
+	}
+
+	if spans[0].Parent.SpanID() != spans[1].SpanContext.SpanID() {
+		t.Fatalf("expected the template span to be a child of the request span")
+	}
+}